@@ -17,6 +17,80 @@ type Config struct {
 	TemplateDir string `json:"templateDir"` // The directory containing HTML page templates.
 	Database    string `json:"database"`    // The connection string for the database.
 	ScriptDir   string `json:"scriptDir"`   // The directory to save monitor scripts to.
+	SnapshotDir string `json:"snapshotDir"` // The directory to save page snapshots to.
+
+	// The file containing the key that monitor secrets are encrypted with,
+	// which is created if it doesn't exist.
+	SecretsKeyFile string `json:"secretsKeyFile"`
+
+	// The interpreters that monitor scripts can be written for. Miru's
+	// original Python, Ruby and Perl interpreters are used if none are set.
+	Interpreters []Interpreter `json:"interpreters"`
+
+	// The number of seconds to let a monitor script run past its expected
+	// run time before it is killed.
+	ScriptGracePeriod uint `json:"scriptGracePeriod"`
+
+	// The maximum number of bytes of a monitor script's stderr output to store.
+	MaxStderrBytes uint `json:"maxStderrBytes"`
+
+	// Monitor scripts are run in a sandbox unless it is disabled. Scripts run
+	// as SandboxUser, a dedicated unprivileged user that miru must be allowed
+	// to switch to, and in new Linux namespaces if SandboxNamespaces is set.
+	// Each run gets a private directory created in SandboxTempDir.
+	DisableSandbox    bool   `json:"disableSandbox"`
+	SandboxUser       string `json:"sandboxUser"`
+	SandboxNamespaces bool   `json:"sandboxNamespaces"`
+	SandboxTempDir    string `json:"sandboxTempDir"`
+
+	// The limits on the resources that a sandboxed monitor script may use, and
+	// on the number of bytes it may write to stdout or to any one file.
+	ScriptCPUSeconds  uint `json:"scriptCPUSeconds"`
+	ScriptMemoryBytes uint `json:"scriptMemoryBytes"`
+	ScriptOpenFiles   uint `json:"scriptOpenFiles"`
+	MaxOutputBytes    uint `json:"maxOutputBytes"`
+
+	// The fractions of a page's words that must change for miru to classify
+	// the change as a content change or a rewrite, when scoring changes itself.
+	ContentChangeThreshold float64 `json:"contentChangeThreshold"`
+	RewrittenThreshold     float64 `json:"rewrittenThreshold"`
+
+	// The number of monitor scripts that can be run at the same time, and the
+	// number of ready monitors that can be queued up waiting for a free worker.
+	MonitorWorkers   uint `json:"monitorWorkers"`
+	MonitorQueueSize uint `json:"monitorQueueSize"`
+
+	// The number of times to try sending a report to a webhook, and the number
+	// of seconds to wait before the first retry, which doubles after each one.
+	WebhookMaxAttempts uint `json:"webhookMaxAttempts"`
+	WebhookBackoff     uint `json:"webhookBackoff"`
+
+	// The SMTP server to send email notifications through, and the address
+	// they are sent from. Notifications are only logged if no host is set.
+	SMTPHost     string `json:"smtpHost"`
+	SMTPPort     uint   `json:"smtpPort"`
+	SMTPUsername string `json:"smtpUsername"`
+	SMTPPassword string `json:"smtpPassword"`
+	EmailFrom    string `json:"emailFrom"`
+
+	// The least significant change, as a number, that subscribed administrators
+	// are emailed about. It is a pointer so that 0, which emails every report,
+	// can be told apart from leaving it unset.
+	ReportEmailThreshold *uint `json:"reportEmailThreshold"`
+}
+
+// Interpreter describes a program that monitor scripts can be run with.
+// Scripts are run by executing Path with Args followed by the path to the
+// script, and are saved with the file extension Extension. VersionCheck holds
+// the arguments that make the program print its version, which miru runs when
+// it starts to check that the interpreter works.
+type Interpreter struct {
+	Name         string   `json:"name"`  // Stored with each monitor that uses it.
+	Label        string   `json:"label"` // Shown to administrators uploading scripts.
+	Path         string   `json:"path"`
+	Args         []string `json:"args"`
+	Extension    string   `json:"extension"`
+	VersionCheck []string `json:"versionCheck"`
 }
 
 // MustLoad tries to load a configuration and panics if it cannot do so.
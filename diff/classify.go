@@ -0,0 +1,68 @@
+package diff
+
+import (
+	"../models"
+
+	"strings"
+)
+
+// Default thresholds used to classify changes when none are configured.
+const (
+	defaultContentChangeThreshold float64 = 0.02
+	defaultRewrittenThreshold     float64 = 0.5
+)
+
+// Thresholds configure how much of a page's words have to change for the
+// change to be classified at each level of significance. Each threshold is a
+// fraction between 0 and 1 of the words in both versions that were inserted
+// or deleted. Any change smaller than ContentChange is a minor update.
+type Thresholds struct {
+	ContentChange float64
+	Rewritten     float64
+}
+
+// DefaultThresholds constructs Thresholds that treat a change to at least 2% of
+// a page's words as a change to its content, and a change to at least half of
+// its words as a rewrite.
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		ContentChange: defaultContentChangeThreshold,
+		Rewritten:     defaultRewrittenThreshold,
+	}
+}
+
+// ChangedFraction computes the fraction of the words in two versions of some
+// text that were inserted or deleted between them.
+func ChangedFraction(previous, current string) float64 {
+	old := Words(previous)
+	new := Words(current)
+	if len(old)+len(new) == 0 {
+		return 0
+	}
+	changed := 0
+	for _, edit := range Diff(old, new) {
+		if edit.Op != Equal {
+			changed++
+		}
+	}
+	return float64(changed) / float64(len(old)+len(new))
+}
+
+// Classify determines the significance of the change between the previous and
+// current text extracted from a page.
+func Classify(previous, current string, thresholds Thresholds) models.Importance {
+	if strings.TrimSpace(current) == "" && strings.TrimSpace(previous) != "" {
+		return models.Deleted
+	}
+	changed := ChangedFraction(previous, current)
+	switch {
+	case changed == 0:
+		return models.NoChange
+	case changed >= thresholds.Rewritten:
+		return models.Rewritten
+	case changed >= thresholds.ContentChange:
+		return models.ContentChange
+	default:
+		return models.MinorUpdate
+	}
+}
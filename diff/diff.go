@@ -0,0 +1,164 @@
+package diff
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maxEditDistance is the largest number of insertions and deletions that Diff
+// will search for before giving up and treating the inputs as entirely
+// different. This bounds the time and memory spent comparing very different
+// pages, which would be classified as rewritten anyway.
+const maxEditDistance int = 1000
+
+// Operation is a pseudo-enum describing what happened to a piece of text.
+type Operation int
+
+// The ways in which a piece of text can differ between two versions.
+const (
+	Equal   Operation = iota // The text is in both versions.
+	Insert                   // The text was added in the new version.
+	Delete                   // The text was removed from the old version.
+	Replace                  // The text was changed. Only used to describe whole lines.
+)
+
+// Edit is a single piece of text, such as a line or a word, and what happened
+// to it between two versions.
+type Edit struct {
+	Op   Operation
+	Text string
+}
+
+// Lines splits text into lines, without their line endings.
+func Lines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n")
+}
+
+// Words splits text into words, discarding whitespace.
+func Words(text string) []string {
+	return strings.FieldsFunc(text, unicode.IsSpace)
+}
+
+// Diff computes the shortest sequence of edits that transforms old into new,
+// using Myers' O(ND) difference algorithm.
+func Diff(old, new []string) []Edit {
+	// Common prefixes and suffixes are very likely for two versions of the same
+	// page, and are cheap to strip before searching for the differences.
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && old[prefix] == new[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(new)-prefix &&
+		old[len(old)-1-suffix] == new[len(new)-1-suffix] {
+		suffix++
+	}
+	edits := []Edit{}
+	for _, text := range old[:prefix] {
+		edits = append(edits, Edit{Equal, text})
+	}
+	edits = append(edits, myers(old[prefix:len(old)-suffix], new[prefix:len(new)-suffix])...)
+	for _, text := range old[len(old)-suffix:] {
+		edits = append(edits, Edit{Equal, text})
+	}
+	return edits
+}
+
+// myers finds the edits between old and new, falling back to deleting all of
+// old and inserting all of new if they differ by more than maxEditDistance.
+func myers(old, new []string) []Edit {
+	n, m := len(old), len(new)
+	max := n + m
+	if max > maxEditDistance {
+		max = maxEditDistance
+	}
+	// v[k+offset] holds the furthest x reached on diagonal k, and trace keeps a
+	// copy of v from each step d so that the path can be walked back.
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	trace := [][]int{}
+	found := false
+	for d := 0; d <= max && !found; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+				x = v[k+1+offset]
+			} else {
+				x = v[k-1+offset] + 1
+			}
+			y := x - k
+			for x < n && y < m && old[x] == new[y] {
+				x++
+				y++
+			}
+			v[k+offset] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		return replaceAll(old, new)
+	}
+	return backtrack(old, new, trace, offset)
+}
+
+// backtrack walks back through the snapshots of furthest reaching paths taken
+// by myers to recover the edits along the shortest path.
+func backtrack(old, new []string, trace [][]int, offset int) []Edit {
+	edits := []Edit{}
+	x, y := len(old), len(new)
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK+offset]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			edits = append(edits, Edit{Equal, old[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			edits = append(edits, Edit{Insert, new[y-1]})
+		} else {
+			edits = append(edits, Edit{Delete, old[x-1]})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		edits = append(edits, Edit{Equal, old[x-1]})
+		x--
+		y--
+	}
+	// The edits were collected from the end backwards.
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// replaceAll produces edits that delete everything in old and insert
+// everything in new.
+func replaceAll(old, new []string) []Edit {
+	edits := make([]Edit, 0, len(old)+len(new))
+	for _, text := range old {
+		edits = append(edits, Edit{Delete, text})
+	}
+	for _, text := range new {
+		edits = append(edits, Edit{Insert, text})
+	}
+	return edits
+}
@@ -0,0 +1,133 @@
+package diff
+
+import (
+	"../models"
+
+	"strings"
+	"testing"
+)
+
+// applyEdits rebuilds the old and new versions of some text from edits.
+func applyEdits(edits []Edit) ([]string, []string) {
+	old, new := []string{}, []string{}
+	for _, edit := range edits {
+		if edit.Op != Insert {
+			old = append(old, edit.Text)
+		}
+		if edit.Op != Delete {
+			new = append(new, edit.Text)
+		}
+	}
+	return old, new
+}
+
+func TestDiffFindsShortestEdits(t *testing.T) {
+	old := Words("the quick brown fox jumps over the lazy dog")
+	new := Words("the quick red fox jumps over the very lazy dog")
+	edits := Diff(old, new)
+	changed := 0
+	for _, edit := range edits {
+		if edit.Op != Equal {
+			changed++
+		}
+	}
+	if changed != 3 {
+		t.Errorf("expected 3 words to be inserted or deleted, got %d in %v", changed, edits)
+	}
+	rebuiltOld, rebuiltNew := applyEdits(edits)
+	if strings.Join(rebuiltOld, " ") != strings.Join(old, " ") ||
+		strings.Join(rebuiltNew, " ") != strings.Join(new, " ") {
+		t.Errorf("expected edits to rebuild both versions, got %v", edits)
+	}
+}
+
+func TestDiffEmptyInputs(t *testing.T) {
+	if len(Diff([]string{}, []string{})) != 0 {
+		t.Errorf("expected no edits between two empty inputs")
+	}
+	edits := Diff([]string{}, []string{"a", "b"})
+	if len(edits) != 2 || edits[0].Op != Insert || edits[1].Op != Insert {
+		t.Errorf("expected everything to be inserted into an empty input, got %v", edits)
+	}
+}
+
+func TestDiffGivesUpOnHugeDifferences(t *testing.T) {
+	old := make([]string, maxEditDistance)
+	new := make([]string, maxEditDistance)
+	for i := range old {
+		old[i] = "old"
+		new[i] = "new"
+	}
+	edits := Diff(old, new)
+	if len(edits) != 2*maxEditDistance {
+		t.Errorf("expected completely different inputs to be replaced entirely")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	page := strings.Repeat("climate data is archived here for everyone to read ", 20)
+	thresholds := DefaultThresholds()
+	cases := []struct {
+		current  string
+		expected models.Importance
+	}{
+		{page, models.NoChange},
+		{strings.Replace(page, "everyone", "everybody", 1), models.MinorUpdate},
+		{strings.Replace(page, "climate", "weather", 10), models.ContentChange},
+		{strings.Repeat("this page has been replaced by something else ", 20), models.Rewritten},
+		{"   ", models.Deleted},
+	}
+	for _, c := range cases {
+		if change := Classify(page, c.current, thresholds); change != c.expected {
+			t.Errorf("expected change to be classified as %v, got %v", c.expected, change)
+		}
+	}
+}
+
+func TestExtractText(t *testing.T) {
+	text := ExtractText(`<html><head><title>EPA</title><style>p { color: red; }</style></head>
+<body><!-- hidden --><p>Climate &amp; weather</p><script>var x = "<p>";</script><p>data</p></body></html>`)
+	expected := "EPA\nClimate & weather\ndata"
+	if text != expected {
+		t.Errorf("expected visible text %q, got %q", expected, text)
+	}
+}
+
+func TestExtractTextSkipsNonASCIIHiddenText(t *testing.T) {
+	cases := []struct {
+		document string
+		expected string
+	}{
+		{"<p>hi</p><script>ȺȺȺȺȺȺȺȺȺȺ</script>", "hi"},
+		{"<p>hi</p><SCRIPT>ȺȺȺ</SCRIPT><p>there</p>", "hi\nthere"},
+		{"<!-- İİİİİİİİİİ --><p>visible</p>", "visible"},
+		{"<style>İ{}</Style><p>Ⱥ stays</p>", "Ⱥ stays"},
+	}
+	for _, c := range cases {
+		if text := ExtractText(c.document); text != c.expected {
+			t.Errorf("expected visible text %q from %q, got %q", c.expected, c.document, text)
+		}
+	}
+}
+
+func TestSideBySidePairsChangedLines(t *testing.T) {
+	old := "title\nthe quick brown fox\nfooter"
+	new := "title\nthe quick red fox\nnew line\nfooter"
+	rows := SideBySide(old, new)
+	ops := []Operation{Equal, Replace, Insert, Equal}
+	if len(rows) != len(ops) {
+		t.Fatalf("expected %d rows, got %v", len(ops), rows)
+	}
+	for i, op := range ops {
+		if rows[i].Op != op {
+			t.Errorf("expected row %d to be %v, got %v", i, op, rows[i].Op)
+		}
+	}
+	if rows[2].OldLine != 0 || rows[2].NewLine != 3 || rows[3].OldLine != 3 || rows[3].NewLine != 4 {
+		t.Errorf("expected line numbers to skip lines missing from one side, got %v", rows)
+	}
+	changed := rows[1]
+	if len(changed.Old) != 4 || changed.Old[2] != (Edit{Delete, "brown"}) || changed.New[2] != (Edit{Insert, "red"}) {
+		t.Errorf("expected the changed words to be marked, got %v and %v", changed.Old, changed.New)
+	}
+}
@@ -0,0 +1,105 @@
+package diff
+
+// Row is one line of a side-by-side comparison of two versions of some text.
+// Each side is broken into words, marked with what happened to them, so that
+// changes within a line can be highlighted. A line that only exists in one
+// version has no words on the other side.
+type Row struct {
+	Op      Operation // Equal if the line is unchanged, Delete or Insert if only one side has it, or Replace.
+	OldLine int       // The line number in the old version, or 0 if there is none.
+	NewLine int       // The line number in the new version, or 0 if there is none.
+	Old     []Edit
+	New     []Edit
+}
+
+// String produces a name for each Operation that is suitable for use as a CSS class.
+func (o Operation) String() string {
+	switch o {
+	case Equal:
+		return "equal"
+	case Insert:
+		return "insert"
+	case Delete:
+		return "delete"
+	case Replace:
+		return "replace"
+	default:
+		return "unknown"
+	}
+}
+
+// SideBySide compares two versions of some text line by line, pairing up
+// lines that were changed and comparing their words.
+func SideBySide(old, new string) []Row {
+	rows := []Row{}
+	oldLine, newLine := 0, 0
+	deleted, inserted := []string{}, []string{}
+	// Lines deleted and inserted between two unchanged lines are paired up in
+	// order, since they most likely are edits of one another.
+	flush := func() {
+		for i := 0; i < len(deleted) || i < len(inserted); i++ {
+			row := Row{}
+			if i < len(deleted) {
+				oldLine++
+				row.OldLine = oldLine
+			}
+			if i < len(inserted) {
+				newLine++
+				row.NewLine = newLine
+			}
+			switch {
+			case i >= len(inserted):
+				row.Op = Delete
+				row.Old = wholeLine(Delete, deleted[i])
+			case i >= len(deleted):
+				row.Op = Insert
+				row.New = wholeLine(Insert, inserted[i])
+			default:
+				row.Op = Replace
+				row.Old, row.New = compareWords(deleted[i], inserted[i])
+			}
+			rows = append(rows, row)
+		}
+		deleted, inserted = []string{}, []string{}
+	}
+	for _, edit := range Diff(Lines(old), Lines(new)) {
+		switch edit.Op {
+		case Delete:
+			deleted = append(deleted, edit.Text)
+		case Insert:
+			inserted = append(inserted, edit.Text)
+		default:
+			flush()
+			oldLine++
+			newLine++
+			words := wholeLine(Equal, edit.Text)
+			rows = append(rows, Row{Equal, oldLine, newLine, words, words})
+		}
+	}
+	flush()
+	return rows
+}
+
+// compareWords splits a changed line into the words that each version of it
+// contains, marked with whether they were deleted, inserted, or kept.
+func compareWords(old, new string) ([]Edit, []Edit) {
+	oldWords, newWords := []Edit{}, []Edit{}
+	for _, edit := range Diff(Words(old), Words(new)) {
+		if edit.Op != Insert {
+			oldWords = append(oldWords, edit)
+		}
+		if edit.Op != Delete {
+			newWords = append(newWords, edit)
+		}
+	}
+	return oldWords, newWords
+}
+
+// wholeLine marks every word in a line with the same operation.
+func wholeLine(op Operation, line string) []Edit {
+	words := []Edit{}
+	for _, word := range Words(line) {
+		words = append(words, Edit{op, word})
+	}
+	return words
+}
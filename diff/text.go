@@ -0,0 +1,100 @@
+package diff
+
+import (
+	"html"
+	"strings"
+)
+
+// skippedElements are HTML elements whose contents are not visible text.
+var skippedElements = []string{"script", "style", "noscript", "template"}
+
+// ExtractText pulls the visible text out of an HTML document, putting each run
+// of text between tags on its own line so that changes can be compared line by
+// line as well as word by word.
+func ExtractText(document string) string {
+	lines := []string{}
+	remaining := document
+	for remaining != "" {
+		tagStart := strings.Index(remaining, "<")
+		if tagStart < 0 {
+			lines = appendText(lines, remaining)
+			break
+		}
+		lines = appendText(lines, remaining[:tagStart])
+		remaining = remaining[tagStart:]
+		if strings.HasPrefix(remaining, "<!--") {
+			remaining = skipPast(remaining, "-->")
+			continue
+		}
+		tagEnd := strings.Index(remaining, ">")
+		if tagEnd < 0 {
+			break
+		}
+		tag := remaining[1:tagEnd]
+		remaining = remaining[tagEnd+1:]
+		if strings.HasPrefix(tag, "/") {
+			continue
+		}
+		name := tagName(tag)
+		for _, skipped := range skippedElements {
+			if name == skipped {
+				remaining = skipPast(skipPast(remaining, "</"+skipped), ">")
+				break
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// appendText adds a run of text to lines with entities decoded and whitespace
+// collapsed, unless it is empty.
+func appendText(lines []string, text string) []string {
+	text = strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+	if text == "" {
+		return lines
+	}
+	return append(lines, text)
+}
+
+// skipPast discards everything in text up to and including the first occurrence
+// of a case-insensitive marker, or everything if the marker never appears.
+func skipPast(text, marker string) string {
+	i := indexASCIIFold(text, marker)
+	if i < 0 {
+		return ""
+	}
+	return text[i+len(marker):]
+}
+
+// indexASCIIFold finds the first occurrence of a lowercase ASCII marker in text,
+// ignoring the case of ASCII letters. Text is searched byte by byte rather than
+// being lowercased, since lowercasing can change the length of non-ASCII text
+// and so the positions in it.
+func indexASCIIFold(text, marker string) int {
+	for i := 0; i+len(marker) <= len(text); i++ {
+		matched := true
+		for j := 0; j < len(marker); j++ {
+			c := text[i+j]
+			if 'A' <= c && c <= 'Z' {
+				c += 'a' - 'A'
+			}
+			if c != marker[j] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return i
+		}
+	}
+	return -1
+}
+
+// tagName gets the lowercase name of an element from the contents of a tag.
+func tagName(tag string) string {
+	fields := strings.Fields(tag)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSuffix(fields[0], "/"))
+}
@@ -0,0 +1,89 @@
+package feeds
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// ContentType is the media type that Atom feeds should be served with.
+const ContentType string = "application/atom+xml; charset=utf-8"
+
+// atomNamespace is the XML namespace of Atom documents.
+const atomNamespace string = "http://www.w3.org/2005/Atom"
+
+// Feed is an Atom feed, as described in RFC 4287.
+type Feed struct {
+	XMLName xml.Name  `xml:"feed"`
+	XMLNS   string    `xml:"xmlns,attr"`
+	ID      string    `xml:"id"`
+	Title   string    `xml:"title"`
+	Updated time.Time `xml:"updated"`
+	Links   []Link    `xml:"link"`
+	Author  Person    `xml:"author"`
+	Entries []Entry   `xml:"entry"`
+}
+
+// Entry is a single item in a feed.
+type Entry struct {
+	ID       string    `xml:"id"`
+	Title    string    `xml:"title"`
+	Updated  time.Time `xml:"updated"`
+	Links    []Link    `xml:"link"`
+	Category Category  `xml:"category"`
+	Summary  string    `xml:"summary"`
+}
+
+// Link points from a feed or entry to another resource. Rel describes how the
+// resource is related, like "self" for the feed itself or "alternate" for a
+// page showing the same thing.
+type Link struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+// Person names the author of a feed.
+type Person struct {
+	Name string `xml:"name"`
+}
+
+// Category labels an entry so that feed readers can filter on it.
+type Category struct {
+	Term string `xml:"term,attr"`
+}
+
+// NewFeed is the constructor function for an empty Feed. The updated time is
+// brought forward as entries are added.
+func NewFeed(id, title, selfURL string) Feed {
+	return Feed{
+		XMLNS:   atomNamespace,
+		ID:      id,
+		Title:   title,
+		Links:   []Link{{Rel: "self", Href: selfURL}},
+		Author:  Person{Name: "Miru"},
+		Entries: []Entry{},
+	}
+}
+
+// AddEntry appends an entry to the feed.
+func (f *Feed) AddEntry(entry Entry) {
+	if entry.Updated.After(f.Updated) {
+		f.Updated = entry.Updated
+	}
+	f.Entries = append(f.Entries, entry)
+}
+
+// Write encodes the feed as an XML document. A feed without entries is given
+// the current time as its updated time, since Atom requires one.
+func (f Feed) Write(w io.Writer) error {
+	if f.Updated.IsZero() {
+		f.Updated = time.Now()
+	}
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(f)
+}
@@ -0,0 +1,54 @@
+package feeds
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteFeed(t *testing.T) {
+	feed := NewFeed("https://miru.site/reports/feed.atom", "Changes", "https://miru.site/reports/feed.atom")
+	older := time.Date(2017, 1, 30, 12, 0, 0, 0, time.UTC)
+	newer := time.Date(2017, 1, 31, 12, 0, 0, 0, time.UTC)
+	feed.AddEntry(Entry{
+		ID:      "https://miru.site/reports/history?monitor=1#report-2",
+		Title:   "Rewritten: <example.com>",
+		Updated: newer,
+		Links:   []Link{{Rel: "alternate", Href: "https://miru.site/reports/history?monitor=1&page=1"}},
+	})
+	feed.AddEntry(Entry{ID: "https://miru.site/reports/history?monitor=1#report-1", Updated: older})
+	if !feed.Updated.Equal(newer) {
+		t.Errorf("expected the feed to be updated when its newest entry was, got %v", feed.Updated)
+	}
+	buf := new(bytes.Buffer)
+	err := feed.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := buf.String()
+	if !strings.HasPrefix(written, "<?xml") || !strings.Contains(written, `xmlns="http://www.w3.org/2005/Atom"`) {
+		t.Errorf("expected an Atom document, got %s", written)
+	}
+	if !strings.Contains(written, "&lt;example.com&gt;") || !strings.Contains(written, "monitor=1&amp;page=1") {
+		t.Errorf("expected text and attributes to be escaped, got %s", written)
+	}
+	decoded := Feed{}
+	err = xml.Unmarshal(buf.Bytes(), &decoded)
+	if err != nil || len(decoded.Entries) != 2 || decoded.Entries[0].Title != "Rewritten: <example.com>" {
+		t.Errorf("expected the feed to decode with its entries, got %v %v", decoded, err)
+	}
+}
+
+func TestWriteEmptyFeed(t *testing.T) {
+	feed := NewFeed("id", "Nothing yet", "https://miru.site/reports/feed.atom")
+	buf := new(bytes.Buffer)
+	err := feed.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "0001-01-01") {
+		t.Errorf("expected an empty feed to have a real updated time, got %s", buf.String())
+	}
+}
@@ -11,4 +11,5 @@ import (
 // RegisterHandlers registers request handlers to a subrouter.
 func RegisterHandlers(r *mux.Router, cfg *config.Config, db *sql.DB) {
 	r.Handle("/panel", NewPanelPageHandler(cfg, db)).Methods("GET")
+	r.Handle("/subscription", NewSubscriptionHandler(cfg, db)).Methods("POST")
 }
@@ -22,18 +22,26 @@ const adminPanelPage string = "adminpanel.html"
 // panel page that links them to other pages where they can carry out
 // administrative tasks.
 type PanelPageHandler struct {
-	cfg *config.Config
-	db  *sql.DB
+	cfg       *config.Config
+	db        *sql.DB
+	Successes []string
 }
 
 // NewPanelPageHandler is the constructor function for an
 func NewPanelPageHandler(cfg *config.Config, db *sql.DB) PanelPageHandler {
 	return PanelPageHandler{
-		cfg: cfg,
-		db:  db,
+		cfg:       cfg,
+		db:        db,
+		Successes: []string{},
 	}
 }
 
+// PushSuccessMsg adds a new message that will be displayed on the page served by the
+// handler to indicate a successful operation.
+func (h *PanelPageHandler) PushSuccessMsg(msg string) {
+	h.Successes = append(h.Successes, msg)
+}
+
 // ServeHTTP serves the administrator panel page, which contains links to other
 // pages that admins can use to perform various actions.
 func (h PanelPageHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
@@ -50,6 +58,14 @@ func (h PanelPageHandler) ServeHTTP(res http.ResponseWriter, req *http.Request)
 		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, err == nil, false)
 		return
 	}
+	_, findErr := models.FindReportSubscription(h.db, activeUser)
+	subscribed := findErr == nil
+	csrfToken := models.GenerateAntiCSRFToken(h.db, auth.AntiCSRFTokenLength)
+	saveErr := csrfToken.Save(h.db)
+	if saveErr != nil {
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
+		return
+	}
 	// Serve the admin panel page.
 	t, err := template.ParseFiles(
 		path.Join(h.cfg.TemplateDir, adminPanelPage),
@@ -61,8 +77,10 @@ func (h PanelPageHandler) ServeHTTP(res http.ResponseWriter, req *http.Request)
 		return
 	}
 	t.Execute(res, struct {
+		Subscribed  bool
+		CSRFToken   string
 		UserIsAdmin bool
 		LoggedIn    bool
 		Successes   []string
-	}{true, true, []string{}})
+	}{subscribed, csrfToken.Token(), true, true, h.Successes})
 }
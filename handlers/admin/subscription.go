@@ -0,0 +1,75 @@
+package admin
+
+import (
+	"../../auth"
+	"../../config"
+	"../../models"
+	"../common"
+	"../fail"
+
+	"database/sql"
+	"fmt"
+	"net/http"
+)
+
+// SubscriptionHandler implements net/http.ServeHTTP to let administrators
+// choose whether to be emailed when monitors report significant changes.
+type SubscriptionHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewSubscriptionHandler is the constructor function for a SubscriptionHandler.
+func NewSubscriptionHandler(cfg *config.Config, db *sql.DB) SubscriptionHandler {
+	return SubscriptionHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP subscribes or unsubscribes the administrator making the request.
+func (h SubscriptionHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	// Check that the request is coming from an authenticated administrator.
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		fmt.Println("Could not find cookie", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	activeUser, err := models.FindSessionOwner(h.db, cookie.Value)
+	if err != nil || !activeUser.IsAdmin() {
+		fmt.Println("Could not get cookie owner", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, err == nil, false)
+		return
+	}
+	// Extract the data submitted in the form.
+	req.ParseForm()
+	csrfToken := req.FormValue("csrfToken")
+	if !models.VerifyAndDeleteAntiCSRFToken(h.db, csrfToken) {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, true, true)
+		return
+	}
+	subscribe := req.FormValue("subscribe") == "true"
+	subscription, findErr := models.FindReportSubscription(h.db, activeUser)
+	subscribed := findErr == nil
+	var saveErr error
+	message := ""
+	if subscribe && !subscribed {
+		subscription = models.NewReportSubscription(activeUser)
+		saveErr = subscription.Save(h.db)
+		message = fmt.Sprintf("Significant changes will be emailed to %s.", activeUser.Email())
+	} else if !subscribe && subscribed {
+		saveErr = subscription.Delete(h.db)
+		message = "You will no longer be emailed about significant changes."
+	}
+	if saveErr != nil {
+		fmt.Println("Could not update report subscription", saveErr)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
+		return
+	}
+	handler := NewPanelPageHandler(h.cfg, h.db)
+	if message != "" {
+		handler.PushSuccessMsg(message)
+	}
+	handler.ServeHTTP(res, req)
+}
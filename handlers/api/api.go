@@ -0,0 +1,106 @@
+package api
+
+import (
+	"../../auth"
+	"../../config"
+	"../../models"
+
+	"github.com/gorilla/mux"
+
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Version is the version of the API served by the handlers in this package,
+// which appears in the path of each of its endpoints.
+const Version string = "v1"
+
+// Errors produced by the API, containing messages that are safe to show the user.
+var (
+	ErrNotAuthenticated  = errors.New("you must be logged in to do that")
+	ErrNotAllowed        = errors.New("you are not allowed to do that")
+	ErrDatabaseOperation = errors.New("an internal database error occurred")
+	ErrNotJSON           = errors.New("request bodies must be JSON with the application/json content type")
+	ErrReadOnlyToken     = errors.New("this api token is read-only")
+)
+
+// bearerPrefix is the start of an Authorization header containing an API token.
+const bearerPrefix string = "Bearer "
+
+// errorResponse is the JSON body written when a request to the API fails.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// RegisterHandlers registers API request handlers to a subrouter.
+func RegisterHandlers(r *mux.Router, cfg *config.Config, db *sql.DB) {
+	r.Handle("/requests", NewListRequestsHandler(cfg, db)).Methods("GET")
+	r.Handle("/requests", NewCreateRequestHandler(cfg, db)).Methods("POST")
+	r.Handle("/requests/import", NewImportRequestsHandler(cfg, db)).Methods("POST")
+	r.Handle("/monitors", NewListMonitorsHandler(cfg, db)).Methods("GET")
+	r.Handle("/reports", NewListReportsHandler(cfg, db)).Methods("GET")
+}
+
+// authenticate finds the archiver making a request to the API, writing an
+// error response if they aren't logged in or, when admin is true, aren't an
+// administrator. Programs can authenticate with an API token sent in an
+// Authorization header like "Bearer <token>", and read-only tokens can only be
+// used to make GET requests. The second value returned is false if an error
+// was written.
+func authenticate(res http.ResponseWriter, req *http.Request, db *sql.DB, admin bool) (models.Archiver, bool) {
+	archiver, scope, err := findRequester(req, db)
+	if err != nil {
+		fmt.Println("Could not authenticate API request", err)
+		res.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(res, http.StatusUnauthorized, ErrNotAuthenticated)
+		return models.Archiver{}, false
+	}
+	if scope == models.ReadOnlyScope && req.Method != "GET" && req.Method != "HEAD" {
+		writeError(res, http.StatusForbidden, ErrReadOnlyToken)
+		return models.Archiver{}, false
+	}
+	if admin && !archiver.IsAdmin() {
+		writeError(res, http.StatusForbidden, ErrNotAllowed)
+		return models.Archiver{}, false
+	}
+	return archiver, true
+}
+
+// findRequester finds the archiver making a request, either from the API
+// token in the request's Authorization header or, if there isn't one, from
+// their session cookie. Sessions can do anything their owner can.
+func findRequester(req *http.Request, db *sql.DB) (models.Archiver, models.APITokenScope, error) {
+	authorization := req.Header.Get("Authorization")
+	if authorization != "" {
+		if !strings.HasPrefix(authorization, bearerPrefix) {
+			return models.Archiver{}, models.ReadOnlyScope, errors.New("unsupported authorization scheme")
+		}
+		token := strings.TrimSpace(strings.TrimPrefix(authorization, bearerPrefix))
+		return models.FindAPITokenOwner(db, token)
+	}
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		return models.Archiver{}, models.ReadOnlyScope, err
+	}
+	archiver, err := models.FindSessionOwner(db, cookie.Value)
+	return archiver, models.AdminScope, err
+}
+
+// writeJSON writes a value to the response as JSON with a given status code.
+func writeJSON(res http.ResponseWriter, status int, value interface{}) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+	encodeErr := json.NewEncoder(res).Encode(value)
+	if encodeErr != nil {
+		fmt.Println("Could not encode API response", encodeErr)
+	}
+}
+
+// writeError writes an error to the response as JSON with a given status code.
+func writeError(res http.ResponseWriter, status int, err error) {
+	writeJSON(res, status, errorResponse{err.Error()})
+}
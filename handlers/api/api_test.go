@@ -0,0 +1,84 @@
+package api
+
+import (
+	"../../models"
+
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openTestDB creates an in-memory database with every table miru uses, or
+// skips the test if the SQLite driver can't be used.
+func openTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err == nil {
+		err = db.Ping()
+	}
+	if err != nil {
+		t.Skipf("could not open an in-memory database: %v", err)
+	}
+	// Every connection to an in-memory database gets a database of its own.
+	db.SetMaxOpenConns(1)
+	err = models.InitializeTables(db)
+	if err != nil {
+		db.Close()
+		t.Fatalf("could not create tables: %v", err)
+	}
+	return db
+}
+
+func TestAuthenticateLimitsReadOnlyTokens(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	owner := models.NewArchiver("owner@example.com", "hash")
+	if err := owner.Save(db); err != nil {
+		t.Fatal(err)
+	}
+	readOnly := models.NewAPIToken(owner, "reader", models.ReadOnlyScope)
+	admin := models.NewAPIToken(owner, "writer", models.AdminScope)
+	if err := readOnly.Save(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := admin.Save(db); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		token   string
+		method  string
+		allowed bool
+		status  int
+	}{
+		{readOnly.Token(), "GET", true, http.StatusOK},
+		{readOnly.Token(), "HEAD", true, http.StatusOK},
+		{readOnly.Token(), "POST", false, http.StatusForbidden},
+		{readOnly.Token(), "DELETE", false, http.StatusForbidden},
+		{admin.Token(), "POST", true, http.StatusOK},
+		{"", "GET", false, http.StatusUnauthorized},
+		{"12.not-a-secret", "GET", false, http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, "/api/v1/requests", nil)
+		if c.token != "" {
+			req.Header.Set("Authorization", bearerPrefix+c.token)
+		}
+		res := httptest.NewRecorder()
+		found, allowed := authenticate(res, req, db, false)
+		if allowed != c.allowed || res.Code != c.status {
+			t.Errorf("expected %s with token %q to be allowed: %v with status %d, got %v with %d",
+				c.method, c.token, c.allowed, c.status, allowed, res.Code)
+		}
+		if allowed && found.ID() != owner.ID() {
+			t.Errorf("expected to authenticate the token's owner, got %d", found.ID())
+		}
+	}
+	req := httptest.NewRequest("GET", "/api/v1/monitors", nil)
+	req.Header.Set("Authorization", bearerPrefix+readOnly.Token())
+	res := httptest.NewRecorder()
+	if _, allowed := authenticate(res, req, db, true); allowed || res.Code != http.StatusForbidden {
+		t.Errorf("expected a token belonging to an archiver who isn't an administrator not to be allowed, got %d", res.Code)
+	}
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"../../config"
+	"../../models"
+
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// monitorJSON is the JSON representation of a monitor.
+type monitorJSON struct {
+	ID                     int       `json:"id"`
+	RequestID              int       `json:"requestId"`
+	URL                    string    `json:"url"`
+	Interpreter            string    `json:"interpreter"`
+	WaitMinutes            float64   `json:"waitMinutes"`
+	ExpectedRunTimeSeconds float64   `json:"expectedRunTimeSeconds"`
+	CreatedAt              time.Time `json:"createdAt"`
+	LastRun                time.Time `json:"lastRun"`
+	Status                 string    `json:"status"`
+	ScriptVersion          int       `json:"scriptVersion"`
+}
+
+// ListMonitorsHandler implements net/http.ServeHTTP to serve the list of
+// monitors to administrators.
+type ListMonitorsHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewListMonitorsHandler is the constructor function for a ListMonitorsHandler.
+func NewListMonitorsHandler(cfg *config.Config, db *sql.DB) ListMonitorsHandler {
+	return ListMonitorsHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP serves every monitor, along with the url of the site it monitors.
+func (h ListMonitorsHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	_, authenticated := authenticate(res, req, h.db, true)
+	if !authenticated {
+		return
+	}
+	monitors, findErr := models.ListMonitors(h.db)
+	if findErr != nil {
+		fmt.Println("Could not list monitors", findErr)
+		writeError(res, http.StatusInternalServerError, ErrDatabaseOperation)
+		return
+	}
+	encoded := []monitorJSON{}
+	for _, monitor := range monitors {
+		url := ""
+		request, findErr := models.FindRequest(h.db, monitor.CreatedFor())
+		if findErr == nil {
+			url = request.URL()
+		}
+		encoded = append(encoded, monitorJSON{
+			ID:                     monitor.ID(),
+			RequestID:              monitor.CreatedFor(),
+			URL:                    url,
+			Interpreter:            string(monitor.Interpreter()),
+			WaitMinutes:            monitor.WaitPeriod().Minutes(),
+			ExpectedRunTimeSeconds: monitor.ExpectedRunTime().Seconds(),
+			CreatedAt:              monitor.CreatedAt(),
+			LastRun:                monitor.LastRun(),
+			Status:                 string(monitor.Status()),
+			ScriptVersion:          monitor.ScriptVersion(),
+		})
+	}
+	writeJSON(res, http.StatusOK, encoded)
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"../../config"
+	"../../models"
+
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Limits on the number of reports that can be listed at once.
+const (
+	defaultReportsLimit uint = 50
+	maxReportsLimit     uint = 500
+)
+
+// reportJSON is the JSON representation of a report.
+type reportJSON struct {
+	ID               int                    `json:"id"`
+	MonitorID        int                    `json:"monitorId"`
+	CreatedAt        time.Time              `json:"createdAt"`
+	Significance     models.Importance      `json:"significance"`
+	SignificanceName string                 `json:"significanceName"`
+	Message          string                 `json:"message"`
+	Checksum         string                 `json:"checksum"`
+	State            map[string]interface{} `json:"state"`
+	Content          string                 `json:"content"`
+	ScriptVersion    int                    `json:"scriptVersion"`
+}
+
+// reportListJSON is the JSON representation of a page of reports.
+type reportListJSON struct {
+	Total   uint         `json:"total"`
+	Limit   uint         `json:"limit"`
+	Offset  uint         `json:"offset"`
+	Reports []reportJSON `json:"reports"`
+}
+
+// ListReportsHandler implements net/http.ServeHTTP to serve reports to
+// administrators.
+type ListReportsHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewListReportsHandler is the constructor function for a ListReportsHandler.
+func NewListReportsHandler(cfg *config.Config, db *sql.DB) ListReportsHandler {
+	return ListReportsHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP serves a page of reports, newest first. The reports can be
+// filtered with the following url parameters:
+// monitor - the ID of the monitor that produced the reports.
+// significance - a level of change significance, as a number or a name like "rewritten".
+// minSignificance - the least significant change to include.
+// since, until - times written in RFC 3339 format, like 2017-01-31T15:04:05Z.
+// limit, offset - which page of reports to serve.
+func (h ListReportsHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	_, authenticated := authenticate(res, req, h.db, true)
+	if !authenticated {
+		return
+	}
+	filter, limit, offset, parseErr := parseReportFilter(req.URL.Query())
+	if parseErr != nil {
+		writeError(res, http.StatusBadRequest, parseErr)
+		return
+	}
+	total, countErr := models.CountReports(h.db, filter)
+	if countErr != nil {
+		fmt.Println("Could not count reports", countErr)
+		writeError(res, http.StatusInternalServerError, ErrDatabaseOperation)
+		return
+	}
+	reports, findErr := models.ListReports(h.db, filter, limit, offset)
+	if findErr != nil {
+		fmt.Println("Could not list reports", findErr)
+		writeError(res, http.StatusInternalServerError, ErrDatabaseOperation)
+		return
+	}
+	encoded := reportListJSON{total, limit, offset, []reportJSON{}}
+	for _, report := range reports {
+		encoded.Reports = append(encoded.Reports, reportJSON{
+			ID:               report.ID(),
+			MonitorID:        report.CreatedBy(),
+			CreatedAt:        report.CreatedAt(),
+			Significance:     report.Change(),
+			SignificanceName: report.Change().String(),
+			Message:          report.Message(),
+			Checksum:         report.Checksum(),
+			State:            report.State(),
+			Content:          report.Content(),
+			ScriptVersion:    report.ScriptVersion(),
+		})
+	}
+	writeJSON(res, http.StatusOK, encoded)
+}
+
+// parseReportFilter reads the url parameters used to filter reports.
+func parseReportFilter(params url.Values) (models.ReportFilter, uint, uint, error) {
+	filter := models.NewReportFilter()
+	limit, offset := defaultReportsLimit, uint(0)
+	if params.Get("monitor") != "" {
+		monitorID, parseErr := strconv.Atoi(params.Get("monitor"))
+		if parseErr != nil || monitorID < 0 {
+			return filter, 0, 0, fmt.Errorf("%q is not a monitor id", params.Get("monitor"))
+		}
+		filter.Monitor = monitorID
+	}
+	if params.Get("significance") != "" {
+		significance, parseErr := models.ParseImportance(params.Get("significance"))
+		if parseErr != nil {
+			return filter, 0, 0, parseErr
+		}
+		filter.MinChange, filter.MaxChange = significance, significance
+	}
+	if params.Get("minSignificance") != "" {
+		significance, parseErr := models.ParseImportance(params.Get("minSignificance"))
+		if parseErr != nil {
+			return filter, 0, 0, parseErr
+		}
+		if significance > filter.MinChange {
+			filter.MinChange = significance
+		}
+	}
+	for name, bound := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if params.Get(name) == "" {
+			continue
+		}
+		parsed, parseErr := time.Parse(time.RFC3339, params.Get(name))
+		if parseErr != nil {
+			return filter, 0, 0, fmt.Errorf("%s must be a time written like 2017-01-31T15:04:05Z", name)
+		}
+		*bound = parsed.Local()
+	}
+	if params.Get("limit") != "" {
+		parsed, parseErr := strconv.ParseUint(params.Get("limit"), 10, 32)
+		if parseErr != nil || parsed == 0 || uint(parsed) > maxReportsLimit {
+			return filter, 0, 0, fmt.Errorf("limit must be between 1 and %d", maxReportsLimit)
+		}
+		limit = uint(parsed)
+	}
+	if params.Get("offset") != "" {
+		parsed, parseErr := strconv.ParseUint(params.Get("offset"), 10, 32)
+		if parseErr != nil {
+			return filter, 0, 0, fmt.Errorf("%q is not a valid offset", params.Get("offset"))
+		}
+		offset = uint(parsed)
+	}
+	return filter, limit, offset, nil
+}
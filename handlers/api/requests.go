@@ -0,0 +1,218 @@
+package api
+
+import (
+	"../../config"
+	"../../models"
+
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// maxRequestBodyBytes is the largest request body that the API will decode.
+const maxRequestBodyBytes int64 = 64 * 1024
+
+// requestJSON is the JSON representation of a request to have a site monitored.
+type requestJSON struct {
+	ID           int       `json:"id"`
+	URL          string    `json:"url"`
+	Instructions string    `json:"instructions"`
+	CreatedBy    int       `json:"createdBy"`
+	CreatedAt    time.Time `json:"createdAt"`
+	State        string    `json:"state"`
+}
+
+// newRequestJSON is the JSON body expected when creating a request.
+type newRequestJSON struct {
+	URL          string `json:"url"`
+	Instructions string `json:"instructions"`
+}
+
+// encodeRequest converts a request into its JSON representation.
+func encodeRequest(request models.Request) requestJSON {
+	return requestJSON{
+		ID:           request.ID(),
+		URL:          request.URL(),
+		Instructions: request.Instructions(),
+		CreatedBy:    request.Creator(),
+		CreatedAt:    request.CreatedAt(),
+		State:        string(request.State()),
+	}
+}
+
+// ListRequestsHandler implements net/http.ServeHTTP to serve the list of
+// pending requests to administrators.
+type ListRequestsHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewListRequestsHandler is the constructor function for a ListRequestsHandler.
+func NewListRequestsHandler(cfg *config.Config, db *sql.DB) ListRequestsHandler {
+	return ListRequestsHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP serves every request that has not been fulfilled or rejected.
+func (h ListRequestsHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	_, authenticated := authenticate(res, req, h.db, true)
+	if !authenticated {
+		return
+	}
+	requests, findErr := models.ListPendingRequests(h.db)
+	if findErr != nil {
+		fmt.Println("Could not list pending requests", findErr)
+		writeError(res, http.StatusInternalServerError, ErrDatabaseOperation)
+		return
+	}
+	encoded := []requestJSON{}
+	for _, request := range requests {
+		encoded = append(encoded, encodeRequest(request))
+	}
+	writeJSON(res, http.StatusOK, encoded)
+}
+
+// CreateRequestHandler implements net/http.ServeHTTP to let archivers make
+// requests to have sites monitored.
+type CreateRequestHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewCreateRequestHandler is the constructor function for a CreateRequestHandler.
+func NewCreateRequestHandler(cfg *config.Config, db *sql.DB) CreateRequestHandler {
+	return CreateRequestHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP creates a request from a JSON body containing the url to monitor
+// and instructions for the administrators, and responds with the new request.
+// Only JSON bodies are accepted, which browsers will not send to another site
+// without its permission, so that other sites can't make requests on behalf of
+// a logged in archiver.
+func (h CreateRequestHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	archiver, authenticated := authenticate(res, req, h.db, false)
+	if !authenticated {
+		return
+	}
+	mediaType, _, parseErr := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if parseErr != nil || mediaType != "application/json" {
+		writeError(res, http.StatusUnsupportedMediaType, ErrNotJSON)
+		return
+	}
+	body := newRequestJSON{}
+	decoder := json.NewDecoder(http.MaxBytesReader(res, req.Body, maxRequestBodyBytes))
+	decoder.DisallowUnknownFields()
+	decodeErr := decoder.Decode(&body)
+	if decodeErr != nil {
+		writeError(res, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", decodeErr))
+		return
+	}
+	cleanURL, parseErr := models.CleanURL(body.URL)
+	if parseErr != nil || body.URL == "" {
+		writeError(res, http.StatusBadRequest, errors.New("please specify a valid url to monitor"))
+		return
+	}
+	request := models.NewRequest(archiver, cleanURL, body.Instructions)
+	saveErr := request.Save(h.db)
+	if saveErr != nil {
+		fmt.Println("Could not save request", saveErr)
+		writeError(res, http.StatusInternalServerError, ErrDatabaseOperation)
+		return
+	}
+	writeJSON(res, http.StatusCreated, encodeRequest(request))
+}
+
+// maxImportBodyBytes is the largest body that can be sent to import requests.
+const maxImportBodyBytes int64 = 4 * 1024 * 1024
+
+// importFormats maps the media types that requests can be imported from to
+// their import format.
+var importFormats = map[string]string{
+	"text/csv":         models.ImportCSV,
+	"application/json": models.ImportJSON,
+}
+
+// importRowJSON is the JSON representation of a row of an import that
+// could not be imported.
+type importRowJSON struct {
+	Row          int    `json:"row"`
+	URL          string `json:"url"`
+	Instructions string `json:"instructions"`
+	Error        string `json:"error,omitempty"`
+}
+
+// importErrorResponse is the JSON body written when some of the rows of an
+// import are invalid.
+type importErrorResponse struct {
+	Error string          `json:"error"`
+	Rows  []importRowJSON `json:"rows"`
+}
+
+// ImportRequestsHandler implements net/http.ServeHTTP to let administrators
+// create many requests at once.
+type ImportRequestsHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewImportRequestsHandler is the constructor function for an ImportRequestsHandler.
+func NewImportRequestsHandler(cfg *config.Config, db *sql.DB) ImportRequestsHandler {
+	return ImportRequestsHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP creates a request for each row of a CSV body, sent as text/csv, or
+// of a JSON array, sent as application/json, in a single transaction, and
+// responds with the new requests. If any row is invalid, nothing is created
+// and every row is listed with its problem, if any.
+func (h ImportRequestsHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	archiver, authenticated := authenticate(res, req, h.db, true)
+	if !authenticated {
+		return
+	}
+	mediaType, _, parseErr := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	format, supported := importFormats[mediaType]
+	if parseErr != nil || !supported {
+		writeError(res, http.StatusUnsupportedMediaType, errors.New("requests can only be imported from text/csv or application/json"))
+		return
+	}
+	rows, decodeErr := models.DecodeImport(format, http.MaxBytesReader(res, req.Body, maxImportBodyBytes))
+	if decodeErr != nil {
+		writeError(res, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", decodeErr))
+		return
+	}
+	requests, importErr := models.ImportRequests(h.db, archiver, rows)
+	if importErr == models.ErrInvalidImport {
+		encoded := []importRowJSON{}
+		for _, row := range rows {
+			problem := ""
+			if row.Err != nil {
+				problem = row.Err.Error()
+			}
+			encoded = append(encoded, importRowJSON{row.Row, row.URL, row.Instructions, problem})
+		}
+		writeJSON(res, http.StatusUnprocessableEntity, importErrorResponse{importErr.Error(), encoded})
+		return
+	}
+	if importErr != nil {
+		fmt.Println("Could not import requests", importErr)
+		writeError(res, http.StatusInternalServerError, ErrDatabaseOperation)
+		return
+	}
+	encoded := []requestJSON{}
+	for _, request := range requests {
+		encoded = append(encoded, encodeRequest(request))
+	}
+	writeJSON(res, http.StatusCreated, encoded)
+}
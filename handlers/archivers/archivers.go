@@ -17,4 +17,7 @@ func RegisterHandlers(r *mux.Router, cfg *config.Config, db *sql.DB) {
 	r.Handle("/register", NewRegisterPageHandler(cfg)).Methods("GET")
 	r.Handle("/register", NewRegisterHandler(cfg, db)).Methods("POST")
 	r.Handle("/promote", NewPromoteHandler(cfg, db)).Methods("POST")
+	r.Handle("/tokens", NewTokensPageHandler(cfg, db)).Methods("GET")
+	r.Handle("/tokens/create", NewCreateTokenHandler(cfg, db)).Methods("POST")
+	r.Handle("/tokens/revoke", NewRevokeTokenHandler(cfg, db)).Methods("POST")
 }
@@ -0,0 +1,257 @@
+package archivers
+
+import (
+	"../../auth"
+	"../../config"
+	"../../models"
+	"../common"
+	"../fail"
+
+	"database/sql"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tokensPage is the name of the template HTML file that lists an archiver's
+// API tokens.
+const tokensPage string = "tokens.html"
+
+// maxTokenNameLength is the longest name that can be given to an API token.
+const maxTokenNameLength int = 128
+
+// TokensPageHandler implements net/http.ServeHTTP to serve a page listing the
+// API tokens belonging to the logged in archiver, with forms to create new
+// tokens and revoke existing ones.
+type TokensPageHandler struct {
+	cfg       *config.Config
+	db        *sql.DB
+	newToken  string
+	Successes []string
+}
+
+// NewTokensPageHandler is the constructor function for a TokensPageHandler.
+func NewTokensPageHandler(cfg *config.Config, db *sql.DB) TokensPageHandler {
+	return TokensPageHandler{
+		cfg:       cfg,
+		db:        db,
+		newToken:  "",
+		Successes: []string{},
+	}
+}
+
+// PushSuccessMsg adds a new message that will be displayed on the page served by the
+// handler to indicate a successful operation.
+func (h *TokensPageHandler) PushSuccessMsg(msg string) {
+	h.Successes = append(h.Successes, msg)
+}
+
+// ShowNewToken displays a token that was just created on the page, since it
+// can't be retrieved later.
+func (h *TokensPageHandler) ShowNewToken(token string) {
+	h.newToken = token
+}
+
+// ServeHTTP serves a page with a table of the archiver's API tokens.
+func (h TokensPageHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	// Check that the request is coming from an authenticated archiver.
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	activeUser, err := models.FindSessionOwner(h.db, cookie.Value)
+	if err != nil {
+		fmt.Println("Could not get cookie owner", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	tokens, findErr := models.ListAPITokensForOwner(h.db, activeUser)
+	if findErr != nil {
+		fmt.Println("Could not get api tokens", findErr)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, activeUser.IsAdmin())
+		return
+	}
+	type Data struct {
+		ID         int
+		Name       string
+		Scope      string
+		CreatedAt  time.Time
+		LastUsedAt time.Time
+		NeverUsed  bool
+		IsRevoked  bool
+		CSRFToken  string
+	}
+	data := []Data{}
+	for _, token := range tokens {
+		csrfToken := models.GenerateAntiCSRFToken(h.db, auth.AntiCSRFTokenLength)
+		saveErr := csrfToken.Save(h.db)
+		if saveErr != nil {
+			fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, activeUser.IsAdmin())
+			return
+		}
+		data = append(data, Data{
+			ID:         token.ID(),
+			Name:       token.Name(),
+			Scope:      string(token.Scope()),
+			CreatedAt:  token.CreatedAt(),
+			LastUsedAt: token.LastUsedAt(),
+			NeverUsed:  token.LastUsedAt().IsZero(),
+			IsRevoked:  token.IsRevoked(),
+			CSRFToken:  csrfToken.Token(),
+		})
+	}
+	csrfToken := models.GenerateAntiCSRFToken(h.db, auth.AntiCSRFTokenLength)
+	saveErr := csrfToken.Save(h.db)
+	if saveErr != nil {
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, activeUser.IsAdmin())
+		return
+	}
+	t, err := template.ParseFiles(
+		path.Join(h.cfg.TemplateDir, tokensPage),
+		path.Join(h.cfg.TemplateDir, common.HeadTemplate),
+		path.Join(h.cfg.TemplateDir, common.NavTemplate))
+	if err != nil {
+		fmt.Println("Error parsing tokens page template", err)
+		fail.InternalError(res, req, h.cfg, common.ErrTemplateLoad, true, activeUser.IsAdmin())
+		return
+	}
+	t.Execute(res, struct {
+		Tokens      []Data
+		NewToken    string
+		CSRFToken   string
+		LoggedIn    bool
+		UserIsAdmin bool
+		Successes   []string
+	}{data, h.newToken, csrfToken.Token(), true, activeUser.IsAdmin(), h.Successes})
+}
+
+// CreateTokenHandler implements net/http.ServeHTTP to handle requests from
+// archivers to create new API tokens.
+type CreateTokenHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewCreateTokenHandler is the constructor function for a CreateTokenHandler.
+func NewCreateTokenHandler(cfg *config.Config, db *sql.DB) CreateTokenHandler {
+	return CreateTokenHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP creates a new API token with the name and scope submitted, and
+// then shows the token to the archiver.
+func (h CreateTokenHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	// Check that the request is coming from an authenticated archiver.
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	activeUser, err := models.FindSessionOwner(h.db, cookie.Value)
+	if err != nil {
+		fmt.Println("Could not get cookie owner", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	// Extract the data submitted in the form.
+	req.ParseForm()
+	csrfToken := req.FormValue("csrfToken")
+	if !models.VerifyAndDeleteAntiCSRFToken(h.db, csrfToken) {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, true, activeUser.IsAdmin())
+		return
+	}
+	name := strings.TrimSpace(req.FormValue("name"))
+	if name == "" || len(name) > maxTokenNameLength {
+		fail.BadRequest(res, req, h.cfg,
+			fmt.Errorf("tokens must be given a name of at most %d characters", maxTokenNameLength),
+			true, activeUser.IsAdmin())
+		return
+	}
+	scope := models.APITokenScope(req.FormValue("scope"))
+	if scope != models.ReadOnlyScope && scope != models.AdminScope {
+		fail.BadRequest(res, req, h.cfg, common.ErrGenericInvalidData, true, activeUser.IsAdmin())
+		return
+	}
+	if scope == models.AdminScope && !activeUser.IsAdmin() {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, true, false)
+		return
+	}
+	token := models.NewAPIToken(activeUser, name, scope)
+	saveErr := token.Save(h.db)
+	if saveErr != nil {
+		fmt.Println("Could not save api token", saveErr)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, activeUser.IsAdmin())
+		return
+	}
+	handler := NewTokensPageHandler(h.cfg, h.db)
+	handler.ShowNewToken(token.Token())
+	handler.PushSuccessMsg(fmt.Sprintf("Created the API token %s.", name))
+	handler.ServeHTTP(res, req)
+}
+
+// RevokeTokenHandler implements net/http.ServeHTTP to handle requests from
+// archivers to revoke their API tokens.
+type RevokeTokenHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewRevokeTokenHandler is the constructor function for a RevokeTokenHandler.
+func NewRevokeTokenHandler(cfg *config.Config, db *sql.DB) RevokeTokenHandler {
+	return RevokeTokenHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP revokes one of the archiver's API tokens so that it can no longer
+// be used.
+func (h RevokeTokenHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	// Check that the request is coming from an authenticated archiver.
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	activeUser, err := models.FindSessionOwner(h.db, cookie.Value)
+	if err != nil {
+		fmt.Println("Could not get cookie owner", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	// Extract the data submitted in the form.
+	req.ParseForm()
+	csrfToken := req.FormValue("csrfToken")
+	if !models.VerifyAndDeleteAntiCSRFToken(h.db, csrfToken) {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, true, activeUser.IsAdmin())
+		return
+	}
+	tokenID, parseErr := strconv.Atoi(req.FormValue("tokenID"))
+	if parseErr != nil {
+		fail.BadRequest(res, req, h.cfg, common.ErrGenericInvalidData, true, activeUser.IsAdmin())
+		return
+	}
+	// Archivers can only revoke their own tokens.
+	token, findErr := models.FindAPIToken(h.db, tokenID)
+	if findErr != nil || token.Owner() != activeUser.ID() {
+		fail.BadRequest(res, req, h.cfg, errors.New("no such api token"), true, activeUser.IsAdmin())
+		return
+	}
+	revokeErr := token.Revoke(h.db)
+	if revokeErr != nil {
+		fmt.Println("Could not revoke api token", revokeErr)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, activeUser.IsAdmin())
+		return
+	}
+	handler := NewTokensPageHandler(h.cfg, h.db)
+	handler.PushSuccessMsg(fmt.Sprintf("Revoked the API token %s.", token.Name()))
+	handler.ServeHTTP(res, req)
+}
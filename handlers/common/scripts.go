@@ -0,0 +1,215 @@
+package common
+
+import (
+	"../../config"
+	"../../interpreters"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// filenameLength is the number of random bytes to get to generate names
+// for uploaded scripts with.
+const filenameLength int = 16
+
+// testedScriptLifetime is how long a script uploaded to be tested is kept for
+// an administrator to create a monitor for it, which is as long as the
+// anti-CSRF token it is kept under stays valid.
+const testedScriptLifetime = 1 * time.Hour
+
+// MaxScriptNoteLength is the longest note describing a new version of a
+// monitor script that can be saved.
+const MaxScriptNoteLength int = 1000
+
+// ErrScriptNoteTooLong is returned when the note describing a new version of a
+// monitor script is too long.
+var ErrScriptNoteTooLong = fmt.Errorf("notes about a script must be at most %d characters long", MaxScriptNoteLength)
+
+// SaveUploadedScript saves the monitor script uploaded in a form to a new file
+// in scriptDir and returns the name of the file created, along with the
+// hex-encoded SHA256 checksum of the script.
+func SaveUploadedScript(req *http.Request, scriptDir, ext string) (string, string, error) {
+	file, _, openErr := req.FormFile("script")
+	if openErr != nil {
+		return "", "", openErr
+	}
+	defer file.Close()
+	// Find a place to save the file to on disk.
+	filename := generateUniqueFilename(scriptDir, ext)
+	toDisk, openErr := os.Create(filename)
+	if openErr != nil {
+		return "", "", openErr
+	}
+	defer toDisk.Close()
+	hash := sha256.New()
+	_, copyErr := io.Copy(io.MultiWriter(toDisk, hash), file)
+	return filename, hex.EncodeToString(hash.Sum(nil)), copyErr
+}
+
+// SaveTestedScript saves the monitor script uploaded in a form to a new file
+// among the scripts uploaded to be tested, rather than in the directory that
+// monitors' scripts are kept in, and returns the name of the file created.
+// Tested scripts that were never used to create a monitor are deleted here.
+func SaveTestedScript(req *http.Request, ext string) (string, error) {
+	dir, err := testedScriptDir()
+	if err != nil {
+		return "", err
+	}
+	removeStaleTestedScripts(dir)
+	filename, _, err := SaveUploadedScript(req, dir, ext)
+	return filename, err
+}
+
+// KeepTestedScript sets aside a script that has been tested under the
+// anti-CSRF token embedded in the page showing the result of the test, so that
+// only the administrator who submits that token can create a monitor for it.
+func KeepTestedScript(filename, csrfToken string) error {
+	kept, err := testedScriptPath(csrfToken, strings.TrimPrefix(path.Ext(filename), "."))
+	if err != nil {
+		return err
+	}
+	err = os.Rename(filename, kept)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	return os.Chtimes(kept, now, now)
+}
+
+// FindTestedScript finds the path to a script set aside by KeepTestedScript
+// under the anti-CSRF token submitted with a form.
+func FindTestedScript(csrfToken, ext string) (string, error) {
+	kept, err := testedScriptPath(csrfToken, ext)
+	if err != nil {
+		return "", err
+	}
+	_, err = os.Stat(kept)
+	return kept, err
+}
+
+// TakeTestedScript moves a script set aside by KeepTestedScript into scriptDir
+// and returns the path to its new file, along with the hex-encoded SHA256
+// checksum of the script.
+func TakeTestedScript(scriptDir, csrfToken, ext string) (string, string, error) {
+	kept, err := FindTestedScript(csrfToken, ext)
+	if err != nil {
+		return "", "", err
+	}
+	fromTemp, err := os.Open(kept)
+	if err != nil {
+		return "", "", err
+	}
+	defer fromTemp.Close()
+	// Scripts are copied rather than renamed, since the temporary directory
+	// is often on another filesystem.
+	filename := generateUniqueFilename(scriptDir, ext)
+	toDisk, err := os.Create(filename)
+	if err != nil {
+		return "", "", err
+	}
+	defer toDisk.Close()
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(toDisk, hash), fromTemp)
+	if err != nil {
+		os.Remove(filename)
+		return "", "", err
+	}
+	os.Remove(kept)
+	return filename, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// testedScriptDir creates, if it doesn't exist, and returns the temporary
+// directory that scripts uploaded to be tested are saved to.
+func testedScriptDir() (string, error) {
+	dir := path.Join(os.TempDir(), "miru-tested-scripts")
+	err := os.MkdirAll(dir, 0700)
+	return dir, err
+}
+
+// testedScriptPath produces the path that a tested script is kept at under an
+// anti-CSRF token.
+func testedScriptPath(csrfToken, ext string) (string, error) {
+	if csrfToken == "" || csrfToken != path.Base(csrfToken) || strings.HasPrefix(csrfToken, ".") {
+		return "", errors.New("invalid anti-csrf token")
+	}
+	dir, err := testedScriptDir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(dir, csrfToken+"."+ext), nil
+}
+
+// removeStaleTestedScripts deletes the scripts in dir that were tested too
+// long ago for a monitor to still be created for them.
+func removeStaleTestedScripts(dir string) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		if time.Since(file.ModTime()) > testedScriptLifetime {
+			os.Remove(path.Join(dir, file.Name()))
+		}
+	}
+}
+
+// generateUniqueFilename produces a filename that is guaranteed to be unique.
+// It continuously generates 16-byte script names, encoded as hex, until one
+// is created that isn't already taken.
+func generateUniqueFilename(scriptDir string, ext string) string {
+	// We don't need cryptographically random names- pseudorandom will do.
+	rand.Seed(int64(time.Now().Unix()))
+	bytes := make([]byte, filenameLength)
+	for {
+		bytesRead, readErr := rand.Read(bytes)
+		for readErr != nil || bytesRead != filenameLength {
+			bytesRead, readErr = rand.Read(bytes)
+		}
+		filename := path.Join(scriptDir, hex.EncodeToString(bytes))
+		f, openErr := os.Open(filename)
+		if openErr != nil {
+			return filename + "." + ext
+		}
+		f.Close()
+	}
+}
+
+// ScriptType is one of the options in the upload form's filetype dropdown menu.
+type ScriptType struct {
+	Value string
+	Label string
+}
+
+// ScriptTypes lists the options for the upload form's filetype dropdown menu,
+// which has one for each interpreter configured, in the order they were
+// configured in.
+func ScriptTypes(cfg *config.Config) []ScriptType {
+	registry, _ := interpreters.Load(cfg)
+	types := []ScriptType{}
+	for _, interpreter := range registry.List() {
+		types = append(types, ScriptType{interpreter.Name(), interpreter.Label()})
+	}
+	return types
+}
+
+// FiletypeExtension converts a filetype, the values in the upload form's
+// filetype dropdown menu, into the extension that scripts run by the
+// interpreter of the same name are saved with.
+func FiletypeExtension(cfg *config.Config, filetype string) (string, error) {
+	registry, _ := interpreters.Load(cfg)
+	interpreter, err := registry.Find(filetype)
+	if err != nil {
+		return "", err
+	}
+	return interpreter.Extension(), nil
+}
@@ -3,10 +3,13 @@ package handlers
 import (
 	"../config"
 	"./admin"
+	"./api"
 	"./archivers"
 	"./index"
+	"./monitors"
 	"./reports"
 	"./requests"
+	"./webhooks"
 
 	"github.com/gorilla/mux"
 
@@ -16,13 +19,19 @@ import (
 // RegisterHandlers registers all of our request handlers.
 func RegisterHandlers(r *mux.Router, cfg *config.Config, db *sql.DB) {
 	adminRouter := r.PathPrefix("/admin").Subrouter()
+	apiRouter := r.PathPrefix("/api/" + api.Version).Subrouter()
 	archiversRouter := r.PathPrefix("/archivers").Subrouter()
 	indexRouter := r.PathPrefix("/").Subrouter()
+	monitorsRouter := r.PathPrefix("/monitors").Subrouter()
 	reportsRouter := r.PathPrefix("/reports").Subrouter()
 	requestsRouter := r.PathPrefix("/requests").Subrouter()
+	webhooksRouter := r.PathPrefix("/webhooks").Subrouter()
 	admin.RegisterHandlers(adminRouter, cfg, db)
+	api.RegisterHandlers(apiRouter, cfg, db)
 	archivers.RegisterHandlers(archiversRouter, cfg, db)
 	index.RegisterHandlers(indexRouter, cfg, db)
+	monitors.RegisterHandlers(monitorsRouter, cfg, db)
 	reports.RegisterHandlers(reportsRouter, cfg, db)
 	requests.RegisterHandlers(requestsRouter, cfg, db)
+	webhooks.RegisterHandlers(webhooksRouter, cfg, db)
 }
@@ -0,0 +1,69 @@
+package monitors
+
+import (
+	"../../auth"
+	"../../config"
+	"../../models"
+	"../common"
+	"../fail"
+
+	"github.com/gorilla/mux"
+
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// RegisterHandlers registers monitor request handlers to a subrouter.
+func RegisterHandlers(r *mux.Router, cfg *config.Config, db *sql.DB) {
+	r.Handle("/view", NewViewPageHandler(cfg, db)).Methods("GET")
+	r.Handle("/schedule", NewScheduleHandler(cfg, db)).Methods("POST")
+	r.Handle("/script", NewReplaceScriptHandler(cfg, db)).Methods("POST")
+	r.Handle("/rollback", NewRollBackHandler(cfg, db)).Methods("POST")
+	r.Handle("/secrets", NewSetSecretHandler(cfg, db)).Methods("POST")
+	r.Handle("/secrets/delete", NewDeleteSecretHandler(cfg, db)).Methods("POST")
+	r.Handle("/pause", NewPauseHandler(cfg, db)).Methods("POST")
+	r.Handle("/resume", NewResumeHandler(cfg, db)).Methods("POST")
+	r.Handle("/retire", NewRetireHandler(cfg, db)).Methods("POST")
+}
+
+// findMonitorToChange checks that a form submitted to change a monitor comes
+// from an administrator and has a valid anti-CSRF token, and finds the monitor
+// identified by its id field. If not, an error page is written and the last
+// value returned is false.
+func findMonitorToChange(
+	res http.ResponseWriter,
+	req *http.Request,
+	cfg *config.Config,
+	db *sql.DB) (models.Archiver, models.Monitor, bool) {
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		fmt.Println("No cookie", err)
+		fail.BadRequest(res, req, cfg, common.ErrNotAllowed, false, false)
+		return models.Archiver{}, models.Monitor{}, false
+	}
+	archiver, err := models.FindSessionOwner(db, cookie.Value)
+	if err != nil || !archiver.IsAdmin() {
+		fmt.Println("Not admin", err)
+		fail.BadRequest(res, req, cfg, common.ErrNotAllowed, err == nil, false)
+		return models.Archiver{}, models.Monitor{}, false
+	}
+	csrfToken := req.FormValue("csrfToken")
+	if !models.VerifyAndDeleteAntiCSRFToken(db, csrfToken) {
+		fail.BadRequest(res, req, cfg, common.ErrNotAllowed, true, true)
+		return models.Archiver{}, models.Monitor{}, false
+	}
+	id, parseErr := strconv.Atoi(req.FormValue("id"))
+	if parseErr != nil {
+		fail.BadRequest(res, req, cfg, common.ErrGenericInvalidData, true, true)
+		return models.Archiver{}, models.Monitor{}, false
+	}
+	monitor, findErr := models.FindMonitor(db, id)
+	if findErr != nil {
+		fail.BadRequest(res, req, cfg, errors.New("no such monitor"), true, true)
+		return models.Archiver{}, models.Monitor{}, false
+	}
+	return archiver, monitor, true
+}
@@ -0,0 +1,51 @@
+package monitors
+
+import (
+	"../../config"
+	"../common"
+	"../fail"
+
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// RollBackHandler implements net/http.ServeHTTP to handle administrators
+// having a monitor run an earlier version of its script again.
+type RollBackHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewRollBackHandler is the constructor function for a RollBackHandler.
+func NewRollBackHandler(cfg *config.Config, db *sql.DB) RollBackHandler {
+	return RollBackHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP has the monitor submitted run the version of its script given in
+// the version field.
+func (h RollBackHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	_, monitor, found := findMonitorToChange(res, req, h.cfg, h.db)
+	if !found {
+		return
+	}
+	version, parseErr := strconv.Atoi(req.FormValue("version"))
+	if parseErr != nil {
+		fail.BadRequest(res, req, h.cfg, common.ErrGenericInvalidData, true, true)
+		return
+	}
+	rollBackErr := monitor.RollBack(h.db, version)
+	if rollBackErr != nil {
+		fmt.Println("Could not roll back monitor script", rollBackErr)
+		fail.BadRequest(res, req, h.cfg, rollBackErr, true, true)
+		return
+	}
+	handler := NewViewPageHandler(h.cfg, h.db)
+	handler.PushSuccessMsg(fmt.Sprintf(
+		"Monitor with ID %d now runs version %d of its script", monitor.ID(), version))
+	handler.ServeHTTP(res, req)
+}
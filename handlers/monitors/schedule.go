@@ -0,0 +1,56 @@
+package monitors
+
+import (
+	"../../config"
+	"../common"
+	"../fail"
+
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ScheduleHandler implements net/http.ServeHTTP to handle administrators
+// changing how often a monitor runs and how long it may run for.
+type ScheduleHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewScheduleHandler is the constructor function for a ScheduleHandler.
+func NewScheduleHandler(cfg *config.Config, db *sql.DB) ScheduleHandler {
+	return ScheduleHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP sets the number of minutes to wait between runs of the monitor
+// submitted, from the waitPeriod field, and the number of seconds its script is
+// expected to run for, from the expectedRuntime field.
+func (h ScheduleHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	_, monitor, found := findMonitorToChange(res, req, h.cfg, h.db)
+	if !found {
+		return
+	}
+	waitPeriod, parseErr1 := strconv.Atoi(req.FormValue("waitPeriod"))
+	expectedRuntime, parseErr2 := strconv.Atoi(req.FormValue("expectedRuntime"))
+	if parseErr1 != nil || parseErr2 != nil || waitPeriod < 1 || expectedRuntime < 1 {
+		fail.BadRequest(res, req, h.cfg, common.ErrGenericInvalidData, true, true)
+		return
+	}
+	monitor.SetSchedule(
+		time.Duration(waitPeriod)*time.Minute,
+		time.Duration(expectedRuntime)*time.Second)
+	updateErr := monitor.UpdateSchedule(h.db)
+	if updateErr != nil {
+		fmt.Println("Could not update monitor", updateErr)
+		fail.BadRequest(res, req, h.cfg, updateErr, true, true)
+		return
+	}
+	handler := NewViewPageHandler(h.cfg, h.db)
+	handler.PushSuccessMsg(fmt.Sprintf("Updated the schedule of monitor with ID %d", monitor.ID()))
+	handler.ServeHTTP(res, req)
+}
@@ -0,0 +1,75 @@
+package monitors
+
+import (
+	"../../config"
+	"../../models"
+	"../common"
+	"../fail"
+
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ReplaceScriptHandler implements net/http.ServeHTTP to handle administrators
+// uploading a new version of an existing monitor's script.
+type ReplaceScriptHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewReplaceScriptHandler is the constructor function for a ReplaceScriptHandler.
+func NewReplaceScriptHandler(cfg *config.Config, db *sql.DB) ReplaceScriptHandler {
+	return ReplaceScriptHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP saves the script uploaded as a new version of the monitor
+// submitted's script, along with an optional note describing what changed,
+// and has the monitor run it from now on.
+func (h ReplaceScriptHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	archiver, monitor, found := findMonitorToChange(res, req, h.cfg, h.db)
+	if !found {
+		return
+	}
+	if monitor.Status() == models.RetiredMonitor {
+		fail.BadRequest(res, req, h.cfg, models.ErrMonitorRetired, true, true)
+		return
+	}
+	note := strings.TrimSpace(req.FormValue("note"))
+	if len(note) > common.MaxScriptNoteLength {
+		fail.BadRequest(res, req, h.cfg, common.ErrScriptNoteTooLong, true, true)
+		return
+	}
+	filetype := req.FormValue("filetype")
+	filename, checksum := "", ""
+	// Fetch monitors are run by miru itself and don't need a script.
+	if filetype != string(models.FetchInterpreter) {
+		ext, ftErr := common.FiletypeExtension(h.cfg, filetype)
+		if ftErr != nil {
+			fail.BadRequest(res, req, h.cfg, common.ErrGenericInvalidData, true, true)
+			return
+		}
+		saved, sum, saveErr := common.SaveUploadedScript(req, h.cfg.ScriptDir, ext)
+		if saveErr != nil {
+			fmt.Printf("Error: %v\n", saveErr)
+			fail.InternalError(res, req, h.cfg, common.ErrCreateFile, true, true)
+			return
+		}
+		filename, checksum = saved, sum
+	}
+	version, addErr := monitor.AddScriptVersion(
+		h.db, archiver, models.Interpreter(filetype), filename, checksum, note)
+	if addErr != nil {
+		fmt.Println("Could not save new script version", addErr)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
+		return
+	}
+	handler := NewViewPageHandler(h.cfg, h.db)
+	handler.PushSuccessMsg(fmt.Sprintf(
+		"Monitor with ID %d now runs version %d of its script", monitor.ID(), version.Version()))
+	handler.ServeHTTP(res, req)
+}
@@ -0,0 +1,97 @@
+package monitors
+
+import (
+	"../../config"
+	"../../models"
+	"../common"
+	"../fail"
+
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// errSecretsKey is shown when the key that secrets are encrypted with can't be
+// loaded, without revealing anything about the key.
+var errSecretsKey = errors.New("could not load the key that secrets are encrypted with")
+
+// SetSecretHandler implements net/http.ServeHTTP to handle administrators
+// setting secrets that a monitor's script is given as environment variables.
+type SetSecretHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewSetSecretHandler is the constructor function for a SetSecretHandler.
+func NewSetSecretHandler(cfg *config.Config, db *sql.DB) SetSecretHandler {
+	return SetSecretHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP encrypts and saves the value field as the secret of the monitor
+// submitted named by the name field, replacing any secret it already has with
+// that name.
+func (h SetSecretHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	archiver, monitor, found := findMonitorToChange(res, req, h.cfg, h.db)
+	if !found {
+		return
+	}
+	key, keyErr := models.LoadSecretsKey(h.cfg.SecretsKeyFile)
+	if keyErr != nil {
+		fmt.Println("Could not load secrets key", keyErr)
+		fail.InternalError(res, req, h.cfg, errSecretsKey, true, true)
+		return
+	}
+	name := strings.TrimSpace(req.FormValue("name"))
+	setErr := models.SetMonitorSecret(h.db, key, archiver, monitor, name, req.FormValue("value"))
+	switch setErr {
+	case nil:
+	case models.ErrInvalidSecretName, models.ErrReservedSecretName, models.ErrSecretTooLong, models.ErrMonitorRetired:
+		fail.BadRequest(res, req, h.cfg, setErr, true, true)
+		return
+	default:
+		fmt.Println("Could not set monitor secret", setErr)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
+		return
+	}
+	handler := NewViewPageHandler(h.cfg, h.db)
+	handler.PushSuccessMsg(fmt.Sprintf("Set secret %s for monitor with ID %d", name, monitor.ID()))
+	handler.ServeHTTP(res, req)
+}
+
+// DeleteSecretHandler implements net/http.ServeHTTP to handle administrators
+// deleting one of a monitor's secrets.
+type DeleteSecretHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewDeleteSecretHandler is the constructor function for a DeleteSecretHandler.
+func NewDeleteSecretHandler(cfg *config.Config, db *sql.DB) DeleteSecretHandler {
+	return DeleteSecretHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP deletes the secret named by the name field from the monitor submitted.
+func (h DeleteSecretHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	archiver, monitor, found := findMonitorToChange(res, req, h.cfg, h.db)
+	if !found {
+		return
+	}
+	name := req.FormValue("name")
+	deleteErr := models.DeleteMonitorSecret(h.db, archiver, monitor, name)
+	if deleteErr != nil {
+		fmt.Println("Could not delete monitor secret", deleteErr)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
+		return
+	}
+	handler := NewViewPageHandler(h.cfg, h.db)
+	handler.PushSuccessMsg(fmt.Sprintf("Deleted secret %s from monitor with ID %d", name, monitor.ID()))
+	handler.ServeHTTP(res, req)
+}
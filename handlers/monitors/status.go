@@ -0,0 +1,106 @@
+package monitors
+
+import (
+	"../../config"
+	"../../models"
+	"../fail"
+
+	"database/sql"
+	"fmt"
+	"net/http"
+)
+
+// statusChange pauses, resumes or retires a monitor on behalf of an administrator.
+type statusChange func(*sql.DB, *models.Monitor, models.Archiver) error
+
+// StatusChangeHandler implements net/http.ServeHTTP to handle administrators
+// pausing, resuming and retiring monitors.
+type StatusChangeHandler struct {
+	cfg     *config.Config
+	db      *sql.DB
+	change  statusChange
+	message string
+}
+
+// NewPauseHandler is the constructor function for a StatusChangeHandler that
+// stops an active monitor from being run until it is resumed.
+func NewPauseHandler(cfg *config.Config, db *sql.DB) StatusChangeHandler {
+	return StatusChangeHandler{
+		cfg:     cfg,
+		db:      db,
+		change:  pauseMonitor,
+		message: "Paused monitor with ID %d",
+	}
+}
+
+// NewResumeHandler is the constructor function for a StatusChangeHandler that
+// has a paused monitor run on its schedule again.
+func NewResumeHandler(cfg *config.Config, db *sql.DB) StatusChangeHandler {
+	return StatusChangeHandler{
+		cfg:     cfg,
+		db:      db,
+		change:  resumeMonitor,
+		message: "Resumed monitor with ID %d",
+	}
+}
+
+// NewRetireHandler is the constructor function for a StatusChangeHandler that
+// stops a monitor from ever being run again, keeping its reports.
+func NewRetireHandler(cfg *config.Config, db *sql.DB) StatusChangeHandler {
+	return StatusChangeHandler{
+		cfg:     cfg,
+		db:      db,
+		change:  retireMonitor,
+		message: "Retired monitor with ID %d. Its reports will be kept.",
+	}
+}
+
+// ServeHTTP changes the status of the monitor submitted.
+func (h StatusChangeHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	archiver, monitor, found := findMonitorToChange(res, req, h.cfg, h.db)
+	if !found {
+		return
+	}
+	changeErr := h.change(h.db, &monitor, archiver)
+	if changeErr != nil {
+		fmt.Println("Could not change monitor status", changeErr)
+		fail.BadRequest(res, req, h.cfg, changeErr, true, true)
+		return
+	}
+	handler := NewViewPageHandler(h.cfg, h.db)
+	handler.PushSuccessMsg(fmt.Sprintf(h.message, monitor.ID()))
+	handler.ServeHTTP(res, req)
+}
+
+// pauseMonitor pauses an active monitor.
+func pauseMonitor(db *sql.DB, monitor *models.Monitor, admin models.Archiver) error {
+	return monitor.Pause(db)
+}
+
+// resumeMonitor resumes a paused monitor.
+func resumeMonitor(db *sql.DB, monitor *models.Monitor, admin models.Archiver) error {
+	return monitor.Resume(db)
+}
+
+// retireMonitor retires a monitor and, if it is the monitor that fulfilled its
+// request, retires the request too, so that the archiver who made it can see
+// that their site is no longer being monitored.
+func retireMonitor(db *sql.DB, monitor *models.Monitor, admin models.Archiver) error {
+	err := monitor.Retire(db)
+	if err != nil {
+		return err
+	}
+	request, findErr := models.FindRequest(db, monitor.CreatedFor())
+	if findErr != nil || request.State() != models.FulfilledState {
+		return nil
+	}
+	latest, findErr := models.FindMonitorForRequest(db, request)
+	if findErr != nil || latest.ID() != monitor.ID() {
+		return nil
+	}
+	retireErr := request.Retire(db, admin)
+	if retireErr != nil {
+		fmt.Println("Could not retire request", request.ID(), retireErr)
+	}
+	return nil
+}
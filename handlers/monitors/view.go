@@ -0,0 +1,200 @@
+package monitors
+
+import (
+	"../../auth"
+	"../../config"
+	"../../models"
+	"../common"
+	"../fail"
+
+	"database/sql"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+)
+
+// monitorPage is the name of the HTML template containing the page that
+// administrators manage a monitor through.
+const monitorPage string = "monitor.html"
+
+// ViewPageHandler implements net/http.ServeHTTP to serve the page describing a
+// monitor to administrators.
+type ViewPageHandler struct {
+	cfg       *config.Config
+	db        *sql.DB
+	Successes []string
+}
+
+// NewViewPageHandler is the constructor function for a ViewPageHandler.
+func NewViewPageHandler(cfg *config.Config, db *sql.DB) ViewPageHandler {
+	return ViewPageHandler{
+		cfg:       cfg,
+		db:        db,
+		Successes: []string{},
+	}
+}
+
+// PushSuccessMsg adds a new message that will be displayed on the page served by the
+// handler to indicate a successful operation.
+func (h *ViewPageHandler) PushSuccessMsg(msg string) {
+	h.Successes = append(h.Successes, msg)
+}
+
+// ServeHTTP serves a page showing the monitor identified by the id url
+// parameter and every version of its script, with forms to change its
+// schedule, upload a new version of its script or roll back to an earlier one,
+// set or delete its secrets, pause or resume it, and retire it. Only the names
+// of secrets are shown.
+func (h ViewPageHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	// Check that the request is coming from an authenticated administrator.
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	activeUser, err := models.FindSessionOwner(h.db, cookie.Value)
+	if err != nil || !activeUser.IsAdmin() {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, err == nil, false)
+		return
+	}
+	monitorID, parseErr := strconv.Atoi(req.FormValue("id"))
+	if parseErr != nil {
+		fail.BadRequest(res, req, h.cfg, common.ErrGenericInvalidData, true, true)
+		return
+	}
+	monitor, findErr := models.FindMonitor(h.db, monitorID)
+	if findErr != nil {
+		fmt.Println("Could not find monitor", monitorID, findErr)
+		fail.BadRequest(res, req, h.cfg, errors.New("no such monitor"), true, true)
+		return
+	}
+	siteURL := ""
+	request, findErr := models.FindRequest(h.db, monitor.CreatedFor())
+	if findErr == nil {
+		siteURL = request.URL()
+	}
+	versions, findErr := models.ListScriptVersions(h.db, monitor)
+	if findErr != nil {
+		fmt.Println("Could not list script versions", findErr)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
+		return
+	}
+	type Version struct {
+		Version    int
+		Type       string
+		UploadedBy string
+		CreatedAt  time.Time
+		Checksum   string
+		Note       string
+		IsCurrent  bool
+	}
+	versionData := []Version{}
+	uploaders := map[int]string{}
+	for _, version := range versions {
+		uploader, found := uploaders[version.UploadedBy()]
+		if !found {
+			archiver, findErr := models.FindArchiver(h.db, version.UploadedBy())
+			if findErr == nil {
+				uploader = archiver.Email()
+			}
+			uploaders[version.UploadedBy()] = uploader
+		}
+		versionData = append(versionData, Version{
+			Version:    version.Version(),
+			Type:       string(version.Interpreter()),
+			UploadedBy: uploader,
+			CreatedAt:  version.CreatedAt(),
+			Checksum:   version.Checksum(),
+			Note:       version.Note(),
+			IsCurrent:  version.Version() == monitor.ScriptVersion(),
+		})
+	}
+	secrets, findErr := models.ListMonitorSecrets(h.db, monitor)
+	if findErr != nil {
+		fmt.Println("Could not list monitor secrets", findErr)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
+		return
+	}
+	type Secret struct {
+		Name      string
+		SetBy     string
+		UpdatedAt time.Time
+	}
+	secretData := []Secret{}
+	for _, secret := range secrets {
+		setter, found := uploaders[secret.SetBy()]
+		if !found {
+			archiver, findErr := models.FindArchiver(h.db, secret.SetBy())
+			if findErr == nil {
+				setter = archiver.Email()
+			}
+			uploaders[secret.SetBy()] = setter
+		}
+		secretData = append(secretData, Secret{secret.Name(), setter, secret.UpdatedAt()})
+	}
+	csrfToken := models.GenerateAntiCSRFToken(h.db, auth.AntiCSRFTokenLength)
+	saveErr := csrfToken.Save(h.db)
+	if saveErr != nil {
+		fmt.Println("Could not save anti-CSRF token", saveErr)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
+		return
+	}
+	// Serve the page.
+	t, err := template.ParseFiles(
+		path.Join(h.cfg.TemplateDir, monitorPage),
+		path.Join(h.cfg.TemplateDir, common.HeadTemplate),
+		path.Join(h.cfg.TemplateDir, common.NavTemplate))
+	if err != nil {
+		fail.InternalError(res, req, h.cfg, common.ErrTemplateLoad, true, true)
+		return
+	}
+	t.Execute(res, struct {
+		ID              int
+		RequestID       int
+		URL             string
+		Interpreter     string
+		ScriptTypes     []common.ScriptType
+		ScriptPath      string
+		ScriptVersion   int
+		Versions        []Version
+		Secrets         []Secret
+		Status          string
+		IsActive        bool
+		IsPaused        bool
+		IsRetired       bool
+		WaitPeriod      uint
+		ExpectedRuntime uint
+		CreatedAt       time.Time
+		LastRun         time.Time
+		CSRFToken       string
+		LoggedIn        bool
+		UserIsAdmin     bool
+		Successes       []string
+	}{
+		monitor.ID(),
+		monitor.CreatedFor(),
+		siteURL,
+		string(monitor.Interpreter()),
+		common.ScriptTypes(h.cfg),
+		monitor.ScriptPath(),
+		monitor.ScriptVersion(),
+		versionData,
+		secretData,
+		monitor.Status().String(),
+		monitor.Status() == models.ActiveMonitor,
+		monitor.Status() == models.PausedMonitor,
+		monitor.Status() == models.RetiredMonitor,
+		uint(monitor.WaitPeriod().Minutes()),
+		uint(monitor.ExpectedRunTime().Seconds()),
+		monitor.CreatedAt(),
+		monitor.LastRun(),
+		csrfToken.Token(),
+		true,
+		true,
+		h.Successes,
+	})
+}
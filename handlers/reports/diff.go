@@ -0,0 +1,146 @@
+package reports
+
+import (
+	"../../auth"
+	"../../config"
+	"../../diff"
+	"../../models"
+	"../common"
+	"../fail"
+
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+)
+
+// diffPage is the name of the template HTML file that displays a side-by-side
+// comparison of two reports produced by the same monitor.
+const diffPage string = "diff.html"
+
+// DiffPageHandler implements net/http.ServeHTTP to serve a page to
+// administrators showing what changed on a site between two reports.
+type DiffPageHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewDiffPageHandler is the constructor function for a DiffPageHandler.
+func NewDiffPageHandler(cfg *config.Config, db *sql.DB) DiffPageHandler {
+	return DiffPageHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP serves a page comparing the reports identified by the old and new
+// url parameters. The text content of the page is compared if either report
+// has any, and otherwise the state the monitor's script saved is compared.
+func (h DiffPageHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	// Check that the request is coming from an authenticated administrator.
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		fmt.Println("Could not find cookie", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	activeUser, err := models.FindSessionOwner(h.db, cookie.Value)
+	if err != nil || !activeUser.IsAdmin() {
+		fmt.Println("Could not get cookie owner", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, err == nil, false)
+		return
+	}
+	oldReport, paramErr := h.findReportParam(req, "old")
+	if paramErr != nil {
+		fail.BadRequest(res, req, h.cfg, paramErr, true, true)
+		return
+	}
+	newReport, paramErr := h.findReportParam(req, "new")
+	if paramErr != nil {
+		fail.BadRequest(res, req, h.cfg, paramErr, true, true)
+		return
+	}
+	if oldReport.CreatedBy() != newReport.CreatedBy() {
+		fail.BadRequest(res, req, h.cfg, errors.New("reports must belong to the same monitor"), true, true)
+		return
+	}
+	url := ""
+	monitor, findErr := models.FindMonitor(h.db, oldReport.CreatedBy())
+	if findErr == nil {
+		request, findErr := models.FindRequest(h.db, monitor.CreatedFor())
+		if findErr == nil {
+			url = request.URL()
+		}
+	}
+	compared := "page content"
+	oldText, newText := oldReport.Content(), newReport.Content()
+	if oldText == "" && newText == "" {
+		compared = "script state"
+		oldText, newText = encodeState(oldReport), encodeState(newReport)
+	}
+	t, err := template.ParseFiles(
+		path.Join(h.cfg.TemplateDir, diffPage),
+		path.Join(h.cfg.TemplateDir, common.HeadTemplate),
+		path.Join(h.cfg.TemplateDir, common.NavTemplate))
+	if err != nil {
+		fmt.Println("Error parsing diff page template", err)
+		fail.InternalError(res, req, h.cfg, common.ErrTemplateLoad, true, true)
+		return
+	}
+	type ReportData struct {
+		ID                 int
+		CreatedAt          time.Time
+		ChangeSignificance string
+		Message            string
+	}
+	describe := func(r models.Report) ReportData {
+		return ReportData{r.ID(), r.CreatedAt(), r.Change().String(), r.Message()}
+	}
+	t.Execute(res, struct {
+		URL         string
+		MonitorID   int
+		Compared    string
+		Old         ReportData
+		New         ReportData
+		Rows        []diff.Row
+		LoggedIn    bool
+		UserIsAdmin bool
+		Successes   []string
+	}{
+		url, oldReport.CreatedBy(), compared, describe(oldReport), describe(newReport),
+		diff.SideBySide(oldText, newText), true, true, []string{},
+	})
+}
+
+// findReportParam looks up the report identified by a url parameter.
+func (h DiffPageHandler) findReportParam(req *http.Request, name string) (models.Report, error) {
+	reportIDs, found := req.URL.Query()[name]
+	if !found || len(reportIDs) == 0 {
+		return models.Report{}, fmt.Errorf("missing %s report id url parameter", name)
+	}
+	reportID, parseErr := strconv.Atoi(reportIDs[0])
+	if parseErr != nil {
+		return models.Report{}, common.ErrGenericInvalidData
+	}
+	report, findErr := models.FindReport(h.db, reportID)
+	if findErr != nil {
+		fmt.Println("Could not find report", reportID, findErr)
+		return models.Report{}, errors.New("no such report")
+	}
+	return report, nil
+}
+
+// encodeState formats a report's state as indented JSON so that each field
+// is compared on its own line.
+func encodeState(report models.Report) string {
+	encoded, encodeErr := json.MarshalIndent(report.State(), "", "  ")
+	if encodeErr != nil {
+		return ""
+	}
+	return string(encoded)
+}
@@ -0,0 +1,169 @@
+package reports
+
+import (
+	"../../auth"
+	"../../config"
+	"../../feeds"
+	"../../models"
+
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// feedLength is the number of the most recent reports to include in a feed.
+const feedLength uint = 50
+
+// FeedHandler implements net/http.ServeHTTP to serve an Atom feed of the
+// changes detected by monitors to administrators.
+type FeedHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewFeedHandler is the constructor function for a FeedHandler.
+func NewFeedHandler(cfg *config.Config, db *sql.DB) FeedHandler {
+	return FeedHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP serves a feed of the most recent reports, newest first. The monitor
+// url parameter limits the feed to one monitor's reports, and minSignificance,
+// written as a number or a name like "rewritten", sets the least significant
+// change to include, which is minor_update by default. Since feed readers can't
+// log in, a read-only API token belonging to an administrator can be given in
+// the token url parameter instead of a session cookie.
+func (h FeedHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	params := req.URL.Query()
+	activeUser, findErr := findFeedReader(h.db, req)
+	if findErr != nil || !activeUser.IsAdmin() {
+		fmt.Println("Could not authenticate feed reader", findErr)
+		http.Error(res, "Only administrators can read feeds.", http.StatusUnauthorized)
+		return
+	}
+	filter := models.NewReportFilter()
+	filter.MinChange = models.MinorUpdate
+	if params.Get("minSignificance") != "" {
+		significance, parseErr := models.ParseImportance(params.Get("minSignificance"))
+		if parseErr != nil {
+			http.Error(res, parseErr.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.MinChange = significance
+	}
+	title := "Changes detected by Miru"
+	siteURLs := map[int]string{}
+	if params.Get("monitor") != "" {
+		monitorID, parseErr := strconv.Atoi(params.Get("monitor"))
+		if parseErr != nil {
+			http.Error(res, "Invalid monitor id.", http.StatusBadRequest)
+			return
+		}
+		siteURL, findErr := findSiteURL(h.db, monitorID)
+		if findErr != nil {
+			http.Error(res, "No such monitor.", http.StatusNotFound)
+			return
+		}
+		filter.Monitor = monitorID
+		siteURLs[monitorID] = siteURL
+		title = "Changes to " + siteURL
+	}
+	reports, findErr := models.ListReports(h.db, filter, feedLength, 0)
+	if findErr != nil {
+		fmt.Println("Could not list reports", findErr)
+		http.Error(res, "Could not list reports.", http.StatusInternalServerError)
+		return
+	}
+	base := baseURL(req)
+	// The token is left out of the feed's own URL so that it isn't copied
+	// anywhere the feed is shared.
+	selfParams := url.Values{}
+	for _, name := range []string{"monitor", "minSignificance"} {
+		if params.Get(name) != "" {
+			selfParams.Set(name, params.Get(name))
+		}
+	}
+	selfURL := base + req.URL.Path
+	if len(selfParams) > 0 {
+		selfURL += "?" + selfParams.Encode()
+	}
+	feed := feeds.NewFeed(selfURL, title, selfURL)
+	for _, report := range reports {
+		siteURL, found := siteURLs[report.CreatedBy()]
+		if !found {
+			siteURL, _ = findSiteURL(h.db, report.CreatedBy())
+			siteURLs[report.CreatedBy()] = siteURL
+		}
+		reportURL := fmt.Sprintf("%s/reports/history?monitor=%d#report-%d", base, report.CreatedBy(), report.ID())
+		links := []feeds.Link{{Rel: "alternate", Href: reportURL}}
+		if siteURL != "" {
+			links = append(links, feeds.Link{Rel: "related", Href: siteURL})
+		} else {
+			siteURL = fmt.Sprintf("monitor #%d", report.CreatedBy())
+		}
+		feed.AddEntry(feeds.Entry{
+			ID:       reportURL,
+			Title:    fmt.Sprintf("%s: %s", report.Change().String(), siteURL),
+			Updated:  report.CreatedAt(),
+			Links:    links,
+			Category: feeds.Category{Term: report.Change().String()},
+			Summary:  report.Message(),
+		})
+	}
+	res.Header().Set("Content-Type", feeds.ContentType)
+	writeErr := feed.Write(res)
+	if writeErr != nil {
+		fmt.Println("Could not write feed", writeErr)
+	}
+}
+
+// findFeedReader finds the archiver reading a feed, either from the API token
+// in the token url parameter or, if there isn't one, from their session cookie.
+// Since feed addresses end up saved in feed readers and shared, only read-only
+// tokens are accepted in them.
+func findFeedReader(db *sql.DB, req *http.Request) (models.Archiver, error) {
+	token := req.URL.Query().Get("token")
+	if token != "" {
+		archiver, scope, err := models.FindAPITokenOwner(db, token)
+		if err != nil {
+			return models.Archiver{}, err
+		}
+		if scope != models.ReadOnlyScope {
+			return models.Archiver{}, errors.New("feeds can only be read with read-only tokens")
+		}
+		return archiver, nil
+	}
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		return models.Archiver{}, errors.New("no token or session cookie")
+	}
+	return models.FindSessionOwner(db, cookie.Value)
+}
+
+// findSiteURL finds the URL of the site that a monitor checks.
+func findSiteURL(db *sql.DB, monitorID int) (string, error) {
+	monitor, err := models.FindMonitor(db, monitorID)
+	if err != nil {
+		return "", err
+	}
+	request, err := models.FindRequest(db, monitor.CreatedFor())
+	if err != nil {
+		return "", err
+	}
+	return request.URL(), nil
+}
+
+// baseURL works out the scheme and host that the request was made to, so
+// that links in feeds can be absolute.
+func baseURL(req *http.Request) string {
+	scheme := "http"
+	if req.TLS != nil || req.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + req.Host
+}
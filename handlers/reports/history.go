@@ -0,0 +1,197 @@
+package reports
+
+import (
+	"../../auth"
+	"../../config"
+	"../../models"
+	"../common"
+	"../fail"
+
+	"database/sql"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+)
+
+// historyPage is the name of the template HTML file that displays a timeline
+// of every report produced by a monitor.
+const historyPage string = "history.html"
+
+// reportsPerPage is the number of reports to show on each page of a monitor's
+// history.
+const reportsPerPage uint = 50
+
+// dateFormat is the format that the since and until url parameters of the
+// history page are expected to be written in.
+const dateFormat string = "2006-01-02"
+
+// HistoryPageHandler implements net/http.ServeHTTP to serve a page to
+// administrators containing every report produced by a monitor, newest first.
+type HistoryPageHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewHistoryPageHandler is the constructor function for a HistoryPageHandler.
+func NewHistoryPageHandler(cfg *config.Config, db *sql.DB) HistoryPageHandler {
+	return HistoryPageHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP serves a page of the history of the monitor identified by the
+// monitor url parameter. The page url parameter selects which page of reports
+// to show, and the since and until url parameters, written like 2017-01-31,
+// limit the reports shown to those created between the two dates.
+func (h HistoryPageHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	// Check that the request is coming from an authenticated administrator.
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		fmt.Println("Could not find cookie", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	activeUser, err := models.FindSessionOwner(h.db, cookie.Value)
+	if err != nil || !activeUser.IsAdmin() {
+		fmt.Println("Could not get cookie owner", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, err == nil, false)
+		return
+	}
+	params := req.URL.Query()
+	monitorID, parseErr := strconv.Atoi(params.Get("monitor"))
+	if parseErr != nil {
+		fail.BadRequest(res, req, h.cfg, errors.New("missing or invalid monitor id url parameter"), true, true)
+		return
+	}
+	page := uint(1)
+	if params.Get("page") != "" {
+		parsed, parseErr := strconv.ParseUint(params.Get("page"), 10, 32)
+		if parseErr != nil || parsed == 0 {
+			fail.BadRequest(res, req, h.cfg, common.ErrGenericInvalidData, true, true)
+			return
+		}
+		page = uint(parsed)
+	}
+	since, until := time.Time{}, time.Time{}
+	if params.Get("since") != "" {
+		since, parseErr = time.ParseInLocation(dateFormat, params.Get("since"), time.Local)
+		if parseErr != nil {
+			fail.BadRequest(res, req, h.cfg, errors.New("dates must be written like 2017-01-31"), true, true)
+			return
+		}
+	}
+	if params.Get("until") != "" {
+		until, parseErr = time.ParseInLocation(dateFormat, params.Get("until"), time.Local)
+		if parseErr != nil {
+			fail.BadRequest(res, req, h.cfg, errors.New("dates must be written like 2017-01-31"), true, true)
+			return
+		}
+		// Include reports created at any time on the last day.
+		until = until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	monitor, findErr := models.FindMonitor(h.db, monitorID)
+	if findErr != nil {
+		fmt.Println("Could not find monitor", monitorID, findErr)
+		fail.BadRequest(res, req, h.cfg, errors.New("no such monitor"), true, true)
+		return
+	}
+	siteURL := ""
+	request, findErr := models.FindRequest(h.db, monitor.CreatedFor())
+	if findErr == nil {
+		siteURL = request.URL()
+	}
+	total, countErr := models.CountReportsForMonitor(h.db, monitor, since, until)
+	if countErr != nil {
+		fmt.Println("Could not count reports", countErr)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
+		return
+	}
+	// Fetch one extra report so that the last report on the page can be
+	// compared to the one before it.
+	reports, findErr := models.ListReportsForMonitor(
+		h.db, monitor, since, until, reportsPerPage+1, (page-1)*reportsPerPage)
+	if findErr != nil {
+		fmt.Println("Could not list reports", findErr)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
+		return
+	}
+	type Data struct {
+		ID                 int
+		CreatedAt          time.Time
+		ChangeSignificance string
+		Message            string
+		Checksum           string
+		PreviousReportID   int
+		ScriptVersion      int
+	}
+	data := []Data{}
+	for i, report := range reports {
+		if uint(i) == reportsPerPage {
+			break
+		}
+		previousReportID := -1
+		if i+1 < len(reports) {
+			previousReportID = reports[i+1].ID()
+		}
+		data = append(data, Data{
+			ID:                 report.ID(),
+			CreatedAt:          report.CreatedAt(),
+			ChangeSignificance: report.Change().String(),
+			Message:            report.Message(),
+			Checksum:           report.Checksum(),
+			PreviousReportID:   previousReportID,
+			ScriptVersion:      report.ScriptVersion(),
+		})
+	}
+	pageLink := func(page uint) string {
+		link := url.Values{}
+		link.Set("monitor", strconv.Itoa(monitorID))
+		link.Set("page", strconv.FormatUint(uint64(page), 10))
+		if params.Get("since") != "" {
+			link.Set("since", params.Get("since"))
+		}
+		if params.Get("until") != "" {
+			link.Set("until", params.Get("until"))
+		}
+		return "/reports/history?" + link.Encode()
+	}
+	newerPage, olderPage := "", ""
+	if page > 1 {
+		newerPage = pageLink(page - 1)
+	}
+	if page*reportsPerPage < total {
+		olderPage = pageLink(page + 1)
+	}
+	t, err := template.ParseFiles(
+		path.Join(h.cfg.TemplateDir, historyPage),
+		path.Join(h.cfg.TemplateDir, common.HeadTemplate),
+		path.Join(h.cfg.TemplateDir, common.NavTemplate))
+	if err != nil {
+		fmt.Println("Error parsing history page template", err)
+		fail.InternalError(res, req, h.cfg, common.ErrTemplateLoad, true, true)
+		return
+	}
+	t.Execute(res, struct {
+		URL         string
+		MonitorID   int
+		Since       string
+		Until       string
+		Total       uint
+		Page        uint
+		NewerPage   string
+		OlderPage   string
+		Reports     []Data
+		LoggedIn    bool
+		UserIsAdmin bool
+		Successes   []string
+	}{
+		siteURL, monitorID, params.Get("since"), params.Get("until"), total, page,
+		newerPage, olderPage, data, true, true, []string{},
+	})
+}
@@ -19,6 +19,10 @@ import (
 // and report information to be displayed to administrators.
 const reportsPage string = "reports.html"
 
+// maxFailedRuns is the number of the most recent failed monitor script runs
+// to display on the reports page.
+const maxFailedRuns uint = 25
+
 // ListHandler implements net/http.ServeHTTP to serve a page to
 // administrators containing information about monitors that miru is
 // running and data the scripts are reporting.
@@ -57,6 +61,7 @@ func (h ListHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
 		return
 	}
+	urls := map[int]string{}
 	type Data struct {
 		URL                string
 		ScriptPath         string
@@ -64,6 +69,13 @@ func (h ListHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 		ChangeSignificance string
 		Message            string
 		Checksum           string
+		RunID              int
+		Stderr             string
+		SnapshotID         int
+		ReportID           int
+		PreviousReportID   int
+		MonitorID          int
+		Status             string
 	}
 	data := []Data{}
 	for _, monitor := range monitors {
@@ -77,6 +89,26 @@ func (h ListHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 			fmt.Println("Could not find the request satisfied by monitor #", monitor.ID())
 			continue
 		}
+		// Reports saved before runs were recorded won't have one.
+		runID := -1
+		stderr := ""
+		run, findErr := models.FindRunForReport(h.db, report)
+		if findErr == nil {
+			runID = run.ID()
+			stderr = run.Stderr()
+		}
+		// Only pages fetched by miru itself have snapshots.
+		snapshotID := -1
+		snapshot, findErr := models.FindSnapshotForReport(h.db, report)
+		if findErr == nil {
+			snapshotID = snapshot.ID()
+		}
+		previousReportID := -1
+		previous, findErr := models.FindPreviousReport(h.db, report)
+		if findErr == nil {
+			previousReportID = previous.ID()
+		}
+		urls[monitor.ID()] = request.URL()
 		data = append(data, Data{
 			URL:                request.URL(),
 			ScriptPath:         monitor.ScriptPath(),
@@ -84,6 +116,44 @@ func (h ListHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 			ChangeSignificance: report.Change().String(),
 			Message:            report.Message(),
 			Checksum:           report.Checksum(),
+			RunID:              runID,
+			Stderr:             stderr,
+			SnapshotID:         snapshotID,
+			ReportID:           report.ID(),
+			PreviousReportID:   previousReportID,
+			MonitorID:          monitor.ID(),
+			Status:             monitor.Status().String(),
+		})
+	}
+	// Load the runs of monitor scripts that have failed recently so that admins
+	// can find out which scripts are broken.
+	runs, findErr := models.ListFailedRuns(h.db, maxFailedRuns)
+	if findErr != nil {
+		fmt.Println("Could not get failed runs", findErr)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
+		return
+	}
+	type RunData struct {
+		ID        int
+		URL       string
+		MonitorID int
+		StartedAt time.Time
+		Duration  time.Duration
+		Outcome   string
+		ExitCode  int
+		Error     string
+	}
+	failedRuns := []RunData{}
+	for _, run := range runs {
+		failedRuns = append(failedRuns, RunData{
+			ID:        run.ID(),
+			URL:       urls[run.Monitor()],
+			MonitorID: run.Monitor(),
+			StartedAt: run.StartedAt(),
+			Duration:  run.Duration(),
+			Outcome:   run.Outcome().String(),
+			ExitCode:  run.ExitCode(),
+			Error:     run.Error(),
 		})
 	}
 	// Serve the page with the data about monitors and their recent reports.
@@ -98,8 +168,9 @@ func (h ListHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	}
 	t.Execute(res, struct {
 		Reports     []Data
+		FailedRuns  []RunData
 		LoggedIn    bool
 		UserIsAdmin bool
 		Successes   []string
-	}{data, true, true, []string{}})
+	}{data, failedRuns, true, true, []string{}})
 }
@@ -11,4 +11,9 @@ import (
 // RegisterHandlers registers request handlers to a subrouter.
 func RegisterHandlers(r *mux.Router, cfg *config.Config, db *sql.DB) {
 	r.Handle("/list", NewListHandler(cfg, db)).Methods("GET")
+	r.Handle("/run", NewRunPageHandler(cfg, db)).Methods("GET")
+	r.Handle("/history", NewHistoryPageHandler(cfg, db)).Methods("GET")
+	r.Handle("/diff", NewDiffPageHandler(cfg, db)).Methods("GET")
+	r.Handle("/snapshot", NewSnapshotHandler(cfg, db)).Methods("GET")
+	r.Handle("/feed.atom", NewFeedHandler(cfg, db)).Methods("GET")
 }
@@ -0,0 +1,99 @@
+package reports
+
+import (
+	"../../auth"
+	"../../config"
+	"../../models"
+	"../common"
+	"../fail"
+
+	"database/sql"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+)
+
+// runPage is the name of the template HTML file that displays the details of
+// a single run of a monitor script, including what it wrote to stderr.
+const runPage string = "run.html"
+
+// RunPageHandler implements net/http.ServeHTTP to serve a page to
+// administrators showing the details of a run of a monitor script so that
+// they can debug scripts without access to the server.
+type RunPageHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewRunPageHandler is the constructor function for a RunPageHandler.
+func NewRunPageHandler(cfg *config.Config, db *sql.DB) RunPageHandler {
+	return RunPageHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP serves a page describing a single run of a monitor script.
+func (h RunPageHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	// Check that the request is coming from an authenticated administrator.
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		fmt.Println("Could not find cookie", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	activeUser, err := models.FindSessionOwner(h.db, cookie.Value)
+	if err != nil || !activeUser.IsAdmin() {
+		fmt.Println("Could not get cookie owner", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, err == nil, false)
+		return
+	}
+	runIDs, found := req.URL.Query()["id"]
+	if !found || len(runIDs) == 0 {
+		fail.BadRequest(res, req, h.cfg, errors.New("missing run id url parameter"), true, true)
+		return
+	}
+	runID, parseErr := strconv.Atoi(runIDs[0])
+	if parseErr != nil {
+		fail.BadRequest(res, req, h.cfg, common.ErrGenericInvalidData, true, true)
+		return
+	}
+	run, findErr := models.FindRun(h.db, runID)
+	if findErr != nil {
+		fmt.Println("Could not find run", runID, findErr)
+		fail.BadRequest(res, req, h.cfg, errors.New("no such run"), true, true)
+		return
+	}
+	t, err := template.ParseFiles(
+		path.Join(h.cfg.TemplateDir, runPage),
+		path.Join(h.cfg.TemplateDir, common.HeadTemplate),
+		path.Join(h.cfg.TemplateDir, common.NavTemplate))
+	if err != nil {
+		fmt.Println("Error parsing run page template", err)
+		fail.InternalError(res, req, h.cfg, common.ErrTemplateLoad, true, true)
+		return
+	}
+	t.Execute(res, struct {
+		ID          int
+		MonitorID   int
+		ReportID    int
+		StartedAt   time.Time
+		Duration    time.Duration
+		Outcome     string
+		ExitCode    int
+		Error       string
+		StdoutSize  int
+		Stderr      string
+		LoggedIn    bool
+		UserIsAdmin bool
+		Successes   []string
+	}{
+		run.ID(), run.Monitor(), run.Report(), run.StartedAt(), run.Duration(),
+		run.Outcome().String(), run.ExitCode(), run.Error(), run.StdoutSize(),
+		run.Stderr(), true, true, []string{},
+	})
+}
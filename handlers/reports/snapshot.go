@@ -0,0 +1,86 @@
+package reports
+
+import (
+	"../../auth"
+	"../../config"
+	"../../models"
+	"../../snapshots"
+	"../common"
+	"../fail"
+
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// SnapshotHandler implements net/http.ServeHTTP to serve the snapshot of a
+// page saved when a report was produced to administrators, either to view in
+// the browser or to download.
+type SnapshotHandler struct {
+	cfg   *config.Config
+	db    *sql.DB
+	store snapshots.Store
+}
+
+// NewSnapshotHandler is the constructor function for a SnapshotHandler.
+func NewSnapshotHandler(cfg *config.Config, db *sql.DB) SnapshotHandler {
+	return SnapshotHandler{
+		cfg:   cfg,
+		db:    db,
+		store: snapshots.NewStore(cfg.SnapshotDir),
+	}
+}
+
+// ServeHTTP serves the contents of a snapshot. Snapshots are served as plain
+// text when viewed so that scripts on archived pages never run in miru's
+// origin, and as an attachment when the download parameter is set.
+func (h SnapshotHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	// Check that the request is coming from an authenticated administrator.
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		fmt.Println("Could not find cookie", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	activeUser, err := models.FindSessionOwner(h.db, cookie.Value)
+	if err != nil || !activeUser.IsAdmin() {
+		fmt.Println("Could not get cookie owner", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, err == nil, false)
+		return
+	}
+	snapshotIDs, found := req.URL.Query()["id"]
+	if !found || len(snapshotIDs) == 0 {
+		fail.BadRequest(res, req, h.cfg, errors.New("missing snapshot id url parameter"), true, true)
+		return
+	}
+	snapshotID, parseErr := strconv.Atoi(snapshotIDs[0])
+	if parseErr != nil {
+		fail.BadRequest(res, req, h.cfg, common.ErrGenericInvalidData, true, true)
+		return
+	}
+	snapshot, findErr := models.FindSnapshot(h.db, snapshotID)
+	if findErr != nil {
+		fmt.Println("Could not find snapshot", snapshotID, findErr)
+		fail.BadRequest(res, req, h.cfg, errors.New("no such snapshot"), true, true)
+		return
+	}
+	f, openErr := h.store.Open(snapshot.Checksum())
+	if openErr != nil {
+		fmt.Println("Could not open snapshot", snapshot.Checksum(), openErr)
+		fail.InternalError(res, req, h.cfg, errors.New("the snapshot is missing from the store"), true, true)
+		return
+	}
+	defer f.Close()
+	res.Header().Set("X-Content-Type-Options", "nosniff")
+	res.Header().Set("Content-Security-Policy", "sandbox")
+	if req.URL.Query().Get("download") != "" {
+		res.Header().Set("Content-Type", "application/octet-stream")
+		res.Header().Set("Content-Disposition",
+			fmt.Sprintf("attachment; filename=\"snapshot-%d-%s\"", snapshot.Report(), snapshot.Checksum()))
+	} else {
+		res.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	http.ServeContent(res, req, "", snapshot.CreatedAt(), f)
+}
@@ -0,0 +1,108 @@
+package requests
+
+import (
+	"../../auth"
+	"../../config"
+	"../../models"
+	"../common"
+	"../fail"
+
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// errRequestNotClaimable is returned when an administrator tries to work on a
+// request that is closed or that someone else is already working on.
+var errRequestNotClaimable = errors.New(
+	"this request has already been fulfilled, rejected, or claimed by another administrator")
+
+// stateChange moves a request to a new state on behalf of an administrator.
+type stateChange func(*models.Request, *sql.DB, models.Archiver) error
+
+// StateChangeHandler implements net/http.ServeHTTP to handle administrators
+// claiming, starting work on, and releasing monitor requests.
+type StateChangeHandler struct {
+	cfg     *config.Config
+	db      *sql.DB
+	change  stateChange
+	message string
+}
+
+// NewClaimHandler is the constructor function for a StateChangeHandler that
+// claims a pending request for the administrator, so that nobody else works on it.
+func NewClaimHandler(cfg *config.Config, db *sql.DB) StateChangeHandler {
+	return StateChangeHandler{
+		cfg:     cfg,
+		db:      db,
+		change:  (*models.Request).Claim,
+		message: "Claimed request with ID %d",
+	}
+}
+
+// NewStartHandler is the constructor function for a StateChangeHandler that
+// marks a request claimed by the administrator as having its script written.
+func NewStartHandler(cfg *config.Config, db *sql.DB) StateChangeHandler {
+	return StateChangeHandler{
+		cfg:     cfg,
+		db:      db,
+		change:  (*models.Request).StartWork,
+		message: "Started work on request with ID %d",
+	}
+}
+
+// NewReleaseHandler is the constructor function for a StateChangeHandler that
+// returns a request claimed by the administrator to pending.
+func NewReleaseHandler(cfg *config.Config, db *sql.DB) StateChangeHandler {
+	return StateChangeHandler{
+		cfg:     cfg,
+		db:      db,
+		change:  (*models.Request).Release,
+		message: "Released request with ID %d for someone else to handle",
+	}
+}
+
+// ServeHTTP changes the state of the request submitted.
+func (h StateChangeHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	// Check that the request is coming from an authenticated administrator.
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		fmt.Println("No cookie", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	archiver, err := models.FindSessionOwner(h.db, cookie.Value)
+	if err != nil || !archiver.IsAdmin() {
+		fmt.Println("Not admin", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, err == nil, false)
+		return
+	}
+	// Extract inputs from the submitted form.
+	req.ParseForm()
+	csrfToken := req.FormValue("csrfToken")
+	if !models.VerifyAndDeleteAntiCSRFToken(h.db, csrfToken) {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, true, true)
+		return
+	}
+	id, parseErr := strconv.Atoi(req.FormValue("requestID"))
+	if parseErr != nil {
+		fail.BadRequest(res, req, h.cfg, common.ErrGenericInvalidData, true, true)
+		return
+	}
+	request, findErr := models.FindRequest(h.db, id)
+	if findErr != nil {
+		fail.BadRequest(res, req, h.cfg, errors.New("no such request"), true, true)
+		return
+	}
+	changeErr := h.change(&request, h.db, archiver)
+	if changeErr != nil {
+		fmt.Println("Could not change request state", changeErr)
+		fail.BadRequest(res, req, h.cfg, changeErr, true, true)
+		return
+	}
+	handler := NewListHandler(h.cfg, h.db)
+	handler.PushSuccessMsg(fmt.Sprintf(h.message, id))
+	handler.ServeHTTP(res, req)
+}
@@ -11,7 +11,6 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"net/url"
 )
 
 // CreateHandler implements net/http.ServeHTTP to handle requests to have a
@@ -46,17 +45,27 @@ func (h CreateHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	// Extract data from the form.
 	requstedURL := req.FormValue("url")
 	instructions := req.FormValue("instructions")
-	parsedURL, parseErr := url.Parse(requstedURL)
+	// Create a new request. We strip the query string from the URL since it
+	// could contain sensitive info about the archiver.
+	cleanURL, parseErr := models.CleanURL(requstedURL)
 	if parseErr != nil {
 		fail.BadRequest(res, req, h.cfg, errors.New("please specify a valid url to monitor"), true, archiver.IsAdmin())
 		return
 	}
-	// Create a new request. We strip the query string from the URL since it:
-	// 1. Shouldn't be necessary.
-	// 2. Could contain sensitive info about the archiver.
-	parsedURL.ForceQuery = false
-	parsedURL.RawQuery = ""
-	request := models.NewRequest(archiver, parsedURL.String(), instructions)
+	// Warn the archiver about requests that have already been made for the
+	// same or an overlapping address, and only save theirs once they confirm
+	// that they still want this address monitored.
+	similar, findErr := models.FindSimilarRequests(h.db, cleanURL)
+	if findErr != nil {
+		fmt.Println("Could not look for similar requests", findErr)
+	}
+	if len(similar) > 0 && req.FormValue("confirmedURL") != cleanURL {
+		handler := NewCreatePageHandler(h.cfg, h.db)
+		handler.WarnAboutSimilar(cleanURL, instructions, similar)
+		handler.ServeHTTP(res, req)
+		return
+	}
+	request := models.NewRequest(archiver, cleanURL, instructions)
 	saveErr := request.Save(h.db)
 	if saveErr != nil {
 		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, archiver.IsAdmin())
@@ -64,6 +73,6 @@ func (h CreateHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	}
 	fmt.Println("Created request", request)
 	handler := NewCreatePageHandler(h.cfg, h.db)
-	handler.PushSuccessMsg("Successfully sent your request. An administrator will review it soon.")
+	handler.PushSuccessMsg("Successfully sent your request. An administrator will review it soon, and you can follow its progress on the My Requests page.")
 	handler.ServeHTTP(res, req)
 }
@@ -19,17 +19,23 @@ const requestPage string = "request.html"
 
 // CreatePageHandler implements net/http.ServeHTTP to serve the request page.
 type CreatePageHandler struct {
-	cfg       *config.Config
-	db        *sql.DB
-	Successes []string
+	cfg          *config.Config
+	db           *sql.DB
+	Successes    []string
+	url          string
+	instructions string
+	similar      []models.SimilarRequest
 }
 
 // NewCreatePageHandler is the constructor function for a CreatePageHandler.
 func NewCreatePageHandler(cfg *config.Config, db *sql.DB) CreatePageHandler {
 	return CreatePageHandler{
-		cfg:       cfg,
-		db:        db,
-		Successes: []string{},
+		cfg:          cfg,
+		db:           db,
+		Successes:    []string{},
+		url:          "",
+		instructions: "",
+		similar:      []models.SimilarRequest{},
 	}
 }
 
@@ -39,6 +45,15 @@ func (h *CreatePageHandler) PushSuccessMsg(msg string) {
 	h.Successes = append(h.Successes, msg)
 }
 
+// WarnAboutSimilar has the page list requests already made for addresses that
+// duplicate or overlap the one being requested, and ask the archiver to confirm
+// their request, which is filled back in to the form.
+func (h *CreatePageHandler) WarnAboutSimilar(url, instructions string, similar []models.SimilarRequest) {
+	h.url = url
+	h.instructions = instructions
+	h.similar = similar
+}
+
 // ServeHTTP serves a page that archivers can use to make requests to have a site monitored.
 func (h CreatePageHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	// Check that the request is coming from an authenticated archiver.
@@ -61,9 +76,25 @@ func (h CreatePageHandler) ServeHTTP(res http.ResponseWriter, req *http.Request)
 		fail.InternalError(res, req, h.cfg, common.ErrTemplateLoad, true, activeUser.IsAdmin())
 		return
 	}
+	type Similar struct {
+		URL     string
+		Overlap string
+		State   string
+	}
+	similar := []Similar{}
+	for _, s := range h.similar {
+		similar = append(similar, Similar{
+			URL:     s.Request.URL(),
+			Overlap: s.Overlap.String(),
+			State:   s.Request.State().String(),
+		})
+	}
 	t.Execute(res, struct {
-		LoggedIn    bool
-		UserIsAdmin bool
-		Successes   []string
-	}{true, activeUser.IsAdmin(), h.Successes})
+		URL          string
+		Instructions string
+		Similar      []Similar
+		LoggedIn     bool
+		UserIsAdmin  bool
+		Successes    []string
+	}{h.url, h.instructions, similar, true, activeUser.IsAdmin(), h.Successes})
 }
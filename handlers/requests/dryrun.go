@@ -0,0 +1,117 @@
+package requests
+
+import (
+	"../../auth"
+	"../../config"
+	"../../models"
+	"../../tasks"
+	"../common"
+	"../fail"
+
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"time"
+)
+
+// dryRunPage is the name of the HTML template showing the result of testing a
+// monitor script before a monitor is created for it.
+const dryRunPage string = "dryrun.html"
+
+// serveDryRun serves a page showing how a test run of a monitor script went,
+// with a form that creates a monitor for the same script and settings.
+func serveDryRun(
+	res http.ResponseWriter,
+	req *http.Request,
+	cfg *config.Config,
+	db *sql.DB,
+	request models.Request,
+	monitor models.Monitor,
+	note string,
+	result tasks.DryRunResult) {
+	t, err := template.ParseFiles(
+		path.Join(cfg.TemplateDir, dryRunPage),
+		path.Join(cfg.TemplateDir, common.HeadTemplate),
+		path.Join(cfg.TemplateDir, common.NavTemplate))
+	if err != nil {
+		fail.InternalError(res, req, cfg, common.ErrTemplateLoad, true, true)
+		return
+	}
+	csrfToken := models.GenerateAntiCSRFToken(db, auth.AntiCSRFTokenLength)
+	saveErr := csrfToken.Save(db)
+	if saveErr != nil {
+		fail.InternalError(res, req, cfg, common.ErrDatabaseOperation, true, true)
+		return
+	}
+	// The script is kept under the token in the page, so that only the
+	// administrator who tested it can create a monitor for it.
+	if monitor.ScriptPath() != "" {
+		keepErr := common.KeepTestedScript(monitor.ScriptPath(), csrfToken.Token())
+		if keepErr != nil {
+			fmt.Println("Could not keep tested script", keepErr)
+			fail.InternalError(res, req, cfg, common.ErrCreateFile, true, true)
+			return
+		}
+	}
+	errMessage := ""
+	state := ""
+	if result.Err != nil {
+		errMessage = result.Err.Error()
+	} else {
+		encoded, encodeErr := json.MarshalIndent(result.Report.State(), "", "  ")
+		if encodeErr != nil {
+			fmt.Println("Could not encode report state", encodeErr)
+		}
+		state = string(encoded)
+	}
+	t.Execute(res, struct {
+		RequestID       int
+		URL             string
+		Filetype        string
+		TestedScript    bool
+		WaitPeriod      uint
+		ExpectedRuntime uint
+		Note            string
+		Succeeded       bool
+		Outcome         string
+		ExitCode        int
+		Duration        time.Duration
+		Stderr          string
+		Error           string
+		Change          string
+		Message         string
+		Checksum        string
+		State           string
+		Content         string
+		CSRFToken       string
+		LoggedIn        bool
+		UserIsAdmin     bool
+		Successes       []string
+	}{
+		request.ID(),
+		request.URL(),
+		string(monitor.Interpreter()),
+		monitor.ScriptPath() != "",
+		uint(monitor.WaitPeriod().Minutes()),
+		uint(monitor.ExpectedRunTime().Seconds()),
+		note,
+		result.Err == nil,
+		result.Run.Outcome().String(),
+		result.Run.ExitCode(),
+		result.Run.Duration(),
+		result.Run.Stderr(),
+		errMessage,
+		result.Report.Change().String(),
+		result.Report.Message(),
+		result.Report.Checksum(),
+		state,
+		result.Report.Content(),
+		csrfToken.Token(),
+		true,
+		true,
+		[]string{},
+	})
+}
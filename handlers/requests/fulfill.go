@@ -4,26 +4,19 @@ import (
 	"../../auth"
 	"../../config"
 	"../../models"
+	"../../notify"
+	"../../tasks"
 	"../common"
 	"../fail"
 
 	"database/sql"
-	"encoding/hex"
-	"errors"
 	"fmt"
-	"io"
-	"math/rand"
 	"net/http"
-	"os"
-	"path"
 	"strconv"
+	"strings"
 	"time"
 )
 
-// filenameLength is the number of random bytes to get to generate names
-// for uploaded scripts with.
-const filenameLength int = 16
-
 // FulfillHandler implements net/http.ServeHTTP to handle new monitor
 // script uploads from administrators.
 type FulfillHandler struct {
@@ -39,7 +32,16 @@ func NewFulfillHandler(c *config.Config, db *sql.DB) FulfillHandler {
 	}
 }
 
-// ServeHTTP handles file uploads containing new monitor scripts.
+// testRunAction is the value of the action field submitted when an
+// administrator wants to try a script out before creating a monitor for it.
+const testRunAction string = "test"
+
+// ServeHTTP handles file uploads containing new monitor scripts. When the
+// action field is "test", the script is saved to a temporary directory and run
+// once, and a page showing the result is served instead of creating a monitor.
+// That page lets the administrator confirm that they want a monitor created for
+// the script they tested, which is set when the testedScript field is, and
+// found by the anti-CSRF token embedded in the page.
 func (h FulfillHandler) ServeHTTP(
 	res http.ResponseWriter, req *http.Request) {
 	// Check that the request is being made by an authenticated administrator.
@@ -66,7 +68,18 @@ func (h FulfillHandler) ServeHTTP(
 	expectedRuntime, parseErr2 := strconv.Atoi(req.FormValue("expectedRuntime"))
 	requestID, parseErr3 := strconv.Atoi(req.FormValue("satisfiedRequest"))
 	filetype := req.FormValue("filetype")
-	ext, ftErr := filetypeExtension(filetype)
+	note := strings.TrimSpace(req.FormValue("note"))
+	if len(note) > common.MaxScriptNoteLength {
+		fail.BadRequest(res, req, h.cfg, common.ErrScriptNoteTooLong, true, true)
+		return
+	}
+	// Fetch monitors are run by miru itself and don't need a script.
+	needsScript := filetype != string(models.FetchInterpreter)
+	var ext string
+	var ftErr error
+	if needsScript {
+		ext, ftErr = common.FiletypeExtension(h.cfg, filetype)
+	}
 	if ftErr != nil || parseErr1 != nil || parseErr2 != nil || parseErr3 != nil {
 		fmt.Println(ftErr)
 		fail.BadRequest(res, req, h.cfg, common.ErrGenericInvalidData, true, true)
@@ -79,24 +92,38 @@ func (h FulfillHandler) ServeHTTP(
 		fail.BadRequest(res, req, h.cfg, common.ErrGenericInvalidData, true, true)
 		return
 	}
-	file, _, openErr := req.FormFile("script")
-	if openErr != nil {
-		fmt.Printf("Error: %v\n", openErr)
-		fail.BadRequest(res, req, h.cfg, common.ErrCreateFile, true, true)
+	if !canFulfill(request, activeUser) {
+		fail.BadRequest(res, req, h.cfg, errRequestNotClaimable, true, true)
 		return
 	}
-	defer file.Close()
-	// Find a place to save the file to on disk.
-	filename := generateUniqueFilename(h.cfg.ScriptDir, ext)
-	toDisk, openErr := os.Create(filename)
-	if openErr != nil {
-		fmt.Printf("Error: %v\n", openErr)
+	// Scripts being tested are kept apart from monitors' scripts until a monitor
+	// is created for them. A script that was already tested is kept under the
+	// anti-CSRF token submitted with the form confirming that a monitor should
+	// be created for it, or that it should be tested again.
+	testing := req.FormValue("action") == testRunAction
+	useTested := needsScript && req.FormValue("testedScript") != ""
+	filename, checksum := "", ""
+	var scriptErr error
+	switch {
+	case !needsScript:
+	case testing && useTested:
+		filename, scriptErr = common.FindTestedScript(csrfToken, ext)
+	case testing:
+		filename, scriptErr = common.SaveTestedScript(req, ext)
+	case useTested:
+		filename, checksum, scriptErr = common.TakeTestedScript(h.cfg.ScriptDir, csrfToken, ext)
+	default:
+		filename, checksum, scriptErr = common.SaveUploadedScript(req, h.cfg.ScriptDir, ext)
+	}
+	if scriptErr != nil && useTested {
+		fmt.Println("Could not find tested script", scriptErr)
+		fail.BadRequest(res, req, h.cfg, common.ErrGenericInvalidData, true, true)
+		return
+	} else if scriptErr != nil {
+		fmt.Printf("Error: %v\n", scriptErr)
 		fail.InternalError(res, req, h.cfg, common.ErrCreateFile, true, true)
 		return
 	}
-	defer toDisk.Close()
-	io.Copy(toDisk, file)
-	// Create a new Monitor in the database.
 	monitor := models.NewMonitor(
 		activeUser,
 		request,
@@ -104,51 +131,27 @@ func (h FulfillHandler) ServeHTTP(
 		filename,
 		time.Duration(waitPeriod)*time.Minute,
 		time.Duration(expectedRuntime)*time.Second)
+	if testing {
+		result := tasks.DryRun(monitor, request.URL(), tasks.NewRunOptions(h.cfg))
+		serveDryRun(res, req, h.cfg, h.db, request, monitor, note, result)
+		return
+	}
+	// Create a new Monitor in the database, claiming the request first if
+	// nobody has, so that no other administrator can fulfill it at the same time.
 	fmt.Println("Creating monitor for", request.ID())
-	fmt.Println("Monitor", monitor)
-	saveErr := monitor.Save(h.db)
-	if saveErr != nil {
-		fmt.Println(saveErr)
+	_, fulfillErr := request.FulfillWithMonitor(h.db, activeUser, &monitor, checksum, note)
+	switch fulfillErr {
+	case nil:
+	case models.ErrClaimedByAnother, models.ErrRequestChanged:
+		fail.BadRequest(res, req, h.cfg, fulfillErr, true, true)
+		return
+	default:
+		fmt.Println("Could not create monitor", fulfillErr)
 		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
 		return
 	}
+	notifyRequester(h.cfg, h.db, request, monitor.ID(), notify.RequestFulfilled)
 	handler := NewListHandler(h.cfg, h.db)
 	handler.PushSuccessMsg(fmt.Sprintf("Successfully created a new monitor script with ID %d", monitor.ID()))
 	handler.ServeHTTP(res, req)
 }
-
-// generateUniqueFilename produces a filename that is guaranteed to be unique.
-// It continuously generates 16-byte script names, encoded as hex, until one
-// is created that isn't already taken.
-func generateUniqueFilename(scriptDir string, ext string) string {
-	// We don't need cryptographically random names- pseudorandom will do.
-	rand.Seed(int64(time.Now().Unix()))
-	bytes := make([]byte, filenameLength)
-	for {
-		bytesRead, readErr := rand.Read(bytes)
-		for readErr != nil || bytesRead != filenameLength {
-			bytesRead, readErr = rand.Read(bytes)
-		}
-		filename := path.Join(scriptDir, hex.EncodeToString(bytes))
-		f, openErr := os.Open(filename)
-		if openErr != nil {
-			return filename + "." + ext
-		}
-		f.Close()
-	}
-}
-
-// Converts a filetype, the values in the upload form's filetype dropdown
-// menu, into the file type's extension.
-func filetypeExtension(filetype string) (string, error) {
-	switch filetype {
-	case "python":
-		return "py", nil
-	case "ruby":
-		return "rb", nil
-	case "perl":
-		return "pl", nil
-	default:
-		return "", errors.New("unknown filetype")
-	}
-}
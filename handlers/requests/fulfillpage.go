@@ -69,6 +69,15 @@ func (h FulfillPageHandler) ServeHTTP(res http.ResponseWriter, req *http.Request
 		fail.BadRequest(res, req, h.cfg, common.ErrGenericInvalidData, true, activeUser.IsAdmin())
 		return
 	}
+	request, findErr := models.FindRequest(h.db, requestID)
+	if findErr != nil {
+		fail.BadRequest(res, req, h.cfg, errors.New("no such request"), true, activeUser.IsAdmin())
+		return
+	}
+	if !canFulfill(request, activeUser) {
+		fail.BadRequest(res, req, h.cfg, errRequestNotClaimable, true, activeUser.IsAdmin())
+		return
+	}
 	t, err := template.ParseFiles(
 		path.Join(h.cfg.TemplateDir, uploadPage),
 		path.Join(h.cfg.TemplateDir, common.HeadTemplate),
@@ -85,9 +94,17 @@ func (h FulfillPageHandler) ServeHTTP(res http.ResponseWriter, req *http.Request
 	}
 	t.Execute(res, struct {
 		CreatedFor  int
+		ScriptTypes []common.ScriptType
 		LoggedIn    bool
 		UserIsAdmin bool
 		CSRFToken   string
 		Successes   []string
-	}{requestID, true, activeUser.IsAdmin(), csrfToken.Token(), h.Successes})
+	}{requestID, common.ScriptTypes(h.cfg), true, activeUser.IsAdmin(), csrfToken.Token(), h.Successes})
+}
+
+// canFulfill determines whether an administrator can fulfill a request, which
+// they can if it is pending or they have claimed it.
+func canFulfill(request models.Request, admin models.Archiver) bool {
+	return request.State() == models.PendingState ||
+		(request.State().IsOpen() && request.ClaimedBy() == admin.ID())
 }
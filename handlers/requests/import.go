@@ -0,0 +1,93 @@
+package requests
+
+import (
+	"../../auth"
+	"../../config"
+	"../../models"
+	"../common"
+	"../fail"
+
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxImportBytes is the largest CSV or JSON file that can be imported.
+const maxImportBytes int64 = 4 * 1024 * 1024
+
+// ImportHandler implements net/http.ServeHTTP to handle administrators
+// importing many requests to have sites monitored at once.
+type ImportHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewImportHandler is the constructor function for an ImportHandler.
+func NewImportHandler(cfg *config.Config, db *sql.DB) ImportHandler {
+	return ImportHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP imports the requests in an uploaded file, or pasted into the form,
+// in the format chosen. Requests are only created if every row is valid, and
+// otherwise the import page lists the problem with each row.
+func (h ImportHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	// Check that the request is coming from an authenticated administrator.
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	activeUser, err := models.FindSessionOwner(h.db, cookie.Value)
+	if err != nil || !activeUser.IsAdmin() {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, err == nil, false)
+		return
+	}
+	// Extract inputs from the form.
+	req.Body = http.MaxBytesReader(res, req.Body, maxImportBytes)
+	parseErr := req.ParseMultipartForm(maxImportBytes)
+	if parseErr != nil {
+		fmt.Println("Could not parse import form", parseErr)
+		fail.BadRequest(res, req, h.cfg, common.ErrGenericInvalidData, true, true)
+		return
+	}
+	csrfToken := req.FormValue("csrfToken")
+	if !models.VerifyAndDeleteAntiCSRFToken(h.db, csrfToken) {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, true, true)
+		return
+	}
+	var input io.Reader = strings.NewReader(req.FormValue("rows"))
+	file, _, openErr := req.FormFile("file")
+	if openErr == nil {
+		defer file.Close()
+		input = file
+	} else if strings.TrimSpace(req.FormValue("rows")) == "" {
+		fail.BadRequest(res, req, h.cfg, errors.New("please upload a file or paste the rows to import"), true, true)
+		return
+	}
+	rows, decodeErr := models.DecodeImport(req.FormValue("format"), input)
+	if decodeErr != nil {
+		fail.BadRequest(res, req, h.cfg, fmt.Errorf("could not read the import: %v", decodeErr), true, true)
+		return
+	}
+	requests, importErr := models.ImportRequests(h.db, activeUser, rows)
+	handler := NewImportPageHandler(h.cfg, h.db)
+	handler.ShowRows(rows)
+	if importErr == models.ErrInvalidImport {
+		handler.ServeHTTP(res, req)
+		return
+	}
+	if importErr != nil {
+		fmt.Println("Could not import requests", importErr)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
+		return
+	}
+	fmt.Println("Imported", len(requests), "requests")
+	handler.PushSuccessMsg(fmt.Sprintf("Imported %d requests", len(requests)))
+	handler.ServeHTTP(res, req)
+}
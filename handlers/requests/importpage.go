@@ -0,0 +1,106 @@
+package requests
+
+import (
+	"../../auth"
+	"../../config"
+	"../../models"
+	"../common"
+	"../fail"
+
+	"database/sql"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+)
+
+// importPage is the name of the HTML template containing the form that
+// administrators import many requests at once through.
+const importPage string = "import.html"
+
+// ImportPageHandler implements net/http.ServeHTTP to serve the bulk import page.
+type ImportPageHandler struct {
+	cfg       *config.Config
+	db        *sql.DB
+	Successes []string
+	rows      []models.ImportRow
+}
+
+// NewImportPageHandler is the constructor function for an ImportPageHandler.
+func NewImportPageHandler(cfg *config.Config, db *sql.DB) ImportPageHandler {
+	return ImportPageHandler{
+		cfg:       cfg,
+		db:        db,
+		Successes: []string{},
+		rows:      []models.ImportRow{},
+	}
+}
+
+// PushSuccessMsg adds a new message that will be displayed on the page served by the
+// handler to indicate a successful operation.
+func (h *ImportPageHandler) PushSuccessMsg(msg string) {
+	h.Successes = append(h.Successes, msg)
+}
+
+// ShowRows has the page list the rows of an import, along with the problem
+// found with each one, if any.
+func (h *ImportPageHandler) ShowRows(rows []models.ImportRow) {
+	h.rows = rows
+}
+
+// ServeHTTP serves a page that administrators can upload a CSV or JSON list of
+// requests through.
+func (h ImportPageHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	// Check that the request is coming from an authenticated administrator.
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	activeUser, err := models.FindSessionOwner(h.db, cookie.Value)
+	if err != nil || !activeUser.IsAdmin() {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, err == nil, false)
+		return
+	}
+	csrfToken := models.GenerateAntiCSRFToken(h.db, auth.AntiCSRFTokenLength)
+	saveErr := csrfToken.Save(h.db)
+	if saveErr != nil {
+		fmt.Println("Could not save anti-CSRF token", saveErr)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
+		return
+	}
+	// Serve the page.
+	t, err := template.ParseFiles(
+		path.Join(h.cfg.TemplateDir, importPage),
+		path.Join(h.cfg.TemplateDir, common.HeadTemplate),
+		path.Join(h.cfg.TemplateDir, common.NavTemplate))
+	if err != nil {
+		fail.InternalError(res, req, h.cfg, common.ErrTemplateLoad, true, true)
+		return
+	}
+	type Row struct {
+		Row          int
+		URL          string
+		Instructions string
+		Problem      string
+	}
+	rows := []Row{}
+	failed := false
+	for _, row := range h.rows {
+		problem := ""
+		if row.Err != nil {
+			problem = row.Err.Error()
+			failed = true
+		}
+		rows = append(rows, Row{row.Row, row.URL, row.Instructions, problem})
+	}
+	t.Execute(res, struct {
+		Rows        []Row
+		Failed      bool
+		MaxRows     int
+		CSRFToken   string
+		LoggedIn    bool
+		UserIsAdmin bool
+		Successes   []string
+	}{rows, failed, models.MaxImportRows, csrfToken.Token(), true, true, h.Successes})
+}
@@ -63,12 +63,30 @@ func (h ListHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
 		return
 	}
+	// Compare every open request to the others so that admins can merge duplicates.
+	active, err := models.ListActiveRequests(h.db)
+	if err != nil {
+		fmt.Println("Could not get active requests", err)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
+		return
+	}
+	type Similar struct {
+		ID      int
+		URL     string
+		Overlap string
+	}
 	type Data struct {
 		MadeBy       string
 		URL          string
 		Instructions string
 		CSRFToken    string
 		RequestID    int
+		State        string
+		IsPending    bool
+		IsInProgress bool
+		ClaimedBy    string
+		ClaimedByMe  bool
+		Similar      []Similar
 	}
 	pendingRequests := []Data{}
 	for _, request := range requests {
@@ -86,13 +104,37 @@ func (h ListHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 		if saveErr != nil {
 			fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
 		}
+		claimedBy := ""
+		if request.ClaimedBy() >= 0 {
+			claimant, findErr := models.FindArchiver(h.db, request.ClaimedBy())
+			if findErr == nil {
+				claimedBy = claimant.Email()
+			}
+		}
 		pendingRequests = append(pendingRequests, Data{
 			MadeBy:       madeBy,
 			URL:          request.URL(),
 			Instructions: request.Instructions(),
 			CSRFToken:    csrfToken.Token(),
 			RequestID:    request.ID(),
+			State:        request.State().String(),
+			IsPending:    request.State() == models.PendingState,
+			IsInProgress: request.State() == models.InProgressState,
+			ClaimedBy:    claimedBy,
+			ClaimedByMe:  request.ClaimedBy() == archiver.ID(),
+			Similar:      []Similar{},
 		})
+		for _, other := range active {
+			if other.ID() == request.ID() {
+				continue
+			}
+			overlap := models.CompareURLs(request.URL(), other.URL())
+			if overlap == models.NoOverlap {
+				continue
+			}
+			row := &pendingRequests[len(pendingRequests)-1]
+			row.Similar = append(row.Similar, Similar{other.ID(), other.URL(), overlap.String()})
+		}
 	}
 	// Serve the listing page.
 	t, err := template.ParseFiles(
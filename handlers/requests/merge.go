@@ -0,0 +1,78 @@
+package requests
+
+import (
+	"../../auth"
+	"../../config"
+	"../../models"
+	"../../notify"
+	"../common"
+	"../fail"
+
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// MergeHandler implements net/http.ServeHTTP to handle administrators merging
+// a duplicate request into another request for the same site.
+type MergeHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewMergeHandler is the constructor function for a MergeHandler.
+func NewMergeHandler(cfg *config.Config, db *sql.DB) MergeHandler {
+	return MergeHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP merges the request identified by requestID into the one identified
+// by intoID, so that both archivers share the monitor created for the latter.
+func (h MergeHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	// Check that the request is coming from an authenticated administrator.
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		fmt.Println("No cookie", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	archiver, err := models.FindSessionOwner(h.db, cookie.Value)
+	if err != nil || !archiver.IsAdmin() {
+		fmt.Println("Not admin", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, err == nil, false)
+		return
+	}
+	// Extract inputs from the submitted form.
+	req.ParseForm()
+	csrfToken := req.FormValue("csrfToken")
+	if !models.VerifyAndDeleteAntiCSRFToken(h.db, csrfToken) {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, true, true)
+		return
+	}
+	id, parseErr1 := strconv.Atoi(req.FormValue("requestID"))
+	intoID, parseErr2 := strconv.Atoi(req.FormValue("intoID"))
+	if parseErr1 != nil || parseErr2 != nil {
+		fail.BadRequest(res, req, h.cfg, common.ErrGenericInvalidData, true, true)
+		return
+	}
+	request, findErr1 := models.FindRequest(h.db, id)
+	into, findErr2 := models.FindRequest(h.db, intoID)
+	if findErr1 != nil || findErr2 != nil {
+		fail.BadRequest(res, req, h.cfg, errors.New("no such request"), true, true)
+		return
+	}
+	mergeErr := request.Merge(h.db, archiver, into)
+	if mergeErr != nil {
+		fmt.Println("Could not merge request", mergeErr)
+		fail.BadRequest(res, req, h.cfg, mergeErr, true, true)
+		return
+	}
+	notifyRequester(h.cfg, h.db, request, -1, notify.RequestMerged)
+	handler := NewListHandler(h.cfg, h.db)
+	handler.PushSuccessMsg(fmt.Sprintf("Merged request with ID %d into request with ID %d", id, intoID))
+	handler.ServeHTTP(res, req)
+}
@@ -0,0 +1,120 @@
+package requests
+
+import (
+	"../../auth"
+	"../../config"
+	"../../models"
+	"../common"
+	"../fail"
+
+	"database/sql"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"time"
+)
+
+// myRequestsPage is the name of the HTML template that lists the requests made
+// by an archiver.
+const myRequestsPage string = "myrequests.html"
+
+// maxMergeHops is the most requests that will be followed to find the one that
+// a request was merged into, in case it was merged into a request that was
+// itself merged into another.
+const maxMergeHops int = 5
+
+// MyRequestsPageHandler implements net/http.ServeHTTP to serve archivers a page
+// listing every request they have made and what has happened to it.
+type MyRequestsPageHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewMyRequestsPageHandler is the constructor function for a MyRequestsPageHandler.
+func NewMyRequestsPageHandler(cfg *config.Config, db *sql.DB) MyRequestsPageHandler {
+	return MyRequestsPageHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP serves a page listing the archiver's requests, newest first, along
+// with the status of each, the reason it was rejected, the monitor created for
+// it, and how significant the change in its latest report was.
+func (h MyRequestsPageHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	// Check that the request is coming from an authenticated archiver.
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	activeUser, err := models.FindSessionOwner(h.db, cookie.Value)
+	if err != nil {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	requests, findErr := models.ListRequestsForCreator(h.db, activeUser)
+	if findErr != nil {
+		fmt.Println("Could not list requests", findErr)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, activeUser.IsAdmin())
+		return
+	}
+	type Data struct {
+		ID                 int
+		URL                string
+		CreatedAt          time.Time
+		State              string
+		RejectionReason    string
+		MonitorID          int
+		LastReportAt       time.Time
+		ChangeSignificance string
+	}
+	data := []Data{}
+	for _, request := range requests {
+		row := Data{
+			ID:              request.ID(),
+			URL:             request.URL(),
+			CreatedAt:       request.CreatedAt(),
+			State:           request.State().String(),
+			RejectionReason: request.RejectionReason(),
+			MonitorID:       -1,
+		}
+		// Requests merged into another share the other request's monitor.
+		monitored := request
+		for hops := 0; hops < maxMergeHops && monitored.MergedInto() >= 0; hops++ {
+			into, findErr := models.FindRequest(h.db, monitored.MergedInto())
+			if findErr != nil {
+				break
+			}
+			monitored = into
+			row.State = fmt.Sprintf("%s into #%d (%s)",
+				request.State().String(), into.ID(), into.State().String())
+		}
+		monitor, findErr := models.FindMonitorForRequest(h.db, monitored)
+		if findErr == nil {
+			row.MonitorID = monitor.ID()
+			report, findErr := models.FindLastReportForMonitor(h.db, monitor)
+			if findErr == nil {
+				row.LastReportAt = report.CreatedAt()
+				row.ChangeSignificance = report.Change().String()
+			}
+		}
+		data = append(data, row)
+	}
+	t, err := template.ParseFiles(
+		path.Join(h.cfg.TemplateDir, myRequestsPage),
+		path.Join(h.cfg.TemplateDir, common.HeadTemplate),
+		path.Join(h.cfg.TemplateDir, common.NavTemplate))
+	if err != nil {
+		fmt.Println("Could not load template", err)
+		fail.InternalError(res, req, h.cfg, common.ErrTemplateLoad, true, activeUser.IsAdmin())
+		return
+	}
+	t.Execute(res, struct {
+		Requests    []Data
+		LoggedIn    bool
+		UserIsAdmin bool
+		Successes   []string
+	}{data, true, activeUser.IsAdmin(), []string{}})
+}
@@ -0,0 +1,64 @@
+package requests
+
+import (
+	"../../config"
+	"../../models"
+	"../../notify"
+
+	"database/sql"
+	"fmt"
+)
+
+// notifyRequester emails the archiver who made a request about what happened
+// to it, along with the archivers whose duplicate requests were merged into it.
+// The emails are sent in the background so that the administrator isn't kept
+// waiting on the mail server, and failures are only logged.
+func notifyRequester(
+	cfg *config.Config,
+	db *sql.DB,
+	request models.Request,
+	monitorID int,
+	compose func(string, notify.RequestData) (notify.Message, error)) {
+	mergedURL := ""
+	if request.MergedInto() >= 0 {
+		into, findErr := models.FindRequest(db, request.MergedInto())
+		if findErr == nil {
+			mergedURL = into.URL()
+		}
+	}
+	requests := []models.Request{request}
+	duplicates, findErr := models.ListRequestsMergedInto(db, request)
+	if findErr != nil {
+		fmt.Println("Could not find requests merged into request", request.ID(), findErr)
+	}
+	requests = append(requests, duplicates...)
+	messages := []notify.Message{}
+	for _, r := range requests {
+		requester, findErr := models.FindArchiver(db, r.Creator())
+		if findErr != nil {
+			fmt.Println("Could not find the archiver who made request", r.ID(), findErr)
+			continue
+		}
+		msg, composeErr := compose(requester.Email(), notify.RequestData{
+			RequestID: r.ID(),
+			URL:       r.URL(),
+			MonitorID: monitorID,
+			Reason:    request.RejectionReason(),
+			MergedURL: mergedURL,
+		})
+		if composeErr != nil {
+			fmt.Println("Could not compose email to requester", composeErr)
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	sender := notify.NewSender(cfg)
+	go func() {
+		for _, msg := range messages {
+			sendErr := sender.Send(msg)
+			if sendErr != nil {
+				fmt.Println("Could not email requester", sendErr)
+			}
+		}
+	}()
+}
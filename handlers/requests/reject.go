@@ -4,6 +4,7 @@ import (
 	"../../auth"
 	"../../config"
 	"../../models"
+	"../../notify"
 	"../common"
 	"../fail"
 
@@ -12,6 +13,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 // RejectHandler implements net/http.ServeHTTP to handle the rejection
@@ -29,7 +31,8 @@ func NewRejectHandler(cfg *config.Config, db *sql.DB) RejectHandler {
 	}
 }
 
-// ServeHTTP deletes a pending request.
+// ServeHTTP rejects an open request, recording the reason given so that the
+// archiver who made it can see why.
 func (h RejectHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	// Check that the request is coming from an authenticated administrator.
 	cookie, err := req.Cookie(auth.SessionCookieName)
@@ -63,11 +66,14 @@ func (h RejectHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 		fail.BadRequest(res, req, h.cfg, errors.New("no such request"), true, true)
 		return
 	}
-	deleteErr := request.Delete(h.db)
-	if deleteErr != nil {
-		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
+	reason := strings.TrimSpace(req.FormValue("reason"))
+	rejectErr := request.Reject(h.db, archiver, reason)
+	if rejectErr != nil {
+		fmt.Println("Could not reject request", rejectErr)
+		fail.BadRequest(res, req, h.cfg, rejectErr, true, true)
 		return
 	}
+	notifyRequester(h.cfg, h.db, request, -1, notify.RequestRejected)
 	handler := NewListHandler(h.cfg, h.db)
 	handler.PushSuccessMsg(fmt.Sprintf("Successfully rejected request with ID %d", id))
 	handler.ServeHTTP(res, req)
@@ -16,4 +16,12 @@ func RegisterHandlers(r *mux.Router, cfg *config.Config, db *sql.DB) {
 	r.Handle("/fulfill", NewFulfillPageHandler(cfg, db)).Methods("GET")
 	r.Handle("/fulfill", NewFulfillHandler(cfg, db)).Methods("POST")
 	r.Handle("/reject", NewRejectHandler(cfg, db)).Methods("POST")
+	r.Handle("/claim", NewClaimHandler(cfg, db)).Methods("POST")
+	r.Handle("/start", NewStartHandler(cfg, db)).Methods("POST")
+	r.Handle("/release", NewReleaseHandler(cfg, db)).Methods("POST")
+	r.Handle("/merge", NewMergeHandler(cfg, db)).Methods("POST")
+	r.Handle("/view", NewViewPageHandler(cfg, db)).Methods("GET")
+	r.Handle("/mine", NewMyRequestsPageHandler(cfg, db)).Methods("GET")
+	r.Handle("/import", NewImportPageHandler(cfg, db)).Methods("GET")
+	r.Handle("/import", NewImportHandler(cfg, db)).Methods("POST")
 }
@@ -0,0 +1,119 @@
+package requests
+
+import (
+	"../../auth"
+	"../../config"
+	"../../models"
+	"../common"
+	"../fail"
+
+	"database/sql"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+)
+
+// requestViewPage is the name of the HTML template that shows the status and
+// history of a single monitor request.
+const requestViewPage string = "requestview.html"
+
+// ViewPageHandler implements net/http.ServeHTTP to serve a page showing the
+// status of a request, and every change made to it, to the archiver who made
+// it and to administrators.
+type ViewPageHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewViewPageHandler is the constructor function for a ViewPageHandler.
+func NewViewPageHandler(cfg *config.Config, db *sql.DB) ViewPageHandler {
+	return ViewPageHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP serves the page for the request identified by the id url parameter.
+func (h ViewPageHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	// Check that the request is coming from an authenticated archiver.
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	activeUser, err := models.FindSessionOwner(h.db, cookie.Value)
+	if err != nil {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	requestID, parseErr := strconv.Atoi(req.URL.Query().Get("id"))
+	if parseErr != nil {
+		fail.BadRequest(res, req, h.cfg, errors.New("missing or invalid request id url parameter"), true, activeUser.IsAdmin())
+		return
+	}
+	request, findErr := models.FindRequest(h.db, requestID)
+	if findErr != nil || (request.Creator() != activeUser.ID() && !activeUser.IsAdmin()) {
+		fail.BadRequest(res, req, h.cfg, errors.New("no such request"), true, activeUser.IsAdmin())
+		return
+	}
+	transitions, findErr := models.ListTransitionsForRequest(h.db, request)
+	if findErr != nil {
+		fmt.Println("Could not list request transitions", findErr)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, activeUser.IsAdmin())
+		return
+	}
+	type Data struct {
+		Actor     string
+		From      string
+		To        string
+		Reason    string
+		CreatedAt time.Time
+	}
+	history := []Data{}
+	for _, transition := range transitions {
+		// Only administrators are shown who else is working on requests.
+		actor := "An administrator"
+		if activeUser.IsAdmin() {
+			archiver, findErr := models.FindArchiver(h.db, transition.Actor())
+			if findErr == nil {
+				actor = archiver.Email()
+			}
+		}
+		history = append(history, Data{
+			Actor:     actor,
+			From:      transition.From().String(),
+			To:        transition.To().String(),
+			Reason:    transition.Reason(),
+			CreatedAt: transition.CreatedAt(),
+		})
+	}
+	t, err := template.ParseFiles(
+		path.Join(h.cfg.TemplateDir, requestViewPage),
+		path.Join(h.cfg.TemplateDir, common.HeadTemplate),
+		path.Join(h.cfg.TemplateDir, common.NavTemplate))
+	if err != nil {
+		fmt.Println("Could not load template", err)
+		fail.InternalError(res, req, h.cfg, common.ErrTemplateLoad, true, activeUser.IsAdmin())
+		return
+	}
+	t.Execute(res, struct {
+		ID              int
+		URL             string
+		Instructions    string
+		CreatedAt       time.Time
+		State           string
+		RejectionReason string
+		History         []Data
+		LoggedIn        bool
+		UserIsAdmin     bool
+		Successes       []string
+	}{
+		request.ID(), request.URL(), request.Instructions(), request.CreatedAt(),
+		request.State().String(), request.RejectionReason(), history,
+		true, activeUser.IsAdmin(), []string{},
+	})
+}
@@ -0,0 +1,139 @@
+package webhooks
+
+import (
+	"../../auth"
+	"../../config"
+	"../../models"
+	"../common"
+	"../fail"
+
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// CreateHandler implements net/http.ServeHTTP to handle requests from
+// administrators to register new webhooks.
+type CreateHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewCreateHandler is the constructor function for a CreateHandler.
+func NewCreateHandler(cfg *config.Config, db *sql.DB) CreateHandler {
+	return CreateHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP registers a webhook with the URL and threshold submitted.
+func (h CreateHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	// Check that the request is coming from an authenticated administrator.
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		fmt.Println("Could not find cookie", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	activeUser, err := models.FindSessionOwner(h.db, cookie.Value)
+	if err != nil || !activeUser.IsAdmin() {
+		fmt.Println("Could not get cookie owner", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, err == nil, false)
+		return
+	}
+	// Extract the data submitted in the form.
+	req.ParseForm()
+	csrfToken := req.FormValue("csrfToken")
+	if !models.VerifyAndDeleteAntiCSRFToken(h.db, csrfToken) {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, true, true)
+		return
+	}
+	webhookURL, parseErr := url.Parse(req.FormValue("url"))
+	if parseErr != nil || !webhookURL.IsAbs() ||
+		(webhookURL.Scheme != "http" && webhookURL.Scheme != "https") {
+		fail.BadRequest(res, req, h.cfg, errors.New("webhooks must have an http or https url"), true, true)
+		return
+	}
+	minChange, parseErr := strconv.Atoi(req.FormValue("minChange"))
+	if parseErr != nil || minChange < int(models.NoChange) || minChange > int(models.Deleted) {
+		fail.BadRequest(res, req, h.cfg, common.ErrGenericInvalidData, true, true)
+		return
+	}
+	webhook, newErr := models.NewWebhook(activeUser, webhookURL.String(), models.Importance(minChange))
+	if newErr != nil {
+		fmt.Println("Could not generate webhook secret", newErr)
+		fail.InternalError(res, req, h.cfg, errors.New("could not generate a secret for the webhook"), true, true)
+		return
+	}
+	saveErr := webhook.Save(h.db)
+	if saveErr != nil {
+		fmt.Println("Could not save webhook", saveErr)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
+		return
+	}
+	handler := NewListHandler(h.cfg, h.db)
+	handler.PushSuccessMsg(fmt.Sprintf("Registered a webhook for %s.", webhook.URL()))
+	handler.ServeHTTP(res, req)
+}
+
+// DeleteHandler implements net/http.ServeHTTP to handle requests from
+// administrators to stop sending reports to a webhook.
+type DeleteHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewDeleteHandler is the constructor function for a DeleteHandler.
+func NewDeleteHandler(cfg *config.Config, db *sql.DB) DeleteHandler {
+	return DeleteHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP deletes the webhook submitted.
+func (h DeleteHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	// Check that the request is coming from an authenticated administrator.
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		fmt.Println("Could not find cookie", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	activeUser, err := models.FindSessionOwner(h.db, cookie.Value)
+	if err != nil || !activeUser.IsAdmin() {
+		fmt.Println("Could not get cookie owner", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, err == nil, false)
+		return
+	}
+	// Extract the data submitted in the form.
+	req.ParseForm()
+	csrfToken := req.FormValue("csrfToken")
+	if !models.VerifyAndDeleteAntiCSRFToken(h.db, csrfToken) {
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, true, true)
+		return
+	}
+	webhookID, parseErr := strconv.Atoi(req.FormValue("webhookID"))
+	if parseErr != nil {
+		fail.BadRequest(res, req, h.cfg, common.ErrGenericInvalidData, true, true)
+		return
+	}
+	webhook, findErr := models.FindWebhook(h.db, webhookID)
+	if findErr != nil {
+		fail.BadRequest(res, req, h.cfg, errors.New("no such webhook"), true, true)
+		return
+	}
+	deleteErr := webhook.Delete(h.db)
+	if deleteErr != nil {
+		fmt.Println("Could not delete webhook", deleteErr)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
+		return
+	}
+	handler := NewListHandler(h.cfg, h.db)
+	handler.PushSuccessMsg(fmt.Sprintf("Stopped sending reports to %s.", webhook.URL()))
+	handler.ServeHTTP(res, req)
+}
@@ -0,0 +1,109 @@
+package webhooks
+
+import (
+	"../../auth"
+	"../../config"
+	"../../models"
+	"../common"
+	"../fail"
+
+	"database/sql"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+)
+
+// deliveriesPage is the name of the template HTML file that shows the log of
+// attempts to send reports to a webhook.
+const deliveriesPage string = "webhookdeliveries.html"
+
+// maxDeliveries is the number of the most recent deliveries to show.
+const maxDeliveries uint = 100
+
+// DeliveriesPageHandler implements net/http.ServeHTTP to serve a page to
+// administrators showing the attempts made to send reports to a webhook.
+type DeliveriesPageHandler struct {
+	cfg *config.Config
+	db  *sql.DB
+}
+
+// NewDeliveriesPageHandler is the constructor function for a DeliveriesPageHandler.
+func NewDeliveriesPageHandler(cfg *config.Config, db *sql.DB) DeliveriesPageHandler {
+	return DeliveriesPageHandler{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// ServeHTTP serves the delivery log of the webhook identified by the id url parameter.
+func (h DeliveriesPageHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	// Check that the request is coming from an authenticated administrator.
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		fmt.Println("Could not find cookie", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	activeUser, err := models.FindSessionOwner(h.db, cookie.Value)
+	if err != nil || !activeUser.IsAdmin() {
+		fmt.Println("Could not get cookie owner", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, err == nil, false)
+		return
+	}
+	webhookID, parseErr := strconv.Atoi(req.URL.Query().Get("id"))
+	if parseErr != nil {
+		fail.BadRequest(res, req, h.cfg, errors.New("missing or invalid webhook id url parameter"), true, true)
+		return
+	}
+	webhook, findErr := models.FindWebhook(h.db, webhookID)
+	if findErr != nil {
+		fail.BadRequest(res, req, h.cfg, errors.New("no such webhook"), true, true)
+		return
+	}
+	deliveries, findErr := models.ListDeliveriesForWebhook(h.db, webhook, maxDeliveries)
+	if findErr != nil {
+		fmt.Println("Could not get webhook deliveries", findErr)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
+		return
+	}
+	type Data struct {
+		ReportID    int
+		Attempt     int
+		AttemptedAt time.Time
+		StatusCode  int
+		Succeeded   bool
+		Error       string
+	}
+	data := []Data{}
+	for _, delivery := range deliveries {
+		data = append(data, Data{
+			ReportID:    delivery.Report(),
+			Attempt:     delivery.Attempt(),
+			AttemptedAt: delivery.AttemptedAt(),
+			StatusCode:  delivery.StatusCode(),
+			Succeeded:   delivery.Succeeded(),
+			Error:       delivery.Error(),
+		})
+	}
+	t, err := template.ParseFiles(
+		path.Join(h.cfg.TemplateDir, deliveriesPage),
+		path.Join(h.cfg.TemplateDir, common.HeadTemplate),
+		path.Join(h.cfg.TemplateDir, common.NavTemplate))
+	if err != nil {
+		fmt.Println("Error parsing webhook deliveries page template", err)
+		fail.InternalError(res, req, h.cfg, common.ErrTemplateLoad, true, true)
+		return
+	}
+	t.Execute(res, struct {
+		URL         string
+		IsActive    bool
+		Deliveries  []Data
+		LoggedIn    bool
+		UserIsAdmin bool
+		Successes   []string
+	}{webhook.URL(), webhook.IsActive(), data, true, true, []string{}})
+}
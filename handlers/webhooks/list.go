@@ -0,0 +1,113 @@
+package webhooks
+
+import (
+	"../../auth"
+	"../../config"
+	"../../models"
+	"../common"
+	"../fail"
+
+	"database/sql"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"time"
+)
+
+// webhooksPage is the name of the template HTML file that lists webhooks and
+// contains a form to register new ones.
+const webhooksPage string = "webhooks.html"
+
+// ListHandler implements net/http.ServeHTTP to serve a page to administrators
+// listing the webhooks that reports are sent to.
+type ListHandler struct {
+	cfg       *config.Config
+	db        *sql.DB
+	Successes []string
+}
+
+// NewListHandler is the constructor function for a ListHandler.
+func NewListHandler(cfg *config.Config, db *sql.DB) ListHandler {
+	return ListHandler{
+		cfg:       cfg,
+		db:        db,
+		Successes: []string{},
+	}
+}
+
+// PushSuccessMsg adds a new message that will be displayed on the page served by the
+// handler to indicate a successful operation.
+func (h *ListHandler) PushSuccessMsg(msg string) {
+	h.Successes = append(h.Successes, msg)
+}
+
+// ServeHTTP serves a page with a table of all active webhooks.
+func (h ListHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	// Check that the request is coming from an authenticated administrator.
+	cookie, err := req.Cookie(auth.SessionCookieName)
+	if err != nil {
+		fmt.Println("Could not find cookie", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, false, false)
+		return
+	}
+	activeUser, err := models.FindSessionOwner(h.db, cookie.Value)
+	if err != nil || !activeUser.IsAdmin() {
+		fmt.Println("Could not get cookie owner", err)
+		fail.BadRequest(res, req, h.cfg, common.ErrNotAllowed, err == nil, false)
+		return
+	}
+	webhooks, findErr := models.ListWebhooks(h.db)
+	if findErr != nil {
+		fmt.Println("Could not get webhooks", findErr)
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
+		return
+	}
+	type Data struct {
+		ID        int
+		URL       string
+		Secret    string
+		MinChange string
+		CreatedAt time.Time
+		CSRFToken string
+	}
+	data := []Data{}
+	for _, webhook := range webhooks {
+		csrfToken := models.GenerateAntiCSRFToken(h.db, auth.AntiCSRFTokenLength)
+		saveErr := csrfToken.Save(h.db)
+		if saveErr != nil {
+			fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
+			return
+		}
+		data = append(data, Data{
+			ID:        webhook.ID(),
+			URL:       webhook.URL(),
+			Secret:    webhook.Secret(),
+			MinChange: webhook.MinChange().String(),
+			CreatedAt: webhook.CreatedAt(),
+			CSRFToken: csrfToken.Token(),
+		})
+	}
+	csrfToken := models.GenerateAntiCSRFToken(h.db, auth.AntiCSRFTokenLength)
+	saveErr := csrfToken.Save(h.db)
+	if saveErr != nil {
+		fail.InternalError(res, req, h.cfg, common.ErrDatabaseOperation, true, true)
+		return
+	}
+	t, err := template.ParseFiles(
+		path.Join(h.cfg.TemplateDir, webhooksPage),
+		path.Join(h.cfg.TemplateDir, common.HeadTemplate),
+		path.Join(h.cfg.TemplateDir, common.NavTemplate))
+	if err != nil {
+		fmt.Println("Error parsing webhooks page template", err)
+		fail.InternalError(res, req, h.cfg, common.ErrTemplateLoad, true, true)
+		return
+	}
+	t.Execute(res, struct {
+		Webhooks    []Data
+		CSRFToken   string
+		LoggedIn    bool
+		UserIsAdmin bool
+		Successes   []string
+	}{data, csrfToken.Token(), true, true, h.Successes})
+}
@@ -0,0 +1,17 @@
+package webhooks
+
+import (
+	"../../config"
+
+	"github.com/gorilla/mux"
+
+	"database/sql"
+)
+
+// RegisterHandlers registers request handlers to a subrouter.
+func RegisterHandlers(r *mux.Router, cfg *config.Config, db *sql.DB) {
+	r.Handle("/list", NewListHandler(cfg, db)).Methods("GET")
+	r.Handle("/create", NewCreateHandler(cfg, db)).Methods("POST")
+	r.Handle("/delete", NewDeleteHandler(cfg, db)).Methods("POST")
+	r.Handle("/deliveries", NewDeliveriesPageHandler(cfg, db)).Methods("GET")
+}
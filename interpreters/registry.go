@@ -0,0 +1,206 @@
+package interpreters
+
+import (
+	"../config"
+
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// versionCheckTimeout is how long an interpreter has to print its version
+// when miru checks that it works.
+const versionCheckTimeout = 10 * time.Second
+
+// fetchName is the name reserved for monitors that miru runs itself without a
+// script, which no interpreter can be configured with.
+const fetchName string = "fetch"
+
+// validName matches the names and file extensions that interpreters can have.
+// Both end up in file names and form values, so they are kept simple.
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ErrUnknownInterpreter is returned when a script is uploaded for, or a
+// monitor is set to run with, an interpreter that is not configured.
+var ErrUnknownInterpreter = errors.New("unknown interpreter")
+
+// defaults are the interpreters that miru supported before they could be
+// configured, which are used if the configuration doesn't name any.
+var defaults = []config.Interpreter{
+	{Name: "python", Label: "Python", Path: "python", Extension: "py", VersionCheck: []string{"--version"}},
+	{Name: "ruby", Label: "Ruby", Path: "ruby", Extension: "rb", VersionCheck: []string{"--version"}},
+	{Name: "perl", Label: "Perl", Path: "perl", Extension: "pl", VersionCheck: []string{"--version"}},
+}
+
+// Interpreter is a program that monitor scripts can be run with.
+type Interpreter struct {
+	name         string
+	label        string
+	path         string
+	args         []string
+	extension    string
+	versionCheck []string
+}
+
+// Registry holds the interpreters that monitor scripts can be run with, in
+// the order that they were configured in.
+type Registry struct {
+	interpreters []Interpreter
+}
+
+// NewRegistry creates a Registry of the interpreters defined. Any definition
+// that is invalid, or uses a name that is already taken, is left out of the
+// registry and described by the error returned.
+func NewRegistry(definitions []config.Interpreter) (Registry, error) {
+	r := Registry{[]Interpreter{}}
+	problems := []string{}
+	for _, def := range definitions {
+		problem := ""
+		switch {
+		case !validName.MatchString(def.Name):
+			problem = "names must be lowercase letters, numbers, dashes and underscores"
+		case def.Name == fetchName:
+			problem = "the name is reserved for fetch monitors"
+		case r.has(def.Name):
+			problem = "the name is already used by another interpreter"
+		case def.Path == "":
+			problem = "no path to the interpreter was given"
+		case !validName.MatchString(def.Extension):
+			problem = "file extensions must be lowercase letters, numbers, dashes and underscores"
+		}
+		if problem != "" {
+			problems = append(problems, fmt.Sprintf("interpreter %q: %s", def.Name, problem))
+			continue
+		}
+		label := def.Label
+		if label == "" {
+			label = def.Name
+		}
+		r.interpreters = append(r.interpreters, Interpreter{
+			name:         def.Name,
+			label:        label,
+			path:         def.Path,
+			args:         append([]string{}, def.Args...),
+			extension:    def.Extension,
+			versionCheck: append([]string{}, def.VersionCheck...),
+		})
+	}
+	if len(problems) > 0 {
+		return r, errors.New(strings.Join(problems, "; "))
+	}
+	return r, nil
+}
+
+// Load creates a Registry of the interpreters in the application's
+// configuration, or of miru's default interpreters if none are configured.
+func Load(cfg *config.Config) (Registry, error) {
+	if len(cfg.Interpreters) == 0 {
+		return Default(), nil
+	}
+	return NewRegistry(cfg.Interpreters)
+}
+
+// Default creates a Registry of miru's default Python, Ruby and Perl
+// interpreters.
+func Default() Registry {
+	r, _ := NewRegistry(defaults)
+	return r
+}
+
+// Find looks up an interpreter by name.
+func (r Registry) Find(name string) (Interpreter, error) {
+	for _, interpreter := range r.interpreters {
+		if interpreter.name == name {
+			return interpreter, nil
+		}
+	}
+	return Interpreter{}, ErrUnknownInterpreter
+}
+
+// List gets every interpreter in the registry, in the order they were configured.
+func (r Registry) List() []Interpreter {
+	return append([]Interpreter{}, r.interpreters...)
+}
+
+// Verify checks that every interpreter in the registry can be found, and runs
+// its version check, if it has one, to make sure that it works. The version
+// each interpreter printed is returned, keyed by name, along with an error
+// describing every interpreter that could not be verified.
+func (r Registry) Verify() (map[string]string, error) {
+	versions := map[string]string{}
+	problems := []string{}
+	for _, interpreter := range r.interpreters {
+		version, err := interpreter.verify()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("interpreter %q: %v", interpreter.name, err))
+			continue
+		}
+		versions[interpreter.name] = version
+	}
+	if len(problems) > 0 {
+		return versions, errors.New(strings.Join(problems, "; "))
+	}
+	return versions, nil
+}
+
+// has determines whether the registry contains an interpreter with a name.
+func (r Registry) has(name string) bool {
+	_, err := r.Find(name)
+	return err == nil
+}
+
+// Name is a getter function for the name that monitors refer to the
+// interpreter by.
+func (i Interpreter) Name() string {
+	return i.name
+}
+
+// Label is a getter function for the name of the interpreter that is shown to
+// administrators.
+func (i Interpreter) Label() string {
+	return i.label
+}
+
+// Path is a getter function for the interpreter's executable.
+func (i Interpreter) Path() string {
+	return i.path
+}
+
+// Extension is a getter function for the file extension that scripts for the
+// interpreter are saved with.
+func (i Interpreter) Extension() string {
+	return i.extension
+}
+
+// Args produces the arguments to run the interpreter with to have it run a script.
+func (i Interpreter) Args(scriptPath string) []string {
+	return append(append([]string{}, i.args...), scriptPath)
+}
+
+// verify finds the interpreter's executable and runs its version check.
+func (i Interpreter) verify() (string, error) {
+	path, err := exec.LookPath(i.path)
+	if err != nil {
+		return "", err
+	}
+	if len(i.versionCheck) == 0 {
+		return "", nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), versionCheckTimeout)
+	defer cancel()
+	output := bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, path, i.versionCheck...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err = cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("version check failed: %v", err)
+	}
+	// Only the first line is kept, since some interpreters print a lot more.
+	return strings.SplitN(strings.TrimSpace(output.String()), "\n", 2)[0], nil
+}
@@ -0,0 +1,88 @@
+package interpreters
+
+import (
+	"../config"
+
+	"testing"
+)
+
+func TestNewRegistryLeavesOutInvalidInterpreters(t *testing.T) {
+	registry, err := NewRegistry([]config.Interpreter{
+		{Name: "node", Label: "JavaScript", Path: "node", Extension: "js"},
+		{Name: "fetch", Path: "curl", Extension: "sh"},
+		{Name: "node", Path: "nodejs", Extension: "js"},
+		{Name: "Bad Name", Path: "bash", Extension: "sh"},
+		{Name: "bash", Path: "", Extension: "sh"},
+		{Name: "jq", Path: "jq", Extension: "../jq"},
+		{Name: "python3", Path: "python3", Extension: "py"},
+	})
+	if err == nil {
+		t.Error("expected an error describing the invalid interpreters")
+	}
+	names := []string{}
+	for _, interpreter := range registry.List() {
+		names = append(names, interpreter.Name())
+	}
+	if len(names) != 2 || names[0] != "node" || names[1] != "python3" {
+		t.Errorf("expected only node and python3 to be registered, got %v", names)
+	}
+	node, findErr := registry.Find("node")
+	if findErr != nil || node.Path() != "node" || node.Label() != "JavaScript" {
+		t.Errorf("expected to find the first node interpreter, got %v %v", node, findErr)
+	}
+	python, _ := registry.Find("python3")
+	if python.Label() != "python3" {
+		t.Errorf("expected the label to default to the name, got %q", python.Label())
+	}
+}
+
+func TestFindUnknownInterpreter(t *testing.T) {
+	_, err := Default().Find("cobol")
+	if err != ErrUnknownInterpreter {
+		t.Errorf("expected ErrUnknownInterpreter, got %v", err)
+	}
+}
+
+func TestLoadUsesDefaultsWhenNoneConfigured(t *testing.T) {
+	registry, err := Load(&config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"python", "ruby", "perl"} {
+		if _, findErr := registry.Find(name); findErr != nil {
+			t.Errorf("expected the default %s interpreter to be registered", name)
+		}
+	}
+}
+
+func TestArgsEndWithScript(t *testing.T) {
+	registry, _ := NewRegistry([]config.Interpreter{
+		{Name: "jq", Path: "jq", Args: []string{"-f"}, Extension: "jq"},
+	})
+	jq, _ := registry.Find("jq")
+	args := jq.Args("script.jq")
+	if len(args) != 2 || args[0] != "-f" || args[1] != "script.jq" {
+		t.Errorf("expected [-f script.jq], got %v", args)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	registry, _ := NewRegistry([]config.Interpreter{
+		{Name: "sh", Path: "sh", Extension: "sh", VersionCheck: []string{"-c", "echo 1.0; echo more"}},
+		{Name: "missing", Path: "miru-no-such-interpreter", Extension: "x"},
+		{Name: "broken", Path: "sh", Extension: "sh", VersionCheck: []string{"-c", "exit 1"}},
+	})
+	versions, err := registry.Verify()
+	if err == nil {
+		t.Error("expected an error for the missing and broken interpreters")
+	}
+	if versions["sh"] != "1.0" {
+		t.Errorf("expected sh's version to be 1.0, got %q", versions["sh"])
+	}
+	if _, found := versions["missing"]; found {
+		t.Error("expected the missing interpreter not to be verified")
+	}
+	if _, found := versions["broken"]; found {
+		t.Error("expected the broken interpreter not to be verified")
+	}
+}
@@ -1,7 +1,7 @@
 (function () {
 
-const rejectButtons = $('a.submitbtn')
-rejectButtons.click(function () {
+const submitButtons = $('a.submitbtn')
+submitButtons.click(function () {
     const _this = $(this)
     _this.parent().submit()
 })
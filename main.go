@@ -3,6 +3,7 @@ package main
 import (
 	"./config"
 	"./handlers"
+	"./interpreters"
 	"./models"
 	"./tasks"
 
@@ -28,12 +29,44 @@ func main() {
 		panic(initErr)
 	}
 
+	// Refuse to start if monitor scripts can't be run in the sandbox.
+	sandboxErr := tasks.NewSandbox(&cfg).Check()
+	if sandboxErr != nil {
+		panic(sandboxErr)
+	}
+
+	// Create the key that monitor secrets are encrypted with, if there isn't one.
+	_, keyErr := models.LoadSecretsKey(cfg.SecretsKeyFile)
+	if keyErr != nil {
+		panic(keyErr)
+	}
+
+	// Make sure that every interpreter monitor scripts can be written for is
+	// installed. Miru won't start without the interpreters configured, but
+	// only warns about missing default interpreters, which were always
+	// optional.
+	registry, registryErr := interpreters.Load(&cfg)
+	if registryErr != nil {
+		panic(registryErr)
+	}
+	versions, verifyErr := registry.Verify()
+	for _, interpreter := range registry.List() {
+		if version, found := versions[interpreter.Name()]; found {
+			fmt.Println("Found interpreter", interpreter.Name(), version)
+		}
+	}
+	if verifyErr != nil && len(cfg.Interpreters) > 0 {
+		panic(verifyErr)
+	} else if verifyErr != nil {
+		fmt.Println("[---] Warning: ", verifyErr.Error())
+	}
+
 	// Start the task runner so that it will periodically run a monitor script
 	// to check for changes to sites, and shut everything down if a terminate
 	// signal is sent by the user.
 	errors := make(chan error)
 	terminate := make(chan bool, 2)
-	go tasks.RunMonitors(db, 1*time.Second, errors, terminate)
+	go tasks.RunMonitors(db, &cfg, 1*time.Second, errors, terminate)
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, os.Kill)
 	go func() {
@@ -0,0 +1,199 @@
+package models
+
+import (
+	"../auth"
+
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiTokenSecretLength is the number of random bytes to generate for the
+// secret part of new API tokens.
+const apiTokenSecretLength uint = 32
+
+// APITokenScope is a pseudo-enum describing what an API token can be used for.
+type APITokenScope string
+
+const (
+	// ReadOnlyScope allows a token to be used to read data but not change it.
+	ReadOnlyScope APITokenScope = "read_only"
+
+	// AdminScope allows a token to be used to do anything its owner can do.
+	// Only administrators can create admin tokens.
+	AdminScope APITokenScope = "admin"
+)
+
+var errInvalidAPIToken = errors.New("invalid api token")
+
+// APIToken is a credential that an archiver can give to a program so that it
+// can use the API on their behalf without logging in. Tokens are written as
+// the token's ID and a secret separated by a dot. Like a password, only a hash
+// of the secret is stored, so a token can't be recovered after it's created.
+type APIToken struct {
+	id         int
+	owner      int
+	name       string
+	scope      string
+	secretHash string
+	createdAt  time.Time
+	lastUsedAt time.Time
+	revoked    bool
+	token      string
+}
+
+// NewAPIToken is the constructor function for a new API token. The token's
+// secret is generated when it is saved.
+func NewAPIToken(owner Archiver, name string, scope APITokenScope) APIToken {
+	return APIToken{
+		id:         -1,
+		owner:      owner.ID(),
+		name:       name,
+		scope:      string(scope),
+		secretHash: "",
+		createdAt:  time.Now(),
+		lastUsedAt: time.Time{},
+		revoked:    false,
+		token:      "",
+	}
+}
+
+// ListAPITokensForOwner obtains all of the API tokens that belong to an
+// archiver, including revoked ones.
+func ListAPITokensForOwner(db *sql.DB, owner Archiver) ([]APIToken, error) {
+	tokens := []APIToken{}
+	rows, err := db.Query(QListAPITokensForOwner, owner.ID())
+	if err != nil {
+		return tokens, err
+	}
+	for rows.Next() {
+		t := APIToken{}
+		err = rows.Scan(
+			&t.id, &t.name, &t.scope, &t.secretHash, &t.createdAt, &t.lastUsedAt, &t.revoked)
+		if err != nil {
+			break
+		}
+		t.owner = owner.ID()
+		tokens = append(tokens, t)
+	}
+	return tokens, err
+}
+
+// FindAPIToken attempts to find an API token given its ID.
+func FindAPIToken(db *sql.DB, id int) (APIToken, error) {
+	t := APIToken{}
+	err := db.QueryRow(QFindAPIToken, id).Scan(
+		&t.owner, &t.name, &t.scope, &t.secretHash, &t.createdAt, &t.lastUsedAt, &t.revoked)
+	if err != nil {
+		return APIToken{}, err
+	}
+	t.id = id
+	return t, nil
+}
+
+// ID is a getter function for the token's unique identifier.
+func (t APIToken) ID() int {
+	return t.id
+}
+
+// Owner is a getter function for the ID of the archiver that owns the token.
+func (t APIToken) Owner() int {
+	return t.owner
+}
+
+// Name is a getter function for the name given to the token by its owner to
+// remember what it is used for.
+func (t APIToken) Name() string {
+	return t.name
+}
+
+// Scope is a getter function that converts the token's scope back into an
+// APITokenScope type.
+func (t APIToken) Scope() APITokenScope {
+	return APITokenScope(t.scope)
+}
+
+// CreatedAt is a getter function for the time that the token was created.
+func (t APIToken) CreatedAt() time.Time {
+	return t.createdAt
+}
+
+// LastUsedAt is a getter function for the time that the token was last used,
+// which is the zero time if it has never been used.
+func (t APIToken) LastUsedAt() time.Time {
+	return t.lastUsedAt
+}
+
+// IsRevoked determines whether the token has been revoked and can no longer be used.
+func (t APIToken) IsRevoked() bool {
+	return t.revoked
+}
+
+// Token is a getter function for the token to give to a program. It is only
+// available immediately after the token is saved.
+func (t APIToken) Token() string {
+	return t.token
+}
+
+// Save generates a secret for a new token, and then stores the token with
+// only a hash of the secret.
+func (t *APIToken) Save(db *sql.DB) error {
+	secret, genErr := auth.GenerateUniqueSessionToken(
+		apiTokenSecretLength,
+		func(string) bool { return false })
+	if genErr != nil {
+		return genErr
+	}
+	t.secretHash = auth.SecurePassword(secret)
+	_, err := db.Exec(QSaveAPIToken,
+		t.owner, t.name, t.scope, t.secretHash, t.createdAt, t.lastUsedAt, t.revoked)
+	if err != nil {
+		return err
+	}
+	err = db.QueryRow(QLastRowID).Scan(&t.id)
+	if err != nil {
+		return err
+	}
+	t.token = fmt.Sprintf("%d.%s", t.id, secret)
+	return nil
+}
+
+// Revoke stops a token from being usable.
+func (t *APIToken) Revoke(db *sql.DB) error {
+	t.revoked = true
+	return t.Update(db)
+}
+
+// Update saves changes to the time the token was last used and whether it
+// has been revoked. A token that has been revoked cannot be restored.
+func (t *APIToken) Update(db *sql.DB) error {
+	_, err := db.Exec(QUpdateAPIToken, t.lastUsedAt, t.revoked, t.id)
+	return err
+}
+
+// Delete always returns an error so that there is a record of every token
+// that was created. Tokens should be revoked instead.
+func (t *APIToken) Delete(db *sql.DB) error {
+	return errors.New("cannot delete an api token; revoke it instead")
+}
+
+// verifyAPIToken finds the token that a string written in the form that
+// tokens are given to programs refers to, and checks that it is usable.
+func verifyAPIToken(db *sql.DB, token string) (APIToken, error) {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return APIToken{}, errInvalidAPIToken
+	}
+	id, parseErr := strconv.Atoi(parts[0])
+	if parseErr != nil {
+		return APIToken{}, errInvalidAPIToken
+	}
+	t, findErr := FindAPIToken(db, id)
+	if findErr != nil || t.revoked || !auth.IsPasswordCorrect(parts[1], t.secretHash) {
+		return APIToken{}, errInvalidAPIToken
+	}
+	return t, nil
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+)
+
+// saveTestToken saves an archiver with an email address, and an API token
+// belonging to them.
+func saveTestToken(t *testing.T, db *sql.DB, email string, scope APITokenScope) (Archiver, APIToken) {
+	owner := NewArchiver(email, "hash")
+	if err := owner.Save(db); err != nil {
+		t.Fatal(err)
+	}
+	token := NewAPIToken(owner, "test", scope)
+	if err := token.Save(db); err != nil {
+		t.Fatal(err)
+	}
+	return owner, token
+}
+
+func TestFindAPITokenOwner(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	owner, token := saveTestToken(t, db, "owner@example.com", ReadOnlyScope)
+	expectedPrefix := fmt.Sprintf("%d.", token.ID())
+	if len(token.Token()) <= len(expectedPrefix) || token.Token()[:len(expectedPrefix)] != expectedPrefix {
+		t.Fatalf("expected the token to be its ID and secret separated by a dot, got %q", token.Token())
+	}
+	found, scope, err := FindAPITokenOwner(db, token.Token())
+	if err != nil || found.ID() != owner.ID() || scope != ReadOnlyScope {
+		t.Errorf("expected to find the token's owner and scope, got %d %s %v", found.ID(), scope, err)
+	}
+	used, _ := FindAPIToken(db, token.ID())
+	if used.LastUsedAt().IsZero() {
+		t.Errorf("expected using the token to record when it was last used")
+	}
+}
+
+func TestFindAPITokenOwnerRejectsInvalidTokens(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	_, token := saveTestToken(t, db, "owner@example.com", AdminScope)
+	_, other := saveTestToken(t, db, "other@example.com", AdminScope)
+	otherSecret := other.Token()[len(fmt.Sprintf("%d.", other.ID())):]
+	invalid := []string{
+		"",
+		fmt.Sprintf("%d", token.ID()),
+		"not-an-id." + otherSecret,
+		fmt.Sprintf("%d.", token.ID()),
+		fmt.Sprintf("%d.wrong-secret", token.ID()),
+		fmt.Sprintf("%d.%s", token.ID(), otherSecret),
+		fmt.Sprintf("%d.%s", other.ID()+100, otherSecret),
+	}
+	for _, written := range invalid {
+		if _, _, err := FindAPITokenOwner(db, written); err == nil {
+			t.Errorf("expected %q not to be accepted", written)
+		}
+	}
+}
+
+func TestRevokedAPITokensAreRejected(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	_, token := saveTestToken(t, db, "owner@example.com", ReadOnlyScope)
+	if err := token.Revoke(db); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := FindAPITokenOwner(db, token.Token()); err == nil {
+		t.Errorf("expected a revoked token not to be accepted")
+	}
+	saved, err := FindAPIToken(db, token.ID())
+	if err != nil || !saved.IsRevoked() {
+		t.Errorf("expected the token to be saved as revoked, got %v", err)
+	}
+}
@@ -75,6 +75,23 @@ func FindSessionOwner(db *sql.DB, sessionToken string) (Archiver, error) {
 	return FindArchiver(db, s.Owner())
 }
 
+// FindAPITokenOwner attempts to find the archiver that owns an API token, as
+// written by the program using it, along with the scope of the token. Using a
+// token records the time it was last used.
+func FindAPITokenOwner(db *sql.DB, token string) (Archiver, APITokenScope, error) {
+	t, err := verifyAPIToken(db, token)
+	if err != nil {
+		return Archiver{}, ReadOnlyScope, err
+	}
+	t.lastUsedAt = time.Now()
+	err = t.Update(db)
+	if err != nil {
+		return Archiver{}, ReadOnlyScope, err
+	}
+	a, err := FindArchiver(db, t.Owner())
+	return a, t.Scope(), err
+}
+
 // FindArchiverByEmail attempts to find an Archiver in the database who has
 // registered with the provided email address.
 func FindArchiverByEmail(db *sql.DB, email string) (Archiver, error) {
@@ -0,0 +1,230 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MaxImportRows is the largest number of requests that can be imported at once.
+const MaxImportRows int = 1000
+
+// Formats that requests can be imported from.
+const (
+	ImportCSV  = "csv"
+	ImportJSON = "json"
+)
+
+// ErrInvalidImport is returned when requests can't be imported because some of
+// the rows being imported have problems.
+var ErrInvalidImport = errors.New("some rows could not be imported, nothing was saved")
+
+// ImportRow is a request to have a site monitored read from a bulk import,
+// along with the problem that prevents it from being imported, if any. Rows are
+// numbered from 1, not counting a CSV header.
+type ImportRow struct {
+	Row          int
+	URL          string
+	Instructions string
+	Err          error
+}
+
+// importJSON is the JSON representation of a row being imported.
+type importJSON struct {
+	URL          string `json:"url"`
+	Instructions string `json:"instructions"`
+}
+
+// DecodeImport reads the rows of a bulk import in either the CSV or JSON format,
+// normalizing each URL and recording any problems with the row in its Err field.
+// An error is only returned if the import can't be read at all.
+func DecodeImport(format string, r io.Reader) ([]ImportRow, error) {
+	var rows []ImportRow
+	var err error
+	switch format {
+	case ImportCSV:
+		rows, err = decodeImportCSV(r)
+	case ImportJSON:
+		rows, err = decodeImportJSON(r)
+	default:
+		return []ImportRow{}, fmt.Errorf("unsupported import format %q", format)
+	}
+	if err != nil {
+		return []ImportRow{}, err
+	}
+	if len(rows) == 0 {
+		return []ImportRow{}, errors.New("there are no requests to import")
+	}
+	if len(rows) > MaxImportRows {
+		return []ImportRow{}, fmt.Errorf("at most %d requests can be imported at once", MaxImportRows)
+	}
+	validateImportRows(rows)
+	return rows, nil
+}
+
+// decodeImportCSV reads rows from a CSV file whose first column is the URL to
+// monitor and second column, if present, is the instructions. If the first
+// record has a column named "url", it is treated as a header naming the url
+// and instructions columns instead.
+func decodeImportCSV(r io.Reader) ([]ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return []ImportRow{}, err
+	}
+	urlColumn, instructionsColumn := 0, 1
+	if len(records) > 0 && hasColumn(records[0], "url") {
+		urlColumn, instructionsColumn = -1, -1
+		for i, name := range records[0] {
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "url":
+				urlColumn = i
+			case "instructions":
+				instructionsColumn = i
+			}
+		}
+		records = records[1:]
+	}
+	rows := []ImportRow{}
+	for i, record := range records {
+		row := ImportRow{Row: i + 1}
+		if urlColumn < len(record) {
+			row.URL = record[urlColumn]
+		}
+		if instructionsColumn >= 0 && instructionsColumn < len(record) {
+			row.Instructions = record[instructionsColumn]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// hasColumn determines whether a CSV record contains a column name.
+func hasColumn(record []string, name string) bool {
+	for _, column := range record {
+		if strings.ToLower(strings.TrimSpace(column)) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeImportJSON reads rows from a JSON array of objects containing url and
+// instructions fields.
+func decodeImportJSON(r io.Reader) ([]ImportRow, error) {
+	decoded := []importJSON{}
+	err := json.NewDecoder(r).Decode(&decoded)
+	if err != nil {
+		return []ImportRow{}, err
+	}
+	rows := []ImportRow{}
+	for i, row := range decoded {
+		rows = append(rows, ImportRow{
+			Row:          i + 1,
+			URL:          row.URL,
+			Instructions: row.Instructions,
+		})
+	}
+	return rows, nil
+}
+
+// validateImportRows cleans the URL of each row the same way as for a request
+// made through the site, and checks that no URL appears twice in an import.
+func validateImportRows(rows []ImportRow) {
+	seen := map[string]int{}
+	for i := range rows {
+		rows[i].Instructions = strings.TrimSpace(rows[i].Instructions)
+		if strings.TrimSpace(rows[i].URL) == "" {
+			rows[i].Err = errors.New("missing url")
+			continue
+		}
+		cleanURL, err := CleanURL(rows[i].URL)
+		if err != nil {
+			rows[i].Err = fmt.Errorf("invalid url: %v", err)
+			continue
+		}
+		rows[i].URL = cleanURL
+		if first, found := seen[cleanURL]; found {
+			rows[i].Err = fmt.Errorf("duplicate of row %d", first)
+			continue
+		}
+		seen[cleanURL] = rows[i].Row
+	}
+}
+
+// ImportRequests creates a request for each row of a bulk import, made by an
+// administrator, in a single transaction. Rows whose URL was already requested
+// and is still open or being monitored are marked with an error, and nothing is
+// saved if any row has an error, in which case ErrInvalidImport is returned.
+func ImportRequests(db *sql.DB, creator Archiver, rows []ImportRow) ([]Request, error) {
+	if !creator.IsAdmin() {
+		return []Request{}, errors.New("only administrators can import requests")
+	}
+	existing, err := ListActiveRequests(db)
+	if err != nil {
+		return []Request{}, err
+	}
+	valid := true
+	for i := range rows {
+		if rows[i].Err == nil {
+			for _, request := range existing {
+				if CompareURLs(rows[i].URL, request.URL()) == DuplicateURL {
+					rows[i].Err = fmt.Errorf("already requested as request #%d", request.ID())
+					break
+				}
+			}
+		}
+		valid = valid && rows[i].Err == nil
+	}
+	if !valid {
+		return []Request{}, ErrInvalidImport
+	}
+	requests := []Request{}
+	for _, row := range rows {
+		requests = append(requests, NewRequest(creator, row.URL, row.Instructions))
+	}
+	err = SaveRequests(db, requests)
+	if err != nil {
+		return []Request{}, err
+	}
+	return requests, nil
+}
+
+// SaveRequests inserts several new requests in a single transaction, so that
+// either all of them are saved or none are.
+func SaveRequests(db *sql.DB, requests []Request) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for i := range requests {
+		r := &requests[i]
+		_, err = tx.Exec(QSaveRequest, r.createdBy, r.createdAt, r.url, r.instructions)
+		if err == nil {
+			err = tx.QueryRow(QLastRowID).Scan(&r.id)
+		}
+		if err != nil {
+			tx.Rollback()
+			resetRequestIDs(requests)
+			return err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		resetRequestIDs(requests)
+	}
+	return err
+}
+
+// resetRequestIDs marks requests as unsaved after a transaction saving them fails.
+func resetRequestIDs(requests []Request) {
+	for i := range requests {
+		requests[i].id = -1
+	}
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeImportCSV(t *testing.T) {
+	input := "Instructions,URL\n" +
+		"\"Check the \"\"Data\"\" tab\", HTTPS://EPA.gov/climate/\n" +
+		"Track the front page,noaa.gov/?utm=x\n" +
+		"Same as row 1,https://epa.gov/climate#top\n" +
+		"No address,\n" +
+		"Not a website,ftp://epa.gov/file\n"
+	rows, err := DecodeImport(ImportCSV, strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 5 {
+		t.Fatalf("expected 5 rows, got %d", len(rows))
+	}
+	if rows[0].Row != 1 || rows[0].URL != "https://epa.gov/climate" || rows[0].Instructions != "Check the \"Data\" tab" || rows[0].Err != nil {
+		t.Errorf("unexpected first row %+v", rows[0])
+	}
+	if rows[1].URL != "http://noaa.gov/" || rows[1].Err != nil {
+		t.Errorf("expected the url to be cleaned, got %+v", rows[1])
+	}
+	if rows[2].Err == nil || !strings.Contains(rows[2].Err.Error(), "row 1") {
+		t.Errorf("expected the third row to be a duplicate of the first, got %v", rows[2].Err)
+	}
+	for _, row := range rows[3:] {
+		if row.Err == nil {
+			t.Errorf("expected row %d to be invalid", row.Row)
+		}
+	}
+}
+
+func TestDecodeImportCSVWithoutHeader(t *testing.T) {
+	rows, err := DecodeImport(ImportCSV, strings.NewReader("https://epa.gov/a,first\nhttps://epa.gov/b\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Instructions != "first" || rows[1].URL != "https://epa.gov/b" {
+		t.Errorf("unexpected rows %+v", rows)
+	}
+}
+
+func TestDecodeImportJSON(t *testing.T) {
+	input := `[
+		{"url": "https://epa.gov/climate", "instructions": "Watch the data tab"},
+		{"url": "not a url"}
+	]`
+	rows, err := DecodeImport(ImportJSON, strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Err != nil || rows[0].Instructions != "Watch the data tab" {
+		t.Errorf("unexpected rows %+v", rows)
+	}
+	if rows[1].Err == nil {
+		t.Errorf("expected the second row to be invalid")
+	}
+}
+
+func TestDecodeImportRejectsUnreadableInput(t *testing.T) {
+	inputs := map[string]string{
+		ImportJSON: `{"url": "https://epa.gov"}`,
+		ImportCSV:  "",
+		"xml":      "<url>https://epa.gov</url>",
+	}
+	for format, input := range inputs {
+		if _, err := DecodeImport(format, strings.NewReader(input)); err == nil {
+			t.Errorf("expected %s input %q to be rejected", format, input)
+		}
+	}
+	tooMany := strings.Repeat("https://epa.gov\n", MaxImportRows+1)
+	if _, err := DecodeImport(ImportCSV, strings.NewReader(tooMany)); err == nil {
+		t.Errorf("expected an import of more than %d rows to be rejected", MaxImportRows)
+	}
+}
@@ -2,8 +2,13 @@ package models
 
 import (
 	"database/sql"
+	"strings"
 )
 
+// errDuplicateColumn is the start of the error message SQLite produces when a
+// migration tries to add a column that already exists.
+const errDuplicateColumn = "duplicate column name"
+
 // Model is an interface for all model types. We expect at least the following
 // basic database manipulations to be implemented for each model.
 type Model interface {
@@ -34,6 +39,42 @@ func InitializeTables(db *sql.DB) error {
 	if err != nil {
 		return err
 	}
+	_, err = db.Exec(QInitRunsTable)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(QInitScriptVersionsTable)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(QInitMonitorSecretsTable)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(QInitSnapshotsTable)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(QInitAPITokensTable)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(QInitWebhooksTable)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(QInitWebhookDeliveriesTable)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(QInitRequestTransitionsTable)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(QInitReportSubscriptionsTable)
+	if err != nil {
+		return err
+	}
 	_, err = db.Exec(QInitLoginAttemptsTable)
 	if err != nil {
 		return err
@@ -42,5 +83,17 @@ func InitializeTables(db *sql.DB) error {
 	if err != nil {
 		return err
 	}
+	return migrateTables(db)
+}
+
+// migrateTables adds any columns that are missing from tables created by older
+// versions of miru.
+func migrateTables(db *sql.DB) error {
+	for _, migration := range QMigrations {
+		_, err := db.Exec(migration)
+		if err != nil && !strings.HasPrefix(err.Error(), errDuplicateColumn) {
+			return err
+		}
+	}
 	return nil
 }
@@ -2,25 +2,49 @@ package models
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"math"
 	"time"
 )
 
-// Interpreter is a pseudo-enum covering the script interpreters supported.
+// Interpreter is the name of the interpreter that a monitor's script is run
+// with, which is one of those configured for miru, or FetchInterpreter.
 type Interpreter string
 
-const (
-	// PythonInterpreter identifies a python script
-	PythonInterpreter Interpreter = "python"
+// FetchInterpreter identifies a monitor that has no script, which miru runs
+// itself by fetching the requested URL and checking if its checksum changed.
+const FetchInterpreter Interpreter = "fetch"
 
-	// RubyInterpreter identifies a ruby script
-	RubyInterpreter Interpreter = "ruby"
+// MonitorStatus is a pseudo-enum covering whether a monitor is being run.
+type MonitorStatus string
 
-	// PerlInterpreter identifies a perl script
-	PerlInterpreter Interpreter = "perl"
+// Active monitors are run on their schedule. Paused monitors are skipped until
+// they are resumed, and retired monitors are never run again, but are kept so
+// that the history of their reports can still be read.
+const (
+	ActiveMonitor  MonitorStatus = "active"
+	PausedMonitor  MonitorStatus = "paused"
+	RetiredMonitor MonitorStatus = "retired"
 )
 
+// ErrMonitorRetired is returned when trying to change a retired monitor.
+var ErrMonitorRetired = errors.New("retired monitors cannot be changed")
+
+// String returns a human-readable name for the status.
+func (s MonitorStatus) String() string {
+	switch s {
+	case ActiveMonitor:
+		return "Active"
+	case PausedMonitor:
+		return "Paused"
+	case RetiredMonitor:
+		return "Retired"
+	default:
+		return "Unknown"
+	}
+}
+
 // Monitor is the model for "rules" that specify a script to run in order to
 // check a website for changes.
 type Monitor struct {
@@ -33,6 +57,8 @@ type Monitor struct {
 	lastRan     time.Time
 	waitPeriod  uint
 	timeToRun   uint
+	status      MonitorStatus
+	version     int
 }
 
 // NewMonitor is the constructor for the monitor type. When a new script is
@@ -57,6 +83,7 @@ func NewMonitor(
 		lastRan:     time.Now().Add(-1 * waitBetweenRuns),
 		waitPeriod:  waitMinutes,
 		timeToRun:   runTimeSeconds,
+		status:      ActiveMonitor,
 	}
 }
 
@@ -71,7 +98,7 @@ func ListMonitors(db *sql.DB) ([]Monitor, error) {
 		m := Monitor{}
 		err = rows.Scan(
 			&m.id, &m.interpreter, &m.scriptPath, &m.createdFor, &m.createdBy,
-			&m.createdAt, &m.lastRan, &m.waitPeriod, &m.timeToRun)
+			&m.createdAt, &m.lastRan, &m.waitPeriod, &m.timeToRun, &m.status, &m.version)
 		if err != nil {
 			break
 		}
@@ -80,6 +107,33 @@ func ListMonitors(db *sql.DB) ([]Monitor, error) {
 	return allMonitors, err
 }
 
+// FindMonitor attempts to find a monitor given its ID.
+func FindMonitor(db *sql.DB, id int) (Monitor, error) {
+	m := Monitor{}
+	err := db.QueryRow(QFindMonitor, id).Scan(
+		&m.interpreter, &m.scriptPath, &m.createdFor, &m.createdBy,
+		&m.createdAt, &m.lastRan, &m.waitPeriod, &m.timeToRun, &m.status, &m.version)
+	if err != nil {
+		return Monitor{}, err
+	}
+	m.id = id
+	return m, nil
+}
+
+// FindMonitorForRequest attempts to find the monitor most recently created to
+// fulfill a request.
+func FindMonitorForRequest(db *sql.DB, request Request) (Monitor, error) {
+	m := Monitor{}
+	err := db.QueryRow(QFindMonitorForRequest, request.ID()).Scan(
+		&m.id, &m.interpreter, &m.scriptPath, &m.createdBy,
+		&m.createdAt, &m.lastRan, &m.waitPeriod, &m.timeToRun, &m.status, &m.version)
+	if err != nil {
+		return Monitor{}, err
+	}
+	m.createdFor = request.ID()
+	return m, nil
+}
+
 // FindReadyMonitors finds monitors that we've waited long enough to run again.
 // The function will return the first error it encounters, along with any
 // monitors retrieved until that point.
@@ -94,7 +148,7 @@ func FindReadyMonitors(db *sql.DB, limit uint) ([]Monitor, error) {
 		var m Monitor
 		err = rows.Scan(
 			&m.id, &m.interpreter, &m.scriptPath, &m.createdFor, &m.createdBy,
-			&m.createdAt, &m.lastRan, &m.waitPeriod, &m.timeToRun)
+			&m.createdAt, &m.lastRan, &m.waitPeriod, &m.timeToRun, &m.status, &m.version)
 		if err != nil {
 			break
 		}
@@ -128,6 +182,41 @@ func (m Monitor) ID() int {
 	return m.id
 }
 
+// ExpectedRunTime gets the amount of time that the monitor's script is
+// expected to take to run.
+func (m Monitor) ExpectedRunTime() time.Duration {
+	return time.Duration(m.timeToRun) * time.Second
+}
+
+// WaitPeriod computes the amount of time to wait between runs of the monitor.
+func (m Monitor) WaitPeriod() time.Duration {
+	return time.Duration(m.waitPeriod) * time.Minute
+}
+
+// CreatedAt is a getter function for the time that the monitor was created.
+func (m Monitor) CreatedAt() time.Time {
+	return m.createdAt
+}
+
+// Status is a getter function for whether the monitor is active, paused or retired.
+func (m Monitor) Status() MonitorStatus {
+	return m.status
+}
+
+// ScriptVersion is a getter function for the number of the version of its
+// script that the monitor runs.
+func (m Monitor) ScriptVersion() int {
+	return m.version
+}
+
+// SetSchedule changes the amount of time to wait between runs of the monitor
+// and the amount of time to allow it to run for. UpdateSchedule must be called
+// to save the changes.
+func (m *Monitor) SetSchedule(waitBetweenRuns, expectedRuntime time.Duration) {
+	m.waitPeriod = uint(math.Ceil(waitBetweenRuns.Minutes()))
+	m.timeToRun = uint(math.Ceil(expectedRuntime.Seconds()))
+}
+
 // SetLastRun sets the monitor's last run time to now.
 func (m *Monitor) SetLastRun() {
 	m.lastRan = time.Now()
@@ -138,6 +227,76 @@ func (m Monitor) LastRun() time.Time {
 	return m.lastRan
 }
 
+// UpdateLastRun saves the monitor's last run time without changing anything
+// else, so that changes made to its schedule while it runs aren't undone.
+func (m *Monitor) UpdateLastRun(db *sql.DB) error {
+	_, err := db.Exec(QUpdateMonitorLastRun, m.lastRan, m.id)
+	return err
+}
+
+// UpdateSchedule saves the monitor's schedule without changing anything else,
+// so that the time it last ran isn't undone if it ran while the schedule was
+// being changed. Retired monitors cannot be updated.
+func (m *Monitor) UpdateSchedule(db *sql.DB) error {
+	if m.status == RetiredMonitor {
+		return ErrMonitorRetired
+	}
+	result, err := db.Exec(QUpdateMonitorSchedule, m.waitPeriod, m.timeToRun, m.id)
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated != 1 {
+		return ErrMonitorRetired
+	}
+	return nil
+}
+
+// Pause stops an active monitor from being run until it is resumed.
+func (m *Monitor) Pause(db *sql.DB) error {
+	return m.setStatus(db, ActiveMonitor, PausedMonitor)
+}
+
+// Resume has a paused monitor run on its schedule again.
+func (m *Monitor) Resume(db *sql.DB) error {
+	return m.setStatus(db, PausedMonitor, ActiveMonitor)
+}
+
+// Retire stops a monitor from ever being run again. Its reports are kept.
+func (m *Monitor) Retire(db *sql.DB) error {
+	if m.status == RetiredMonitor {
+		return ErrMonitorRetired
+	}
+	return m.setStatus(db, m.status, RetiredMonitor)
+}
+
+// setStatus changes the monitor's status, as long as it has the expected status
+// both here and in the database.
+func (m *Monitor) setStatus(db *sql.DB, from, to MonitorStatus) error {
+	if m.status == RetiredMonitor {
+		return ErrMonitorRetired
+	}
+	if m.status != from {
+		return fmt.Errorf("cannot change a monitor that is %s to %s", m.status, to)
+	}
+	result, err := db.Exec(QSetMonitorStatus, to, m.id, from)
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated != 1 {
+		return errors.New("the monitor was changed by someone else, please try again")
+	}
+	m.status = to
+	return nil
+}
+
 // Save inserts a new monitor into the database and updates the id field.
 // WARNING: Save should *not* be called more than once on a model.
 func (m *Monitor) Save(db *sql.DB) error {
@@ -152,16 +311,31 @@ func (m *Monitor) Save(db *sql.DB) error {
 	return err
 }
 
+// insert saves a new monitor as part of a transaction and updates the id field.
+func (m *Monitor) insert(tx *sql.Tx) error {
+	_, err := tx.Exec(QSaveMonitor,
+		m.interpreter, m.scriptPath, m.createdFor, m.createdBy, m.createdAt,
+		m.lastRan, m.waitPeriod, m.timeToRun)
+	if err != nil {
+		return err
+	}
+	return tx.QueryRow(QLastRowID).Scan(&m.id)
+}
+
 // Update modifies the monitor's database row to set the time the monitor was
 // last run, the time we want to wait between running it, and the amount of
-// time to allow the monitor to run for.
+// time to allow the monitor to run for. Retired monitors cannot be updated.
 func (m *Monitor) Update(db *sql.DB) error {
+	if m.status == RetiredMonitor {
+		return ErrMonitorRetired
+	}
 	_, err := db.Exec(QUpdateMonitor,
 		m.lastRan, m.waitPeriod, m.timeToRun, m.id)
 	return err
 }
 
-// Delete removes the monitor from the database.
+// Delete removes the monitor from the database. Monitors should usually be
+// retired instead, which keeps the history of their reports.
 func (m *Monitor) Delete(db *sql.DB) error {
 	_, err := db.Exec(QDeleteMonitor, m.id)
 	return err
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMonitorStatusChangesRequireExpectedStatus(t *testing.T) {
+	active := Monitor{id: 1, status: ActiveMonitor}
+	if err := active.Resume(nil); err == nil {
+		t.Errorf("expected an active monitor not to be resumed")
+	}
+	paused := Monitor{id: 1, status: PausedMonitor}
+	if err := paused.Pause(nil); err == nil {
+		t.Errorf("expected a paused monitor not to be paused again")
+	}
+	retired := Monitor{id: 1, status: RetiredMonitor}
+	changes := map[string]func() error{
+		"paused":      func() error { return retired.Pause(nil) },
+		"resumed":     func() error { return retired.Resume(nil) },
+		"retired":     func() error { return retired.Retire(nil) },
+		"updated":     func() error { return retired.Update(nil) },
+		"rescheduled": func() error { return retired.UpdateSchedule(nil) },
+		"replaced": func() error {
+			_, err := retired.AddScriptVersion(nil, Archiver{}, FetchInterpreter, "", "", "")
+			return err
+		},
+		"rolled back": func() error { return retired.RollBack(nil, 1) },
+	}
+	for name, change := range changes {
+		if err := change(); err != ErrMonitorRetired {
+			t.Errorf("expected a retired monitor not to be %s, got %v", name, err)
+		}
+	}
+}
+
+func TestSetSchedule(t *testing.T) {
+	monitor := Monitor{}
+	monitor.SetSchedule(90*time.Second, 1500*time.Millisecond)
+	if monitor.WaitPeriod() != 2*time.Minute || monitor.ExpectedRunTime() != 2*time.Second {
+		t.Errorf("expected the schedule to be rounded up, got %v and %v",
+			monitor.WaitPeriod(), monitor.ExpectedRunTime())
+	}
+}
+
+func TestUpdateScheduleKeepsLastRun(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	monitor := NewMonitor(Archiver{id: 1}, Request{id: 1}, Interpreter("python"), "script.py", time.Hour, time.Minute)
+	if err := monitor.Save(db); err != nil {
+		t.Fatal(err)
+	}
+	// The monitor runs while an administrator is changing its schedule.
+	running := monitor
+	running.SetLastRun()
+	if err := running.UpdateLastRun(db); err != nil {
+		t.Fatal(err)
+	}
+	monitor.SetSchedule(2*time.Hour, 5*time.Minute)
+	if err := monitor.UpdateSchedule(db); err != nil {
+		t.Fatalf("expected to update the schedule, got %v", err)
+	}
+	saved, err := FindMonitor(db, monitor.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.WaitPeriod() != 2*time.Hour || saved.ExpectedRunTime() != 5*time.Minute {
+		t.Errorf("expected the new schedule to be saved, got %v and %v", saved.WaitPeriod(), saved.ExpectedRunTime())
+	}
+	if !saved.LastRun().Equal(running.LastRun()) {
+		t.Errorf("expected the last run at %v to be kept, got %v", running.LastRun(), saved.LastRun())
+	}
+}
@@ -0,0 +1,215 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+// ReportProtocolVersion is the latest version of the report protocol that
+// monitor scripts use to communicate with miru. Scripts that do not specify
+// a version are assumed to speak version 1.
+// Version 2 added the content field, which lets a script leave scoring the
+// change significance to miru.
+const ReportProtocolVersion uint = 2
+
+// maxChecksumLength is the longest checksum that can be stored for a report.
+const maxChecksumLength int = 128
+
+// Names of the fields in the report protocol. The change significance was
+// documented as lastChangeSignificance but has also been written as
+// changeSignificance, so both names are accepted for now.
+const (
+	fieldVersion      = "version"
+	fieldChange       = "lastChangeSignificance"
+	fieldChangeLegacy = "changeSignificance"
+	fieldMessage      = "message"
+	fieldChecksum     = "checksum"
+	fieldState        = "state"
+	fieldContent      = "content"
+)
+
+// maxValidImportance is the highest level of Importance a report can have.
+const maxValidImportance Importance = Deleted
+
+// ValidationError describes a problem with a report output by a monitor script
+// that prevented it from being accepted.
+type ValidationError struct {
+	Field   string
+	Problem string
+}
+
+// Error produces a message describing which field of a report is invalid and why.
+func (e ValidationError) Error() string {
+	if e.Field == "" {
+		return "invalid report: " + e.Problem
+	}
+	return fmt.Sprintf("invalid report field %q: %s", e.Field, e.Problem)
+}
+
+// ReportOutput is the validated content of a report written by a monitor script.
+// When a script provides the text content of the page it checked instead of a
+// change significance, ChangeOmitted is true and the change should be scored
+// by comparing Content against the content of the last report.
+type ReportOutput struct {
+	Version       uint
+	Change        Importance
+	ChangeOmitted bool
+	Message       string
+	Checksum      string
+	State         map[string]interface{}
+	Content       string
+	HasContent    bool
+}
+
+// DecodeReportOutput strictly decodes a single report written by a monitor
+// script. Any problem with the report is described by a ValidationError.
+func DecodeReportOutput(r io.Reader) (ReportOutput, error) {
+	raw, readErr := ioutil.ReadAll(r)
+	if readErr != nil {
+		return ReportOutput{}, readErr
+	}
+	fields := map[string]json.RawMessage{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decodeErr := decoder.Decode(&fields)
+	if decodeErr != nil {
+		return ReportOutput{}, ValidationError{"", "output is not a JSON object: " + decodeErr.Error()}
+	}
+	if decoder.More() {
+		return ReportOutput{}, ValidationError{"", "output contains more than one JSON value"}
+	}
+	version := uint(1)
+	if rawVersion, found := fields[fieldVersion]; found {
+		decodeErr = json.Unmarshal(rawVersion, &version)
+		if decodeErr != nil || version == 0 || version > ReportProtocolVersion {
+			return ReportOutput{}, ValidationError{
+				fieldVersion,
+				fmt.Sprintf("must be a supported protocol version from 1 to %d", ReportProtocolVersion),
+			}
+		}
+		delete(fields, fieldVersion)
+	}
+	output, err := decodeReport(fields, version)
+	output.Version = version
+	return output, err
+}
+
+// decodeReport validates the fields of a report written using a given version
+// of the report protocol.
+func decodeReport(fields map[string]json.RawMessage, version uint) (ReportOutput, error) {
+	output := ReportOutput{}
+	if _, found := fields[fieldContent]; found && version >= 2 {
+		err := decodeRequired(fields, fieldContent, "must be a string", &output.Content)
+		if err != nil {
+			return ReportOutput{}, err
+		}
+		output.HasContent = true
+	}
+	_, foundNew := fields[fieldChange]
+	_, foundLegacy := fields[fieldChangeLegacy]
+	if output.HasContent && !foundNew && !foundLegacy {
+		output.ChangeOmitted = true
+	} else {
+		change, err := decodeChange(fields)
+		if err != nil {
+			return ReportOutput{}, err
+		}
+		output.Change = change
+	}
+	err := decodeRequired(fields, fieldMessage, "must be a string", &output.Message)
+	if err != nil {
+		return ReportOutput{}, err
+	}
+	err = decodeRequired(fields, fieldChecksum, "must be a string", &output.Checksum)
+	if err != nil {
+		return ReportOutput{}, err
+	}
+	if len(output.Checksum) > maxChecksumLength {
+		return ReportOutput{}, ValidationError{
+			fieldChecksum,
+			fmt.Sprintf("must not be longer than %d characters", maxChecksumLength),
+		}
+	}
+	err = decodeRequired(fields, fieldState, "must be a JSON object", &output.State)
+	if err != nil {
+		return ReportOutput{}, err
+	}
+	if output.State == nil {
+		return ReportOutput{}, ValidationError{fieldState, "must be a JSON object"}
+	}
+	for name := range fields {
+		return ReportOutput{}, ValidationError{
+			name,
+			fmt.Sprintf("is not part of version %d of the report protocol", version),
+		}
+	}
+	return output, nil
+}
+
+// decodeChange reads the change significance from either its documented or
+// legacy name, and checks that it is one of the known Importance levels.
+func decodeChange(fields map[string]json.RawMessage) (Importance, error) {
+	var change, legacyChange Importance
+	_, foundNew := fields[fieldChange]
+	_, foundLegacy := fields[fieldChangeLegacy]
+	if !foundNew && !foundLegacy {
+		return NoChange, ValidationError{fieldChange, "is required"}
+	}
+	problem := fmt.Sprintf("must be a whole number from 0 to %d", maxValidImportance)
+	if foundNew {
+		err := decodeRequired(fields, fieldChange, problem, &change)
+		if err != nil {
+			return NoChange, err
+		}
+	}
+	if foundLegacy {
+		err := decodeRequired(fields, fieldChangeLegacy, problem, &legacyChange)
+		if err != nil {
+			return NoChange, err
+		}
+		if foundNew && change != legacyChange {
+			return NoChange, ValidationError{fieldChange, "disagrees with " + fieldChangeLegacy}
+		}
+		change = legacyChange
+	}
+	if change > maxValidImportance {
+		return NoChange, ValidationError{fieldChange, problem}
+	}
+	return change, nil
+}
+
+// decodeRequired decodes a field that must be present into dest, removing it
+// from fields so that any fields left over afterwards are known to be unexpected.
+func decodeRequired(fields map[string]json.RawMessage, name, problem string, dest interface{}) error {
+	raw, found := fields[name]
+	if !found {
+		return ValidationError{name, "is required"}
+	}
+	delete(fields, name)
+	if string(raw) == "null" {
+		return ValidationError{name, problem}
+	}
+	err := json.Unmarshal(raw, dest)
+	if err != nil {
+		return ValidationError{name, problem}
+	}
+	return nil
+}
+
+// NextReport creates a new report for a monitor containing the validated output.
+func (o ReportOutput) NextReport(monitor Monitor) Report {
+	return Report{
+		id:                 -1,
+		createdBy:          monitor.ID(),
+		createdAt:          time.Now(),
+		changeSignificance: o.Change,
+		messageToAdmin:     o.Message,
+		checksum:           o.Checksum,
+		stateData:          o.State,
+		content:            o.Content,
+		scriptVersion:      monitor.ScriptVersion(),
+	}
+}
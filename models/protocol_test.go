@@ -0,0 +1,92 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeDocumentedReport(t *testing.T) {
+	output, err := DecodeReportOutput(strings.NewReader(`{
+		"lastChangeSignificance": 2,
+		"message": "hello world",
+		"checksum": "abc123",
+		"state": {"count": 1}
+	}`))
+	if err != nil {
+		t.Fatalf("expected a valid report to decode, got %v", err)
+	}
+	if output.Version != 1 {
+		t.Errorf("expected a report without a version to be treated as version 1")
+	}
+	if output.Change != ContentChange || output.Message != "hello world" || output.Checksum != "abc123" {
+		t.Errorf("expected fields to be decoded, got %v", output)
+	}
+	if output.State["count"] != float64(1) {
+		t.Errorf("expected state to be decoded, got %v", output.State)
+	}
+}
+
+func TestDecodeLegacyReport(t *testing.T) {
+	output, err := DecodeReportOutput(strings.NewReader(
+		`{"changeSignificance": 4, "message": "", "checksum": "", "state": {}}`))
+	if err != nil {
+		t.Fatalf("expected the legacy change significance name to be accepted, got %v", err)
+	}
+	if output.Change != Deleted {
+		t.Errorf("expected change significance to be decoded from legacy name")
+	}
+}
+
+func TestDecodeContentReport(t *testing.T) {
+	output, err := DecodeReportOutput(strings.NewReader(
+		`{"version": 2, "content": "page text", "message": "", "checksum": "", "state": {}}`))
+	if err != nil {
+		t.Fatalf("expected a version 2 report with content to decode, got %v", err)
+	}
+	if !output.HasContent || output.Content != "page text" || !output.ChangeOmitted {
+		t.Errorf("expected content to be decoded and the change left to be scored, got %v", output)
+	}
+	_, err = DecodeReportOutput(strings.NewReader(
+		`{"content": "page text", "message": "", "checksum": "", "state": {}}`))
+	if err == nil {
+		t.Errorf("expected content to be rejected from a version 1 report")
+	}
+}
+
+func TestDecodeEncodedReport(t *testing.T) {
+	report := NewReport(Monitor{})
+	_, err := DecodeReportOutput(strings.NewReader(report.String()))
+	if err != nil {
+		t.Errorf("expected a report written to a script to be valid output, got %v", err)
+	}
+}
+
+func TestDecodeInvalidReports(t *testing.T) {
+	invalid := map[string]string{
+		"not json":           `hello`,
+		"not an object":      `[1, 2, 3]`,
+		"two values":         `{"changeSignificance": 0, "message": "", "checksum": "", "state": {}} {}`,
+		"missing change":     `{"message": "", "checksum": "", "state": {}}`,
+		"fractional change":  `{"changeSignificance": 1.5, "message": "", "checksum": "", "state": {}}`,
+		"negative change":    `{"changeSignificance": -1, "message": "", "checksum": "", "state": {}}`,
+		"change too large":   `{"changeSignificance": 5, "message": "", "checksum": "", "state": {}}`,
+		"conflicting change": `{"changeSignificance": 1, "lastChangeSignificance": 2, "message": "", "checksum": "", "state": {}}`,
+		"missing message":    `{"changeSignificance": 0, "checksum": "", "state": {}}`,
+		"message not string": `{"changeSignificance": 0, "message": 3, "checksum": "", "state": {}}`,
+		"checksum null":      `{"changeSignificance": 0, "message": "", "checksum": null, "state": {}}`,
+		"state not object":   `{"changeSignificance": 0, "message": "", "checksum": "", "state": "x"}`,
+		"state null":         `{"changeSignificance": 0, "message": "", "checksum": "", "state": null}`,
+		"unknown field":      `{"changeSignificance": 0, "message": "", "checksum": "", "state": {}, "extra": 1}`,
+		"unknown version":    `{"version": 99, "changeSignificance": 0, "message": "", "checksum": "", "state": {}}`,
+	}
+	for name, output := range invalid {
+		_, err := DecodeReportOutput(strings.NewReader(output))
+		if err == nil {
+			t.Errorf("expected %s to be rejected", name)
+			continue
+		}
+		if _, isValidationErr := err.(ValidationError); !isValidationErr {
+			t.Errorf("expected %s to produce a ValidationError, got %v", name, err)
+		}
+	}
+}
@@ -52,6 +52,8 @@ create table if not exists sessions (
 );`
 
 // QInitRequestsTable is an SQL query that creates the requests table.
+// The rejected column is kept for older versions of miru, and state is used
+// instead.
 const QInitRequestsTable = `
 create table if not exists requests (
 	id integer primary key,
@@ -76,6 +78,128 @@ create table if not exists reports (
 	foreign key(created_by) references monitors(id)
 );`
 
+// QInitRunsTable is an SQL query that creates the runs table, which records
+// every time a monitor script is run.
+const QInitRunsTable = `
+create table if not exists runs (
+  id integer primary key,
+  monitor_id integer not null,
+  report_id integer,
+  started_at timestamp not null,
+  finished_at timestamp not null,
+  exit_code integer,
+  stderr text,
+  stdout_size integer,
+  outcome varchar(32) not null,
+  error_message text,
+  foreign key(monitor_id) references monitors(id),
+  foreign key(report_id) references reports(id)
+);`
+
+// QInitScriptVersionsTable is an SQL query that creates the script_versions
+// table, which records every script that each monitor has run. Versions are
+// numbered from 1 for each monitor.
+const QInitScriptVersionsTable = `
+create table if not exists script_versions (
+  id integer primary key,
+  monitor_id integer not null,
+  version integer not null,
+  interpreter varchar(16) not null,
+  script_location varchar(255) not null,
+  uploaded_by integer not null,
+  created_at timestamp not null,
+  checksum varchar(64) not null,
+  note text not null default '',
+  unique(monitor_id, version),
+  foreign key(monitor_id) references monitors(id),
+  foreign key(uploaded_by) references archivers(id)
+);`
+
+// QInitSnapshotsTable is an SQL query that creates the snapshots table, which
+// links reports to the page bodies saved in the snapshot store.
+const QInitSnapshotsTable = `
+create table if not exists snapshots (
+  id integer primary key,
+  report_id integer not null,
+  checksum varchar(64) not null,
+  size integer not null,
+  created_at timestamp not null,
+  foreign key(report_id) references reports(id)
+);`
+
+// QInitMonitorSecretsTable is an SQL query that creates the monitor_secrets
+// table, which stores the encrypted values that a monitor's script is given as
+// environment variables.
+const QInitMonitorSecretsTable = `
+create table if not exists monitor_secrets (
+  id integer primary key,
+  monitor_id integer not null,
+  name varchar(64) not null,
+  value text not null,
+  set_by integer not null,
+  updated_at timestamp not null,
+  unique(monitor_id, name),
+  foreign key(monitor_id) references monitors(id),
+  foreign key(set_by) references archivers(id)
+);`
+
+// QInitAPITokensTable is an SQL query that creates the api_tokens table, which
+// stores tokens that archivers can give to programs to use the API with.
+const QInitAPITokensTable = `
+create table if not exists api_tokens (
+  id integer primary key,
+  owner integer not null,
+  name varchar(128) not null,
+  scope varchar(16) not null,
+  secret_hash varchar(255) not null,
+  created_at timestamp not null,
+  last_used_at timestamp not null,
+  revoked bool not null default 0,
+  foreign key(owner) references archivers(id)
+);`
+
+// QInitWebhooksTable is an SQL query that creates the webhooks table, which
+// stores URLs that reports are sent to when significant changes are detected.
+const QInitWebhooksTable = `
+create table if not exists webhooks (
+  id integer primary key,
+  created_by integer not null,
+  created_at timestamp not null,
+  url text not null,
+  secret varchar(64) not null,
+  min_change_significance integer not null,
+  active bool not null default 1,
+  foreign key(created_by) references archivers(id)
+);`
+
+// QInitWebhookDeliveriesTable is an SQL query that creates the
+// webhook_deliveries table, which records every attempt to send a report to
+// a webhook.
+const QInitWebhookDeliveriesTable = `
+create table if not exists webhook_deliveries (
+  id integer primary key,
+  webhook_id integer not null,
+  report_id integer not null,
+  attempt integer not null,
+  attempted_at timestamp not null,
+  status_code integer not null,
+  error_message text,
+  succeeded bool not null,
+  foreign key(webhook_id) references webhooks(id),
+  foreign key(report_id) references reports(id)
+);`
+
+// QInitReportSubscriptionsTable is an SQL query that creates the
+// report_subscriptions table, which records the administrators who want to be
+// emailed about significant changes.
+const QInitReportSubscriptionsTable = `
+create table if not exists report_subscriptions (
+  id integer primary key,
+  archiver_id integer not null unique,
+  created_at timestamp not null,
+  foreign key(archiver_id) references archivers(id)
+);`
+
 // QInitAntiCSRFTokensTable is an SQL query that creates the table we use
 // for anti CSRF tokens, which we will expect to be submitted with all
 // forms for sensitive actions.
@@ -85,22 +209,113 @@ create table if not exists anti_csrf_tokens (
   created_at timestamp
  );`
 
+// QInitRequestTransitionsTable is an SQL query that creates the
+// request_transitions table, an audit log of every change to a request's state.
+const QInitRequestTransitionsTable = `
+create table if not exists request_transitions (
+  id integer primary key,
+  request_id integer not null,
+  actor integer not null,
+  from_state varchar(16) not null,
+  to_state varchar(16) not null,
+  reason text not null default '',
+  created_at timestamp not null,
+  foreign key(request_id) references requests(id),
+  foreign key(actor) references archivers(id)
+);`
+
+// QMigrations are SQL queries that add columns to tables created by older
+// versions of miru, and fill them in for existing rows. They are run every time
+// miru starts, after the tables are created, so adding a column fails harmlessly
+// if it already exists, and updates must only change rows that still need it.
+var QMigrations = []string{
+	`alter table reports add column content text not null default '';`,
+	`alter table requests add column state varchar(16) not null default 'pending';`,
+	`alter table requests add column claimed_by integer not null default -1;`,
+	`alter table requests add column reason text not null default '';`,
+	`alter table requests add column merged_into integer not null default -1;`,
+	`alter table monitors add column status varchar(16) not null default 'active';`,
+	`alter table monitors add column script_version integer not null default 0;`,
+	`alter table reports add column script_version integer not null default 0;`,
+	// Requests made before states existed are rejected or implicitly fulfilled.
+	`update requests set state = 'rejected' where rejected and state = 'pending';`,
+	`update requests set state = 'fulfilled'
+	 where state = 'pending' and exists(select id from monitors where created_for = requests.id);`,
+	// Scripts uploaded before they were versioned become the first version.
+	`insert into script_versions (
+	   monitor_id, version, interpreter, script_location, uploaded_by, created_at, checksum, note)
+	 select id, 1, interpreter, script_location, created_by, created_at, '', 'Uploaded before scripts were versioned'
+	 from monitors
+	 where script_version = 0 and not exists(select id from script_versions where monitor_id = monitors.id);`,
+	`update monitors set script_version = 1 where script_version = 0;`,
+}
+
 // QSaveMonitor is an SQL query that saves a new monitor.
 const QSaveMonitor = `
 insert into monitors (
   interpreter, script_location, created_for, created_by, created_at,
-  last_ran_at, wait_period_minutes, expected_run_time
-) values ($1, $2, $3, $4, $5, $6, $7, $8);`
+  last_ran_at, wait_period_minutes, expected_run_time, status
+) values ($1, $2, $3, $4, $5, $6, $7, $8, 'active');`
 
-// QUpdateMonitor is an SQL query that updates an existing monitor.
-// Note that we would rather a monitor be deleted and a new one created if a new
-// script is uploaded.
+// QUpdateMonitor is an SQL query that updates the schedule of a monitor that
+// hasn't been retired.
 const QUpdateMonitor = `
 update monitors set
   last_ran_at = $1,
   wait_period_minutes = $2,
   expected_run_time = $3
-where id = $4;`
+where id = $4 and status != 'retired';`
+
+// QUpdateMonitorSchedule is an SQL query that changes how often a monitor that
+// hasn't been retired runs, and how long it is expected to run for.
+const QUpdateMonitorSchedule = `
+update monitors set
+  wait_period_minutes = $1,
+  expected_run_time = $2
+where id = $3 and status != 'retired';`
+
+// QUpdateMonitorLastRun is an SQL query that records the time a monitor last ran.
+const QUpdateMonitorLastRun = `update monitors set last_ran_at = $1 where id = $2;`
+
+// QSetMonitorScript is an SQL query that changes the version of its script
+// that a monitor that hasn't been retired runs.
+const QSetMonitorScript = `
+update monitors set
+  interpreter = $1,
+  script_location = $2,
+  script_version = $3
+where id = $4 and status != 'retired';`
+
+// QSaveScriptVersion is an SQL query that saves a new version of a monitor's
+// script, numbered one after the monitor's latest version.
+const QSaveScriptVersion = `
+insert into script_versions (
+  monitor_id, version, interpreter, script_location, uploaded_by, created_at, checksum, note
+) values (
+  $1, (select coalesce(max(version), 0) + 1 from script_versions where monitor_id = $1),
+  $2, $3, $4, $5, $6, $7);`
+
+// QFindScriptVersionNumber is an SQL query that finds the number given to a
+// script version when it was saved.
+const QFindScriptVersionNumber = `select version from script_versions where id = $1;`
+
+// QFindScriptVersion is an SQL query that finds a version of a monitor's script.
+const QFindScriptVersion = `
+select id, interpreter, script_location, uploaded_by, created_at, checksum, note
+from script_versions
+where monitor_id = $1 and version = $2;`
+
+// QListScriptVersions is an SQL query that finds every version of a monitor's
+// script, newest first.
+const QListScriptVersions = `
+select id, version, interpreter, script_location, uploaded_by, created_at, checksum, note
+from script_versions
+where monitor_id = $1
+order by version desc;`
+
+// QSetMonitorStatus is an SQL query that pauses, resumes or retires a monitor,
+// as long as it still has the status it was expected to have.
+const QSetMonitorStatus = `update monitors set status = $1 where id = $2 and status = $3;`
 
 // QDeleteMonitor is an SQL query that deletes a monitor.
 const QDeleteMonitor = `delete from monitors where id = $1;`
@@ -110,9 +325,10 @@ const QDeleteMonitor = `delete from monitors where id = $1;`
 const QFindReadyMonitors = `
 select
   id, interpreter, script_location, created_for, created_by, created_at,
-  last_ran_at, wait_period_minutes, expected_run_time
+  last_ran_at, wait_period_minutes, expected_run_time, status, script_version
 from monitors
 where
+  status = 'active' and
   ((select julianday('now')) - julianday(last_ran_at)) * (60 * 24)
   >= wait_period_minutes
 limit $1;`
@@ -121,9 +337,28 @@ limit $1;`
 const QListMonitors = `
 select
   id, interpreter, script_location, created_for, created_by, created_at,
-  last_ran_at, wait_period_minutes, expected_run_time
+  last_ran_at, wait_period_minutes, expected_run_time, status, script_version
 from monitors;`
 
+// QFindMonitor is an SQL query that finds a monitor given its ID.
+const QFindMonitor = `
+select
+  interpreter, script_location, created_for, created_by, created_at,
+  last_ran_at, wait_period_minutes, expected_run_time, status, script_version
+from monitors
+where id = $1;`
+
+// QFindMonitorForRequest is an SQL query that finds the monitor most recently
+// created to fulfill a request.
+const QFindMonitorForRequest = `
+select
+  id, interpreter, script_location, created_by, created_at,
+  last_ran_at, wait_period_minutes, expected_run_time, status, script_version
+from monitors
+where created_for = $1
+order by id desc
+limit 1;`
+
 // QIsUserAnAdmin is an SQL query that checks if a given user has
 // administrator privileges, allowing them to create monitors.
 const QIsUserAnAdmin = `select is_administrator from archivers where id = $1;`
@@ -204,58 +439,272 @@ where exists(
 // QSaveRequest is an SQL query that inserts a new request.
 const QSaveRequest = `
 insert into requests (
-	created_by, created_at, url, instructions, rejected
-) values ($1, $2, $3, $4, 0);`
+	created_by, created_at, url, instructions, rejected, state, claimed_by, reason, merged_into
+) values ($1, $2, $3, $4, 0, 'pending', -1, '', -1);`
 
-// QRejectRequest is an SQL query that marks a request as rejected.
-const QRejectRequest = `
+// QTransitionRequest is an SQL query that moves a request to a new state, as
+// long as it is still in the state it was expected to be in.
+const QTransitionRequest = `
 update requests
-set rejected = 1 
-where id = $1;`
+set state = $1, claimed_by = $2, reason = $3, merged_into = $4, rejected = ($1 = 'rejected')
+where id = $5 and state = $6;`
 
-// QIsRequestFulfilled is an SQL query that determines whether a request has been
-// fulfilled by looking for a monitor that was created to fulfill it.
-const QIsRequestFulfilled = `
-select exists(
-	select id
-	from monitors 
-	where created_for = $1
-);`
+// QSaveRequestTransition is an SQL query that records a change to a request's state.
+const QSaveRequestTransition = `
+insert into request_transitions (
+  request_id, actor, from_state, to_state, reason, created_at
+) values ($1, $2, $3, $4, $5, $6);`
+
+// QListTransitionsForRequest is an SQL query that finds every change made to
+// a request's state, oldest first.
+const QListTransitionsForRequest = `
+select id, actor, from_state, to_state, reason, created_at
+from request_transitions
+where request_id = $1
+order by id asc;`
 
 // QFindRequest is an SQL query that attempts to find a monitor request.
 const QFindRequest = `
-select created_by, created_at, url, instructions, rejected
+select created_by, created_at, url, instructions, state, claimed_by, reason, merged_into
 from requests
 where id = $1;`
 
-// QListPendingRequests is an SQL query that finds all requests for which no
-// monitor has yet been created to fulfill.
+// QListPendingRequests is an SQL query that finds all requests that are still
+// open, oldest first.
 const QListPendingRequests = `
-select R.id, R.created_by, R.created_at, R.url, R.instructions
-from requests R
-where not R.rejected
-  and not exists(
-	select M.id
-	from monitors M
-	where M.created_for = R.id
-);`
+select id, created_by, created_at, url, instructions, state, claimed_by, reason, merged_into
+from requests
+where state in ('pending', 'claimed', 'in_progress')
+order by id asc;`
+
+// QListActiveRequests is an SQL query that finds all requests that are open or
+// have been fulfilled, oldest first.
+const QListActiveRequests = `
+select id, created_by, created_at, url, instructions, state, claimed_by, reason, merged_into
+from requests
+where state in ('pending', 'claimed', 'in_progress', 'fulfilled')
+order by id asc;`
+
+// QListRequestsMergedInto is an SQL query that finds the requests merged into
+// a given request as duplicates.
+const QListRequestsMergedInto = `
+select id, created_by, created_at, url, instructions, state, claimed_by, reason, merged_into
+from requests
+where merged_into = $1 and state = 'merged'
+order by id asc;`
+
+// QListRequestsForCreator is an SQL query that finds every request made by an
+// archiver, newest first.
+const QListRequestsForCreator = `
+select id, created_at, url, instructions, state, claimed_by, reason, merged_into
+from requests
+where created_by = $1
+order by id desc;`
 
 // QSaveReport is an SQL query that inserts a new report into the reports table.
 const QSaveReport = `
 insert into reports(
 	created_by, created_at, change_significance,
-	message_to_admin, checksum, state_data
-) values($1, $2, $3, $4, $5, $6);`
+	message_to_admin, checksum, state_data, content, script_version
+) values($1, $2, $3, $4, $5, $6, $7, $8);`
 
 // QFindLastReportForMonitor is an SQL query that attempts to find the last report
 // created by a monitor script.
 const QFindLastReportForMonitor = `
-select id, created_at, change_significance, message_to_admin, checksum, state_data
+select id, created_at, change_significance, message_to_admin, checksum, state_data, content, script_version
 from reports
 where created_by = $1
 order by id desc
 limit 1;`
 
+// QFindReport is an SQL query that finds a report given its ID.
+const QFindReport = `
+select created_by, created_at, change_significance, message_to_admin, checksum, state_data, content, script_version
+from reports
+where id = $1;`
+
+// QListReports is an SQL query that lists a page of the reports created by a
+// monitor script, or by any monitor script if the monitor ID is negative,
+// within a range of change significances and times, newest first.
+const QListReports = `
+select
+  id, created_by, created_at, change_significance, message_to_admin,
+  checksum, state_data, content, script_version
+from reports
+where
+  ($1 < 0 or created_by = $1) and
+  change_significance between $2 and $3 and
+  created_at >= $4 and created_at <= $5
+order by id desc
+limit $6 offset $7;`
+
+// QCountReports is an SQL query that counts the reports that QListReports
+// would find if it weren't limited to a page.
+const QCountReports = `
+select count(*)
+from reports
+where
+  ($1 < 0 or created_by = $1) and
+  change_significance between $2 and $3 and
+  created_at >= $4 and created_at <= $5;`
+
+// QFindPreviousReport is an SQL query that finds the report created by a
+// monitor script before the report with a given ID.
+const QFindPreviousReport = `
+select id, created_at, change_significance, message_to_admin, checksum, state_data, content, script_version
+from reports
+where created_by = $1 and id < $2
+order by id desc
+limit 1;`
+
+// QSaveRun is an SQL query that inserts a record of a monitor script's run.
+const QSaveRun = `
+insert into runs (
+  monitor_id, report_id, started_at, finished_at, exit_code,
+  stderr, stdout_size, outcome, error_message
+) values ($1, $2, $3, $4, $5, $6, $7, $8, $9);`
+
+// QListFailedRuns is an SQL query that finds the most recent runs that did not
+// have a successful outcome.
+const QListFailedRuns = `
+select
+  id, monitor_id, report_id, started_at, finished_at, exit_code,
+  stderr, stdout_size, outcome, error_message
+from runs
+where outcome <> $1
+order by id desc
+limit $2;`
+
+// QFindRun is an SQL query that finds a record of a run given its ID.
+const QFindRun = `
+select
+  monitor_id, report_id, started_at, finished_at, exit_code,
+  stderr, stdout_size, outcome, error_message
+from runs
+where id = $1;`
+
+// QFindRunForReport is an SQL query that finds the run that produced a report.
+const QFindRunForReport = `
+select
+  id, monitor_id, started_at, finished_at, exit_code,
+  stderr, stdout_size, outcome, error_message
+from runs
+where report_id = $1;`
+
+// QSaveSnapshot is an SQL query that inserts a record of a snapshot.
+const QSaveSnapshot = `
+insert into snapshots (
+  report_id, checksum, size, created_at
+) values ($1, $2, $3, $4);`
+
+// QFindSnapshot is an SQL query that finds a snapshot given its ID.
+const QFindSnapshot = `
+select report_id, checksum, size, created_at
+from snapshots
+where id = $1;`
+
+// QFindSnapshotForReport is an SQL query that finds the snapshot belonging to a report.
+const QFindSnapshotForReport = `
+select id, checksum, size, created_at
+from snapshots
+where report_id = $1;`
+
+// QSaveAPIToken is an SQL query that inserts a new API token.
+const QSaveAPIToken = `
+insert into api_tokens (
+  owner, name, scope, secret_hash, created_at, last_used_at, revoked
+) values ($1, $2, $3, $4, $5, $6, $7);`
+
+// QUpdateAPIToken is an SQL query that updates the parts of an API token that
+// can change.
+const QUpdateAPIToken = `
+update api_tokens set
+  last_used_at = $1,
+  revoked = $2
+where id = $3;`
+
+// QFindAPIToken is an SQL query that finds an API token given its ID.
+const QFindAPIToken = `
+select owner, name, scope, secret_hash, created_at, last_used_at, revoked
+from api_tokens
+where id = $1;`
+
+// QListAPITokensForOwner is an SQL query that finds all of the API tokens
+// belonging to an archiver.
+const QListAPITokensForOwner = `
+select id, name, scope, secret_hash, created_at, last_used_at, revoked
+from api_tokens
+where owner = $1
+order by id desc;`
+
+// QSaveWebhook is an SQL query that inserts a new webhook.
+const QSaveWebhook = `
+insert into webhooks (
+  created_by, created_at, url, secret, min_change_significance, active
+) values ($1, $2, $3, $4, $5, $6);`
+
+// QDeactivateWebhook is an SQL query that stops reports being sent to a webhook.
+const QDeactivateWebhook = `update webhooks set active = 0 where id = $1;`
+
+// QFindWebhook is an SQL query that finds a webhook given its ID.
+const QFindWebhook = `
+select created_by, created_at, url, secret, min_change_significance, active
+from webhooks
+where id = $1;`
+
+// QListWebhooks is an SQL query that lists every active webhook.
+const QListWebhooks = `
+select id, created_by, created_at, url, secret, min_change_significance, active
+from webhooks
+where active = 1;`
+
+// QListWebhooksForChange is an SQL query that lists the active webhooks whose
+// threshold is met by a change of a given significance.
+const QListWebhooksForChange = `
+select id, created_by, created_at, url, secret, min_change_significance, active
+from webhooks
+where active = 1 and min_change_significance <= $1;`
+
+// QSaveWebhookDelivery is an SQL query that inserts a record of an attempt to
+// send a report to a webhook.
+const QSaveWebhookDelivery = `
+insert into webhook_deliveries (
+  webhook_id, report_id, attempt, attempted_at, status_code, error_message, succeeded
+) values ($1, $2, $3, $4, $5, $6, $7);`
+
+// QListDeliveriesForWebhook is an SQL query that finds the most recent
+// attempts to send reports to a webhook.
+const QListDeliveriesForWebhook = `
+select id, report_id, attempt, attempted_at, status_code, error_message, succeeded
+from webhook_deliveries
+where webhook_id = $1
+order by id desc
+limit $2;`
+
+// QSaveReportSubscription is an SQL query that subscribes an archiver to
+// emails about significant changes.
+const QSaveReportSubscription = `
+insert into report_subscriptions (
+  archiver_id, created_at
+) values ($1, $2);`
+
+// QDeleteReportSubscription is an SQL query that unsubscribes an archiver.
+const QDeleteReportSubscription = `delete from report_subscriptions where id = $1;`
+
+// QFindReportSubscription is an SQL query that finds an archiver's subscription.
+const QFindReportSubscription = `
+select id, created_at
+from report_subscriptions
+where archiver_id = $1;`
+
+// QListReportSubscribers is an SQL query that finds the email addresses of
+// the administrators subscribed to emails about significant changes.
+const QListReportSubscribers = `
+select a.email_address
+from report_subscriptions s
+inner join archivers a on a.id = s.archiver_id
+where a.is_administrator = 1;`
+
 // QSaveLoginAttempt is an SQL query that inserts a new login attempt for a
 // given email address.
 const QSaveLoginAttempt = `
@@ -295,3 +744,24 @@ insert into anti_csrf_tokens (
 
 // QDeleteAntiCSRFToken is an SQL query that deletes a token.
 const QDeleteAntiCSRFToken = `delete from anti_csrf_tokens where token = $1;`
+
+// QSetMonitorSecret is an SQL query that saves a monitor's secret, replacing
+// any secret it already has with the same name.
+const QSetMonitorSecret = `
+insert or replace into monitor_secrets (
+  monitor_id, name, value, set_by, updated_at
+) values ($1, $2, $3, $4, $5);`
+
+// QDeleteMonitorSecret is an SQL query that deletes one of a monitor's secrets
+// given its name.
+const QDeleteMonitorSecret = `
+delete from monitor_secrets
+where monitor_id = $1 and name = $2;`
+
+// QListMonitorSecrets is an SQL query that finds every secret belonging to a
+// monitor, ordered by name.
+const QListMonitorSecrets = `
+select id, name, value, set_by, updated_at
+from monitor_secrets
+where monitor_id = $1
+order by name asc;`
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 )
 
@@ -38,6 +39,27 @@ func (i Importance) String() string {
 	}
 }
 
+// ParseImportance converts either the number of a level of significance or
+// its name, as written in the documentation for monitor scripts, such as
+// "content_change", into an Importance.
+func ParseImportance(s string) (Importance, error) {
+	names := map[string]Importance{
+		"no_change":      NoChange,
+		"minor_update":   MinorUpdate,
+		"content_change": ContentChange,
+		"rewritten":      Rewritten,
+		"deleted":        Deleted,
+	}
+	if importance, found := names[s]; found {
+		return importance, nil
+	}
+	level, parseErr := strconv.ParseUint(s, 10, 8)
+	if parseErr != nil || Importance(level) > Deleted {
+		return NoChange, fmt.Errorf("%q is not a level of change significance", s)
+	}
+	return Importance(level), nil
+}
+
 // Report contains information output by a monitor script informing us of any
 // changes on the site being monitored. The stateData (state in JSON) field can be
 // used by monitor scripts to include any extra data that might be useful to them.
@@ -49,11 +71,14 @@ type Report struct {
 	messageToAdmin     string
 	checksum           string
 	stateData          map[string]interface{}
+	content            string
+	scriptVersion      int
 }
 
 // encodableReport is a private struct that contains public elements, which allows
 // us to JSON encode the data that we need to write as input to monitor scripts.
 type encodableReport struct {
+	Version  uint                   `json:"version"`
 	Change   Importance             `json:"lastChangeSignificance"`
 	Message  string                 `json:"message"`
 	Checksum string                 `json:"checksum"`
@@ -70,6 +95,8 @@ func NewReport(monitor Monitor) Report {
 		messageToAdmin:     "first run",
 		checksum:           "",
 		stateData:          map[string]interface{}{},
+		content:            "",
+		scriptVersion:      monitor.ScriptVersion(),
 	}
 }
 
@@ -78,7 +105,7 @@ func FindLastReportForMonitor(db *sql.DB, monitor Monitor) (Report, error) {
 	r := Report{}
 	stateData := ""
 	err := db.QueryRow(QFindLastReportForMonitor, monitor.ID()).Scan(
-		&r.id, &r.createdAt, &r.changeSignificance, &r.messageToAdmin, &r.checksum, &stateData)
+		&r.id, &r.createdAt, &r.changeSignificance, &r.messageToAdmin, &r.checksum, &stateData, &r.content, &r.scriptVersion)
 	if err != nil {
 		return Report{}, err
 	}
@@ -91,9 +118,140 @@ func FindLastReportForMonitor(db *sql.DB, monitor Monitor) (Report, error) {
 	return r, nil
 }
 
+// FindReport attempts to find a Report given its ID.
+func FindReport(db *sql.DB, id int) (Report, error) {
+	r := Report{}
+	stateData := ""
+	err := db.QueryRow(QFindReport, id).Scan(
+		&r.createdBy, &r.createdAt, &r.changeSignificance, &r.messageToAdmin, &r.checksum, &stateData, &r.content, &r.scriptVersion)
+	if err != nil {
+		return Report{}, err
+	}
+	decodeErr := json.Unmarshal([]byte(stateData), &r.stateData)
+	if decodeErr != nil {
+		return Report{}, decodeErr
+	}
+	r.id = id
+	return r, nil
+}
+
+// ReportFilter describes which reports to list. A zero Since or Until leaves
+// that end of the time range open.
+type ReportFilter struct {
+	Monitor   int        // The ID of the monitor that created the reports, or -1 for any monitor.
+	MinChange Importance // The least significant change to include.
+	MaxChange Importance // The most significant change to include.
+	Since     time.Time
+	Until     time.Time
+}
+
+// NewReportFilter is the constructor function for a ReportFilter that matches
+// every report.
+func NewReportFilter() ReportFilter {
+	return ReportFilter{
+		Monitor:   -1,
+		MinChange: NoChange,
+		MaxChange: Deleted,
+	}
+}
+
+// ListReports obtains the reports matching a filter, newest first. At most
+// limit reports are returned, after skipping the first offset of them.
+func ListReports(db *sql.DB, filter ReportFilter, limit, offset uint) ([]Report, error) {
+	reports := []Report{}
+	since, until := filter.timeRange()
+	rows, err := db.Query(QListReports,
+		filter.Monitor, filter.MinChange, filter.MaxChange, since, until, limit, offset)
+	if err != nil {
+		return reports, err
+	}
+	for rows.Next() {
+		r := Report{}
+		stateData := ""
+		err = rows.Scan(
+			&r.id, &r.createdBy, &r.createdAt, &r.changeSignificance, &r.messageToAdmin,
+			&r.checksum, &stateData, &r.content, &r.scriptVersion)
+		if err != nil {
+			break
+		}
+		err = json.Unmarshal([]byte(stateData), &r.stateData)
+		if err != nil {
+			break
+		}
+		reports = append(reports, r)
+	}
+	return reports, err
+}
+
+// CountReports counts the reports matching a filter, so that lists of them can
+// be paginated.
+func CountReports(db *sql.DB, filter ReportFilter) (uint, error) {
+	var count uint
+	since, until := filter.timeRange()
+	err := db.QueryRow(QCountReports,
+		filter.Monitor, filter.MinChange, filter.MaxChange, since, until).Scan(&count)
+	return count, err
+}
+
+// ListReportsForMonitor obtains the reports produced by a monitor script that
+// were created between since and until, newest first. At most limit reports are
+// returned, after skipping the first offset of them. A zero since or until
+// leaves that end of the time range open.
+func ListReportsForMonitor(
+	db *sql.DB,
+	monitor Monitor,
+	since, until time.Time,
+	limit, offset uint) ([]Report, error) {
+	filter := NewReportFilter()
+	filter.Monitor = monitor.ID()
+	filter.Since, filter.Until = since, until
+	return ListReports(db, filter, limit, offset)
+}
+
+// CountReportsForMonitor counts the reports produced by a monitor script that
+// were created between since and until, so that lists of them can be paginated.
+func CountReportsForMonitor(db *sql.DB, monitor Monitor, since, until time.Time) (uint, error) {
+	filter := NewReportFilter()
+	filter.Monitor = monitor.ID()
+	filter.Since, filter.Until = since, until
+	return CountReports(db, filter)
+}
+
+// timeRange replaces the zero value for either end of the filter's time range
+// with a time that every report is inside of.
+func (f ReportFilter) timeRange() (time.Time, time.Time) {
+	since, until := f.Since, f.Until
+	if since.IsZero() {
+		since = time.Unix(0, 0)
+	}
+	if until.IsZero() {
+		until = time.Now().AddDate(100, 0, 0)
+	}
+	return since, until
+}
+
+// FindPreviousReport attempts to find the Report produced by the same monitor
+// script just before a given report.
+func FindPreviousReport(db *sql.DB, report Report) (Report, error) {
+	r := Report{}
+	stateData := ""
+	err := db.QueryRow(QFindPreviousReport, report.createdBy, report.id).Scan(
+		&r.id, &r.createdAt, &r.changeSignificance, &r.messageToAdmin, &r.checksum, &stateData, &r.content, &r.scriptVersion)
+	if err != nil {
+		return Report{}, err
+	}
+	decodeErr := json.Unmarshal([]byte(stateData), &r.stateData)
+	if decodeErr != nil {
+		return Report{}, decodeErr
+	}
+	r.createdBy = report.createdBy
+	return r, nil
+}
+
 // String converts the report to a JSON string.
 func (r Report) String() string {
 	encodable := encodableReport{
+		Version:  ReportProtocolVersion,
 		Change:   r.changeSignificance,
 		Message:  r.messageToAdmin,
 		Checksum: r.checksum,
@@ -112,6 +270,16 @@ func (r Report) ID() int {
 	return r.id
 }
 
+// CreatedBy is a getter for the ID of the monitor that produced the Report.
+func (r Report) CreatedBy() int {
+	return r.createdBy
+}
+
+// CreatedAt is a getter for the time that the Report was produced.
+func (r Report) CreatedAt() time.Time {
+	return r.createdAt
+}
+
 // Change is a getter for the Report's states significance of the change since its
 // last inspection.
 func (r Report) Change() Importance {
@@ -129,6 +297,24 @@ func (r Report) Checksum() string {
 	return r.checksum
 }
 
+// Content is a getter for the text content of the page that the Report was
+// produced from, if the monitor provided it.
+func (r Report) Content() string {
+	return r.content
+}
+
+// ScriptVersion is a getter for the version of its monitor's script that
+// produced the Report, which is 0 for reports made before scripts were versioned.
+func (r Report) ScriptVersion() int {
+	return r.scriptVersion
+}
+
+// State is a getter for the data that the monitor script wanted to keep for its
+// next run.
+func (r Report) State() map[string]interface{} {
+	return r.stateData
+}
+
 // SetChange is a setter function that sets the recorded significance of a site's last change.
 func (r *Report) SetChange(change Importance) {
 	r.changeSignificance = change
@@ -159,7 +345,8 @@ func (r *Report) Save(db *sql.DB) error {
 		return encodeErr
 	}
 	_, err := db.Exec(QSaveReport,
-		r.createdBy, r.createdAt, r.changeSignificance, r.messageToAdmin, r.checksum, string(stateData))
+		r.createdBy, r.createdAt, r.changeSignificance, r.messageToAdmin, r.checksum, string(stateData),
+		r.content, r.scriptVersion)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseImportance(t *testing.T) {
+	valid := map[string]Importance{
+		"0":              NoChange,
+		"4":              Deleted,
+		"minor_update":   MinorUpdate,
+		"content_change": ContentChange,
+		"rewritten":      Rewritten,
+	}
+	for input, expected := range valid {
+		importance, err := ParseImportance(input)
+		if err != nil || importance != expected {
+			t.Errorf("expected %q to be parsed as %v, got %v %v", input, expected, importance, err)
+		}
+	}
+	for _, input := range []string{"", "5", "-1", "Rewritten", "1.0"} {
+		if _, err := ParseImportance(input); err == nil {
+			t.Errorf("expected %q to be rejected", input)
+		}
+	}
+}
+
+func TestListReportsForMonitorPaginatesHistory(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	monitor := Monitor{id: 1}
+	base := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.Local)
+	saved := []Report{}
+	for i := 0; i < 5; i++ {
+		report := NewReport(monitor)
+		report.createdAt = base.Add(time.Duration(i) * time.Hour)
+		if err := report.Save(db); err != nil {
+			t.Fatal(err)
+		}
+		saved = append(saved, report)
+	}
+	other := NewReport(Monitor{id: 2})
+	other.createdAt = base.Add(2 * time.Hour)
+	if err := other.Save(db); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		since, until  time.Time
+		limit, offset uint
+		expected      []int
+		count         uint
+	}{
+		{time.Time{}, time.Time{}, 2, 0, []int{4, 3}, 5},
+		{time.Time{}, time.Time{}, 2, 2, []int{2, 1}, 5},
+		{time.Time{}, time.Time{}, 2, 4, []int{0}, 5},
+		{base.Add(time.Hour), base.Add(3 * time.Hour), 10, 0, []int{3, 2, 1}, 3},
+		{base.Add(4 * time.Hour), time.Time{}, 10, 0, []int{4}, 1},
+		{time.Time{}, base.Add(-time.Hour), 10, 0, []int{}, 0},
+	}
+	for _, c := range cases {
+		reports, err := ListReportsForMonitor(db, monitor, c.since, c.until, c.limit, c.offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := []int{}
+		for _, report := range reports {
+			ids = append(ids, report.ID())
+		}
+		expected := []int{}
+		for _, index := range c.expected {
+			expected = append(expected, saved[index].ID())
+		}
+		if len(ids) != len(expected) {
+			t.Errorf("expected reports %v, got %v", expected, ids)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != expected[i] {
+				t.Errorf("expected reports %v, got %v", expected, ids)
+				break
+			}
+		}
+		count, err := CountReportsForMonitor(db, monitor, c.since, c.until)
+		if err != nil || count != c.count {
+			t.Errorf("expected %d reports in the time range to be counted, got %d %v", c.count, count, err)
+		}
+	}
+}
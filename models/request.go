@@ -3,6 +3,7 @@ package models
 import (
 	"database/sql"
 	"errors"
+	"net/url"
 	"time"
 )
 
@@ -14,7 +15,10 @@ type Request struct {
 	createdAt    time.Time
 	url          string
 	instructions string
-	rejected     bool
+	state        RequestState
+	claimedBy    int
+	reason       string
+	mergedInto   int
 }
 
 // NewRequest is the constructor function for a new request to have a site monitored.
@@ -25,10 +29,27 @@ func NewRequest(creator Archiver, url, instructions string) Request {
 		createdAt:    time.Now(),
 		url:          url,
 		instructions: instructions,
-		rejected:     false,
+		state:        PendingState,
+		claimedBy:    -1,
+		reason:       "",
+		mergedInto:   -1,
 	}
 }
 
+// CleanURL normalizes a URL that a request is being made to monitor and strips
+// its query string, since it:
+// 1. Shouldn't be necessary.
+// 2. Could contain sensitive info about the archiver.
+func CleanURL(requestedURL string) (string, error) {
+	normalized, err := NormalizeURL(requestedURL)
+	if err != nil {
+		return "", err
+	}
+	parsedURL, _ := url.Parse(normalized)
+	parsedURL.RawQuery = ""
+	return parsedURL.String(), nil
+}
+
 // ID is a getter function for a request's unique identifier.
 func (r Request) ID() int {
 	return r.id
@@ -38,7 +59,8 @@ func (r Request) ID() int {
 func FindRequest(db *sql.DB, id int) (Request, error) {
 	r := Request{}
 	err := db.QueryRow(QFindRequest, id).Scan(
-		&r.createdBy, &r.createdAt, &r.url, &r.instructions, &r.rejected)
+		&r.createdBy, &r.createdAt, &r.url, &r.instructions,
+		&r.state, &r.claimedBy, &r.reason, &r.mergedInto)
 	if err != nil {
 		return Request{}, err
 	}
@@ -46,21 +68,87 @@ func FindRequest(db *sql.DB, id int) (Request, error) {
 	return r, nil
 }
 
-// ListPendingRequests attempts to find all requests that have not had monitors
-// created for them yet.
+// ListPendingRequests attempts to find all requests that are still open, having
+// been neither fulfilled nor rejected yet.
 func ListPendingRequests(db *sql.DB) ([]Request, error) {
+	return listRequests(db, QListPendingRequests)
+}
+
+// ListActiveRequests attempts to find all requests that are still open or have
+// been fulfilled.
+func ListActiveRequests(db *sql.DB) ([]Request, error) {
+	return listRequests(db, QListActiveRequests)
+}
+
+// ListRequestsMergedInto attempts to find the requests that were merged into
+// a request as duplicates of it.
+func ListRequestsMergedInto(db *sql.DB, request Request) ([]Request, error) {
+	return listRequests(db, QListRequestsMergedInto, request.ID())
+}
+
+// SimilarRequest is a request whose URL duplicates or overlaps another URL.
+type SimilarRequest struct {
+	Request Request
+	Overlap URLOverlap
+}
+
+// FindSimilarRequests finds the open and fulfilled requests whose URLs are
+// duplicates of, or overlap with, a URL. Duplicates are listed first.
+func FindSimilarRequests(db *sql.DB, requestedURL string) ([]SimilarRequest, error) {
+	similar := []SimilarRequest{}
+	requests, err := ListActiveRequests(db)
+	if err != nil {
+		return similar, err
+	}
+	overlapping := []SimilarRequest{}
+	for _, request := range requests {
+		switch CompareURLs(requestedURL, request.URL()) {
+		case DuplicateURL:
+			similar = append(similar, SimilarRequest{request, DuplicateURL})
+		case OverlappingURL:
+			overlapping = append(overlapping, SimilarRequest{request, OverlappingURL})
+		}
+	}
+	return append(similar, overlapping...), nil
+}
+
+// listRequests runs a query that lists requests.
+func listRequests(db *sql.DB, query string, args ...interface{}) ([]Request, error) {
+	requests := []Request{}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return requests, err
+	}
+	for rows.Next() {
+		r := Request{}
+		err = rows.Scan(
+			&r.id, &r.createdBy, &r.createdAt, &r.url, &r.instructions,
+			&r.state, &r.claimedBy, &r.reason, &r.mergedInto)
+		if err != nil {
+			return []Request{}, err
+		}
+		requests = append(requests, r)
+	}
+	return requests, nil
+}
+
+// ListRequestsForCreator attempts to find every request made by an archiver,
+// newest first.
+func ListRequestsForCreator(db *sql.DB, creator Archiver) ([]Request, error) {
 	requests := []Request{}
-	rows, err := db.Query(QListPendingRequests)
+	rows, err := db.Query(QListRequestsForCreator, creator.ID())
 	if err != nil {
 		return requests, err
 	}
 	for rows.Next() {
 		r := Request{}
-		err = rows.Scan(&r.id, &r.createdBy, &r.createdAt, &r.url, &r.instructions)
+		err = rows.Scan(
+			&r.id, &r.createdAt, &r.url, &r.instructions,
+			&r.state, &r.claimedBy, &r.reason, &r.mergedInto)
 		if err != nil {
 			return []Request{}, err
 		}
-		r.rejected = false
+		r.createdBy = creator.ID()
 		requests = append(requests, r)
 	}
 	return requests, nil
@@ -77,12 +165,43 @@ func (r Request) Instructions() string {
 	return r.instructions
 }
 
+// CreatedAt is a getter function for the time that the request was made.
+func (r Request) CreatedAt() time.Time {
+	return r.createdAt
+}
+
 // Creator is a getter function for the ID of the archiver that created
 // the request.
 func (r Request) Creator() int {
 	return r.createdBy
 }
 
+// State is a getter function for the stage of its life the request is at.
+func (r Request) State() RequestState {
+	return r.state
+}
+
+// ClaimedBy is a getter function for the ID of the administrator who claimed
+// the request, which is -1 if nobody has.
+func (r Request) ClaimedBy() int {
+	return r.claimedBy
+}
+
+// RejectionReason is a getter function for the explanation given when the
+// request was rejected.
+func (r Request) RejectionReason() string {
+	if r.state != RejectedState {
+		return ""
+	}
+	return r.reason
+}
+
+// MergedInto is a getter function for the ID of the request that this one was
+// merged into as a duplicate, which is -1 if it hasn't been.
+func (r Request) MergedInto() int {
+	return r.mergedInto
+}
+
 // Save inserts a new request into the requests table.
 func (r *Request) Save(db *sql.DB) error {
 	_, err := db.Exec(QSaveRequest, r.createdBy, r.createdAt, r.url, r.instructions)
@@ -98,17 +217,8 @@ func (r *Request) Update(db *sql.DB) error {
 	return errors.New("cannot update a monitor request")
 }
 
-// Delete removes a request from the database if it has not already been fulfilled
-// and had a monitor script created for it. It is a way to reject requests only.
+// Delete always returns an error. Requests are rejected or retired instead,
+// so that the archivers who made them can see what happened to them.
 func (r *Request) Delete(db *sql.DB) error {
-	isFulfilled := false
-	err := db.QueryRow(QIsRequestFulfilled, r.id).Scan(&isFulfilled)
-	if err != nil {
-		return err
-	}
-	if isFulfilled {
-		return errors.New("cannot delete a fulfilled request")
-	}
-	_, err = db.Exec(QRejectRequest, r.id)
-	return err
+	return errors.New("cannot delete a monitor request")
 }
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestListRequestsForCreator(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	admin := Archiver{id: 1, isAdmin: true}
+	creator := Archiver{id: 2}
+	first := saveTestRequest(t, db, creator, "https://example.com/first")
+	saveTestRequest(t, db, Archiver{id: 3}, "https://example.com/someone-else")
+	rejected := saveTestRequest(t, db, creator, "https://example.com/rejected")
+	if err := rejected.Reject(db, admin, "  the site is already archived  "); err != nil {
+		t.Fatal(err)
+	}
+	fulfilled := saveTestRequest(t, db, creator, "https://example.com/fulfilled")
+	monitor := NewMonitor(admin, fulfilled, Interpreter("python"), "script.py", time.Hour, time.Minute)
+	if _, err := fulfilled.FulfillWithMonitor(db, admin, &monitor, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	requests, err := ListRequestsForCreator(db, creator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 3 {
+		t.Fatalf("expected only the archiver's three requests, got %d", len(requests))
+	}
+	if requests[0].ID() != fulfilled.ID() || requests[1].ID() != rejected.ID() || requests[2].ID() != first.ID() {
+		t.Errorf("expected the archiver's requests newest first, got %d, %d, %d",
+			requests[0].ID(), requests[1].ID(), requests[2].ID())
+	}
+	if requests[0].State() != FulfilledState || requests[2].State() != PendingState {
+		t.Errorf("expected each request's current state, got %s and %s", requests[0].State(), requests[2].State())
+	}
+	if requests[1].State() != RejectedState || requests[1].RejectionReason() != "the site is already archived" {
+		t.Errorf("expected the rejection and its reason, got %s %q", requests[1].State(), requests[1].RejectionReason())
+	}
+	found, err := FindMonitorForRequest(db, requests[0])
+	if err != nil || found.ID() != monitor.ID() {
+		t.Errorf("expected to find the monitor created for the request, got %d %v", found.ID(), err)
+	}
+	if _, err := FindMonitorForRequest(db, requests[2]); err == nil {
+		t.Errorf("expected a pending request not to have a monitor")
+	}
+}
@@ -0,0 +1,343 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RequestState is the stage of its life that a request to have a site
+// monitored is at.
+type RequestState string
+
+// A request starts out pending. An administrator claims it so that nobody else
+// writes a script for it, marks it in progress while they do, and it becomes
+// fulfilled once a monitor is created for it. A request can be rejected, or
+// merged into another request for the same site, any time before then, and a
+// fulfilled request is retired once its site no longer needs to be monitored.
+const (
+	PendingState    RequestState = "pending"
+	ClaimedState    RequestState = "claimed"
+	InProgressState RequestState = "in_progress"
+	FulfilledState  RequestState = "fulfilled"
+	RejectedState   RequestState = "rejected"
+	RetiredState    RequestState = "retired"
+	MergedState     RequestState = "merged"
+)
+
+// requestTransitions lists the states that a request in each state can move to.
+// Releasing a claimed or in progress request returns it to pending.
+var requestTransitions = map[RequestState][]RequestState{
+	PendingState:    {ClaimedState, RejectedState, MergedState},
+	ClaimedState:    {PendingState, InProgressState, FulfilledState, RejectedState, MergedState},
+	InProgressState: {PendingState, FulfilledState, RejectedState, MergedState},
+	FulfilledState:  {RetiredState},
+	RejectedState:   {},
+	RetiredState:    {},
+	MergedState:     {},
+}
+
+// ErrClaimedByAnother is returned when an administrator tries to change a
+// request that another administrator has claimed.
+var ErrClaimedByAnother = errors.New("this request has been claimed by another administrator")
+
+// MaxRejectionReasonLength is the longest explanation that can be given for
+// rejecting a request.
+const MaxRejectionReasonLength int = 1000
+
+// ErrInvalidRejectionReason is returned when an administrator rejects a request
+// without explaining why, or with too long an explanation.
+var ErrInvalidRejectionReason = fmt.Errorf(
+	"please explain why the request is being rejected in at most %d characters", MaxRejectionReasonLength)
+
+// ErrRequestChanged is returned when a request was changed by someone else
+// between being read and being moved to a new state.
+var ErrRequestChanged = errors.New("the request was changed by someone else, please try again")
+
+// String returns a human-readable name for the state.
+func (s RequestState) String() string {
+	switch s {
+	case PendingState:
+		return "Pending"
+	case ClaimedState:
+		return "Claimed"
+	case InProgressState:
+		return "In progress"
+	case FulfilledState:
+		return "Fulfilled"
+	case RejectedState:
+		return "Rejected"
+	case RetiredState:
+		return "Retired"
+	case MergedState:
+		return "Merged"
+	default:
+		return "Unknown"
+	}
+}
+
+// IsOpen determines whether a request in the state is still waiting to be
+// fulfilled or rejected.
+func (s RequestState) IsOpen() bool {
+	return s == PendingState || s == ClaimedState || s == InProgressState
+}
+
+// CanTransition determines whether a request can move from one state to another.
+func CanTransition(from, to RequestState) bool {
+	for _, allowed := range requestTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestTransition is a record, kept for auditing, of a request moving from
+// one state to another.
+type RequestTransition struct {
+	id        int
+	request   int
+	actor     int
+	from      RequestState
+	to        RequestState
+	reason    string
+	createdAt time.Time
+}
+
+// ListTransitionsForRequest obtains every change made to a request's state,
+// oldest first.
+func ListTransitionsForRequest(db *sql.DB, request Request) ([]RequestTransition, error) {
+	transitions := []RequestTransition{}
+	rows, err := db.Query(QListTransitionsForRequest, request.ID())
+	if err != nil {
+		return transitions, err
+	}
+	for rows.Next() {
+		t := RequestTransition{}
+		err = rows.Scan(&t.id, &t.actor, &t.from, &t.to, &t.reason, &t.createdAt)
+		if err != nil {
+			break
+		}
+		t.request = request.ID()
+		transitions = append(transitions, t)
+	}
+	return transitions, err
+}
+
+// Actor is a getter function for the ID of the archiver who changed the
+// request's state.
+func (t RequestTransition) Actor() int {
+	return t.actor
+}
+
+// From is a getter function for the state the request was in before.
+func (t RequestTransition) From() RequestState {
+	return t.from
+}
+
+// To is a getter function for the state the request moved to.
+func (t RequestTransition) To() RequestState {
+	return t.to
+}
+
+// Reason is a getter function for the explanation given for the change, if any.
+func (t RequestTransition) Reason() string {
+	return t.reason
+}
+
+// CreatedAt is a getter function for the time that the change was made.
+func (t RequestTransition) CreatedAt() time.Time {
+	return t.createdAt
+}
+
+// Claim marks a pending request as being handled by an administrator so that
+// no other administrator starts writing a script for it.
+func (r *Request) Claim(db *sql.DB, admin Archiver) error {
+	return r.transition(db, admin, ClaimedState, admin.ID(), r.mergedInto, "")
+}
+
+// StartWork marks a request claimed by an administrator as having its script
+// written.
+func (r *Request) StartWork(db *sql.DB, admin Archiver) error {
+	return r.transition(db, admin, InProgressState, admin.ID(), r.mergedInto, "")
+}
+
+// Release returns a request that an administrator claimed to pending, so that
+// someone else can handle it.
+func (r *Request) Release(db *sql.DB, admin Archiver) error {
+	return r.transition(db, admin, PendingState, -1, r.mergedInto, "")
+}
+
+// Fulfill marks a request as having had a monitor created for it.
+func (r *Request) Fulfill(db *sql.DB, admin Archiver) error {
+	return r.transition(db, admin, FulfilledState, admin.ID(), r.mergedInto, "")
+}
+
+// Reject marks a request as one that won't be fulfilled, with an explanation
+// for the archiver who made it, which must be given.
+func (r *Request) Reject(db *sql.DB, admin Archiver, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" || len(reason) > MaxRejectionReasonLength {
+		return ErrInvalidRejectionReason
+	}
+	return r.transition(db, admin, RejectedState, r.claimedBy, r.mergedInto, reason)
+}
+
+// Retire marks a fulfilled request as no longer needing to be monitored.
+func (r *Request) Retire(db *sql.DB, admin Archiver) error {
+	return r.transition(db, admin, RetiredState, r.claimedBy, r.mergedInto, "")
+}
+
+// Merge marks an open request as a duplicate of another request that is open
+// or fulfilled, so that the archivers who made both share the other request's
+// monitor.
+func (r *Request) Merge(db *sql.DB, admin Archiver, into Request) error {
+	if into.id == r.id {
+		return errors.New("cannot merge a request into itself")
+	}
+	if !into.state.IsOpen() && into.state != FulfilledState {
+		return errors.New("requests can only be merged into open or fulfilled requests")
+	}
+	reason := fmt.Sprintf("Merged into request #%d for %s", into.id, into.url)
+	return r.transition(db, admin, MergedState, r.claimedBy, into.id, reason)
+}
+
+// FulfillWithMonitor creates a monitor for the request, along with the first
+// version of its script, and marks the request as fulfilled, claiming it first
+// if nobody has. Everything is saved in one transaction, so that a request is
+// never left claimed or fulfilled without a monitor, nor a monitor left
+// without a script.
+func (r *Request) FulfillWithMonitor(
+	db *sql.DB,
+	admin Archiver,
+	monitor *Monitor,
+	checksum string,
+	note string) (ScriptVersion, error) {
+	claiming := r.state == PendingState
+	claimed := *r
+	if claiming {
+		err := r.checkTransition(admin, ClaimedState)
+		if err != nil {
+			return ScriptVersion{}, err
+		}
+		claimed.state = ClaimedState
+		claimed.claimedBy = admin.ID()
+	}
+	err := claimed.checkTransition(admin, FulfilledState)
+	if err != nil {
+		return ScriptVersion{}, err
+	}
+	v, err := monitor.newScriptVersion(
+		admin, Interpreter(monitor.interpreter), monitor.scriptPath, checksum, note)
+	if err != nil {
+		return ScriptVersion{}, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return ScriptVersion{}, err
+	}
+	if claiming {
+		err = saveTransition(tx, *r, admin, ClaimedState, admin.ID(), r.mergedInto, "")
+	}
+	if err == nil {
+		err = saveTransition(tx, claimed, admin, FulfilledState, admin.ID(), r.mergedInto, "")
+	}
+	if err == nil {
+		err = monitor.insert(tx)
+	}
+	if err == nil {
+		v.monitor = monitor.id
+		err = saveScriptVersion(tx, &v)
+	}
+	if err != nil {
+		tx.Rollback()
+		monitor.id = -1
+		return ScriptVersion{}, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		monitor.id = -1
+		return ScriptVersion{}, err
+	}
+	monitor.useScriptVersion(v)
+	r.state = FulfilledState
+	r.claimedBy = admin.ID()
+	r.reason = ""
+	return v, nil
+}
+
+// transition moves the request to a new state and records the change.
+func (r *Request) transition(
+	db *sql.DB,
+	actor Archiver,
+	to RequestState,
+	claimedBy int,
+	mergedInto int,
+	reason string) error {
+	err := r.checkTransition(actor, to)
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	err = saveTransition(tx, *r, actor, to, claimedBy, mergedInto, reason)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	r.state = to
+	r.claimedBy = claimedBy
+	r.mergedInto = mergedInto
+	r.reason = reason
+	return nil
+}
+
+// checkTransition determines whether an archiver can move the request to a
+// new state. Only the administrator who claimed a request can change it while
+// it is claimed or in progress.
+func (r Request) checkTransition(actor Archiver, to RequestState) error {
+	if !actor.IsAdmin() {
+		return errors.New("only administrators can change the state of a request")
+	}
+	if !CanTransition(r.state, to) {
+		return fmt.Errorf("cannot move a request from %s to %s", r.state, to)
+	}
+	if (r.state == ClaimedState || r.state == InProgressState) && r.claimedBy != actor.ID() {
+		return ErrClaimedByAnother
+	}
+	return nil
+}
+
+// saveTransition moves a request to a new state and records the change as part
+// of a transaction. The update only applies if the request is still in the
+// state it was read in, so two administrators can't both claim the same request.
+func saveTransition(
+	tx *sql.Tx,
+	r Request,
+	actor Archiver,
+	to RequestState,
+	claimedBy int,
+	mergedInto int,
+	reason string) error {
+	result, err := tx.Exec(QTransitionRequest, to, claimedBy, reason, mergedInto, r.id, r.state)
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated != 1 {
+		return ErrRequestChanged
+	}
+	_, err = tx.Exec(QSaveRequestTransition, r.id, actor.ID(), r.state, to, reason, time.Now())
+	return err
+}
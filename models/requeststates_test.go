@@ -0,0 +1,119 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCanTransition(t *testing.T) {
+	allowed := [][2]RequestState{
+		{PendingState, ClaimedState},
+		{PendingState, RejectedState},
+		{ClaimedState, InProgressState},
+		{ClaimedState, PendingState},
+		{InProgressState, FulfilledState},
+		{InProgressState, PendingState},
+		{FulfilledState, RetiredState},
+	}
+	for _, pair := range allowed {
+		if !CanTransition(pair[0], pair[1]) {
+			t.Errorf("expected to be able to move from %s to %s", pair[0], pair[1])
+		}
+	}
+	forbidden := [][2]RequestState{
+		{PendingState, FulfilledState},
+		{PendingState, InProgressState},
+		{FulfilledState, RejectedState},
+		{RejectedState, PendingState},
+		{RetiredState, FulfilledState},
+		{ClaimedState, ClaimedState},
+	}
+	for _, pair := range forbidden {
+		if CanTransition(pair[0], pair[1]) {
+			t.Errorf("expected not to be able to move from %s to %s", pair[0], pair[1])
+		}
+	}
+}
+
+func TestTransitionRequiresClaimant(t *testing.T) {
+	admin := Archiver{id: 1, isAdmin: true}
+	other := Archiver{id: 2, isAdmin: true}
+	request := Request{id: 5, state: ClaimedState, claimedBy: admin.ID()}
+	if err := request.StartWork(nil, other); err != ErrClaimedByAnother {
+		t.Errorf("expected another admin to be stopped from working on a claimed request, got %v", err)
+	}
+	if err := request.Retire(nil, admin); err == nil {
+		t.Errorf("expected a claimed request not to be retired")
+	}
+	if err := request.Claim(nil, Archiver{id: 3}); err == nil {
+		t.Errorf("expected archivers who aren't admins not to be able to claim requests")
+	}
+	if request.State() != ClaimedState {
+		t.Errorf("expected failed transitions to leave the request unchanged, got %s", request.State())
+	}
+}
+
+func TestFulfillWithMonitor(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	admin := Archiver{id: 1, isAdmin: true}
+	request := saveTestRequest(t, db, Archiver{id: 2}, "https://example.com")
+	monitor := NewMonitor(admin, request, Interpreter("python"), "script.py", time.Hour, time.Minute)
+	v, err := request.FulfillWithMonitor(db, admin, &monitor, "abc", "first")
+	if err != nil {
+		t.Fatalf("expected to fulfill the request, got %v", err)
+	}
+	if request.State() != FulfilledState || monitor.ID() < 0 || v.Version() != 1 || monitor.ScriptVersion() != 1 {
+		t.Errorf("expected a fulfilled request with a monitor at version 1, got %s %d %d",
+			request.State(), monitor.ID(), v.Version())
+	}
+	transitions, err := ListTransitionsForRequest(db, request)
+	if err != nil || len(transitions) != 2 {
+		t.Errorf("expected the claim and fulfillment to be recorded, got %d %v", len(transitions), err)
+	}
+}
+
+func TestFulfillWithMonitorSavesNothingOnFailure(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	admin := Archiver{id: 1, isAdmin: true}
+	request := saveTestRequest(t, db, Archiver{id: 2}, "https://example.com")
+	// Someone else claims the request after this copy of it was read.
+	stale := request
+	other := Archiver{id: 3, isAdmin: true}
+	if err := request.Claim(db, other); err != nil {
+		t.Fatal(err)
+	}
+	monitor := NewMonitor(admin, stale, Interpreter("python"), "script.py", time.Hour, time.Minute)
+	if _, err := stale.FulfillWithMonitor(db, admin, &monitor, "", ""); err != ErrRequestChanged {
+		t.Errorf("expected the request to have been changed, got %v", err)
+	}
+	if monitor.ID() != -1 || stale.State() != PendingState {
+		t.Errorf("expected a failed fulfillment to leave the models unchanged")
+	}
+	monitors, err := ListMonitors(db)
+	if err != nil || len(monitors) != 0 {
+		t.Errorf("expected no monitor to be saved, got %d %v", len(monitors), err)
+	}
+	saved, err := FindRequest(db, request.ID())
+	if err != nil || saved.State() != ClaimedState || saved.ClaimedBy() != other.ID() {
+		t.Errorf("expected the request to stay claimed by the other administrator, got %s %v", saved.State(), err)
+	}
+}
+
+func TestRejectRequiresReason(t *testing.T) {
+	admin := Archiver{id: 1, isAdmin: true}
+	request := Request{id: 5, state: PendingState}
+	tooLong := make([]byte, MaxRejectionReasonLength+1)
+	for i := range tooLong {
+		tooLong[i] = 'a'
+	}
+	for _, reason := range []string{"", "   \n", string(tooLong)} {
+		if err := request.Reject(nil, admin, reason); err != ErrInvalidRejectionReason {
+			t.Errorf("expected a reason of %d characters to be rejected, got %v", len(reason), err)
+		}
+	}
+	if request.State() != PendingState {
+		t.Errorf("expected the request to stay pending, got %s", request.State())
+	}
+}
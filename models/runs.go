@@ -0,0 +1,249 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// RunOutcome is a pseudo-enum describing how a run of a monitor script ended.
+type RunOutcome string
+
+const (
+	// RunSucceeded means the script ran and produced a valid report.
+	RunSucceeded RunOutcome = "success"
+
+	// RunBadJSON means the script exited normally but did not output a valid report.
+	RunBadJSON RunOutcome = "bad_json"
+
+	// RunNonZeroExit means the script exited with a non-zero status code.
+	RunNonZeroExit RunOutcome = "nonzero_exit"
+
+	// RunTimedOut means the script ran for too long and was killed.
+	RunTimedOut RunOutcome = "timed_out"
+
+	// RunUnknownInterpreter means the monitor's interpreter is not one we support.
+	RunUnknownInterpreter RunOutcome = "unknown_interpreter"
+
+	// RunStartFailed means the interpreter could not be started at all.
+	RunStartFailed RunOutcome = "start_failed"
+
+	// RunFetchFailed means a fetch monitor could not retrieve the page it monitors.
+	RunFetchFailed RunOutcome = "fetch_failed"
+
+	// RunSandboxViolation means the script was stopped for exceeding one of the
+	// limits that the sandbox imposes on it.
+	RunSandboxViolation RunOutcome = "sandbox_violation"
+
+	// RunSecretsUnavailable means the script was not run because the secrets
+	// it is given could not be decrypted.
+	RunSecretsUnavailable RunOutcome = "secrets_unavailable"
+)
+
+// String produces a human-readable representation of each run outcome.
+func (o RunOutcome) String() string {
+	switch o {
+	case RunSucceeded:
+		return "Succeeded"
+	case RunBadJSON:
+		return "Bad Output"
+	case RunNonZeroExit:
+		return "Non-zero Exit"
+	case RunTimedOut:
+		return "Timed Out"
+	case RunUnknownInterpreter:
+		return "Unknown Interpreter"
+	case RunStartFailed:
+		return "Failed To Start"
+	case RunFetchFailed:
+		return "Fetch Failed"
+	case RunSandboxViolation:
+		return "Sandbox Violation"
+	case RunSecretsUnavailable:
+		return "Secrets Unavailable"
+	default:
+		return "Unknown"
+	}
+}
+
+// Run is a record of a single execution of a monitor's script, kept regardless
+// of whether the script succeeded in producing a report.
+type Run struct {
+	id         int
+	monitor    int
+	report     int
+	startedAt  time.Time
+	finishedAt time.Time
+	exitCode   int
+	stderr     string
+	stdoutSize int
+	outcome    string
+	errMessage string
+}
+
+// NewRun is the constructor function for a Run of a monitor's script that is
+// starting now.
+func NewRun(monitor Monitor) Run {
+	return Run{
+		id:         -1,
+		monitor:    monitor.ID(),
+		report:     -1,
+		startedAt:  time.Now(),
+		finishedAt: time.Now(),
+		exitCode:   -1,
+		stderr:     "",
+		stdoutSize: 0,
+		outcome:    string(RunSucceeded),
+		errMessage: "",
+	}
+}
+
+// ListFailedRuns obtains the most recent runs, up to a limit, that did not
+// succeed in producing a report.
+func ListFailedRuns(db *sql.DB, limit uint) ([]Run, error) {
+	runs := []Run{}
+	rows, err := db.Query(QListFailedRuns, string(RunSucceeded), limit)
+	if err != nil {
+		return runs, err
+	}
+	for rows.Next() {
+		r := Run{}
+		err = rows.Scan(
+			&r.id, &r.monitor, &r.report, &r.startedAt, &r.finishedAt,
+			&r.exitCode, &r.stderr, &r.stdoutSize, &r.outcome, &r.errMessage)
+		if err != nil {
+			break
+		}
+		runs = append(runs, r)
+	}
+	return runs, err
+}
+
+// FindRun attempts to find a record of a run given its ID.
+func FindRun(db *sql.DB, id int) (Run, error) {
+	r := Run{}
+	err := db.QueryRow(QFindRun, id).Scan(
+		&r.monitor, &r.report, &r.startedAt, &r.finishedAt, &r.exitCode,
+		&r.stderr, &r.stdoutSize, &r.outcome, &r.errMessage)
+	if err != nil {
+		return Run{}, err
+	}
+	r.id = id
+	return r, nil
+}
+
+// FindRunForReport attempts to find the run of a monitor script that produced
+// a given report.
+func FindRunForReport(db *sql.DB, report Report) (Run, error) {
+	r := Run{}
+	err := db.QueryRow(QFindRunForReport, report.ID()).Scan(
+		&r.id, &r.monitor, &r.startedAt, &r.finishedAt, &r.exitCode,
+		&r.stderr, &r.stdoutSize, &r.outcome, &r.errMessage)
+	if err != nil {
+		return Run{}, err
+	}
+	r.report = report.ID()
+	return r, nil
+}
+
+// ID is a getter function for the run's unique identifier.
+func (r Run) ID() int {
+	return r.id
+}
+
+// Monitor is a getter function for the ID of the monitor whose script was run.
+func (r Run) Monitor() int {
+	return r.monitor
+}
+
+// Report is a getter function for the ID of the report produced by the run,
+// which will be -1 if the run did not produce a report.
+func (r Run) Report() int {
+	return r.report
+}
+
+// StartedAt is a getter function for the time that the script was started.
+func (r Run) StartedAt() time.Time {
+	return r.startedAt
+}
+
+// FinishedAt is a getter function for the time that the script stopped running.
+func (r Run) FinishedAt() time.Time {
+	return r.finishedAt
+}
+
+// Duration computes the amount of time that the script ran for.
+func (r Run) Duration() time.Duration {
+	return r.finishedAt.Sub(r.startedAt)
+}
+
+// ExitCode is a getter function for the status code the script exited with,
+// which will be -1 if the script did not exit by itself.
+func (r Run) ExitCode() int {
+	return r.exitCode
+}
+
+// Stderr is a getter function for the output the script wrote to stderr.
+func (r Run) Stderr() string {
+	return r.stderr
+}
+
+// StdoutSize is a getter function for the number of bytes the script wrote to stdout.
+func (r Run) StdoutSize() int {
+	return r.stdoutSize
+}
+
+// Outcome is a getter function that converts the run's outcome back into a
+// RunOutcome type.
+func (r Run) Outcome() RunOutcome {
+	return RunOutcome(r.outcome)
+}
+
+// Error is a getter function for a description of what went wrong during the run.
+func (r Run) Error() string {
+	return r.errMessage
+}
+
+// Finish records the time the script stopped running, how it exited, and what
+// it output.
+func (r *Run) Finish(exitCode int, stderr string, stdoutSize int) {
+	r.finishedAt = time.Now()
+	r.exitCode = exitCode
+	r.stderr = stderr
+	r.stdoutSize = stdoutSize
+}
+
+// Fail is a setter function that records the reason that the run failed.
+func (r *Run) Fail(outcome RunOutcome, err error) {
+	r.outcome = string(outcome)
+	if err != nil {
+		r.errMessage = err.Error()
+	}
+}
+
+// SetReport is a setter function that links the run to the report it produced.
+func (r *Run) SetReport(report Report) {
+	r.report = report.ID()
+}
+
+// Save inserts a new record of a run into the database.
+func (r *Run) Save(db *sql.DB) error {
+	_, err := db.Exec(QSaveRun,
+		r.monitor, r.report, r.startedAt, r.finishedAt, r.exitCode,
+		r.stderr, r.stdoutSize, r.outcome, r.errMessage)
+	if err != nil {
+		return err
+	}
+	err = db.QueryRow(QLastRowID).Scan(&r.id)
+	return err
+}
+
+// Update always returns an error because a run is recorded once it finishes.
+func (r *Run) Update(db *sql.DB) error {
+	return errors.New("cannot change a run")
+}
+
+// Delete always returns an error because we don't want to lose run history.
+func (r *Run) Delete(db *sql.DB) error {
+	return errors.New("cannot delete a run")
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFailedRunsAreListedNewestFirst(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	monitor := Monitor{id: 3}
+	report := Report{id: 7}
+	succeeded := NewRun(monitor)
+	succeeded.Finish(0, "", 120)
+	succeeded.SetReport(report)
+	timedOut := NewRun(monitor)
+	timedOut.Finish(-1, "still waiting", 0)
+	timedOut.Fail(RunTimedOut, errors.New("took too long"))
+	badJSON := NewRun(monitor)
+	badJSON.Finish(0, "", 5)
+	badJSON.Fail(RunBadJSON, errors.New("not a report"))
+	for _, run := range []*Run{&succeeded, &timedOut, &badJSON} {
+		if err := run.Save(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	failed, err := ListFailedRuns(db, 10)
+	if err != nil || len(failed) != 2 {
+		t.Fatalf("expected the two failed runs to be listed, got %d %v", len(failed), err)
+	}
+	if failed[0].ID() != badJSON.ID() || failed[1].ID() != timedOut.ID() {
+		t.Errorf("expected the newest failed run first, got %d then %d", failed[0].ID(), failed[1].ID())
+	}
+	if limited, _ := ListFailedRuns(db, 1); len(limited) != 1 {
+		t.Errorf("expected the list to be limited to one run, got %d", len(limited))
+	}
+	found, err := FindRun(db, timedOut.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Monitor() != monitor.ID() || found.Outcome() != RunTimedOut || found.ExitCode() != -1 ||
+		found.Stderr() != "still waiting" || found.Error() != "took too long" || found.Report() != -1 {
+		t.Errorf("expected the failed run to be saved as it was recorded, got %v", found)
+	}
+	linked, err := FindRunForReport(db, report)
+	if err != nil || linked.ID() != succeeded.ID() || linked.StdoutSize() != 120 {
+		t.Errorf("expected to find the run that produced the report, got %v %v", linked, err)
+	}
+}
@@ -0,0 +1,221 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ScriptVersion is a record of one of the scripts that a monitor has run,
+// kept so that the monitor can be rolled back to it. Versions are numbered
+// from 1 for each monitor.
+type ScriptVersion struct {
+	id          int
+	monitor     int
+	version     int
+	interpreter string
+	scriptPath  string
+	uploadedBy  int
+	createdAt   time.Time
+	checksum    string
+	note        string
+}
+
+// ListScriptVersions obtains every version of a monitor's script, newest first.
+func ListScriptVersions(db *sql.DB, monitor Monitor) ([]ScriptVersion, error) {
+	versions := []ScriptVersion{}
+	rows, err := db.Query(QListScriptVersions, monitor.ID())
+	if err != nil {
+		return versions, err
+	}
+	for rows.Next() {
+		v := ScriptVersion{}
+		err = rows.Scan(
+			&v.id, &v.version, &v.interpreter, &v.scriptPath, &v.uploadedBy,
+			&v.createdAt, &v.checksum, &v.note)
+		if err != nil {
+			break
+		}
+		v.monitor = monitor.ID()
+		versions = append(versions, v)
+	}
+	return versions, err
+}
+
+// FindScriptVersion attempts to find a version of a monitor's script given its number.
+func FindScriptVersion(db *sql.DB, monitor Monitor, version int) (ScriptVersion, error) {
+	v := ScriptVersion{}
+	err := db.QueryRow(QFindScriptVersion, monitor.ID(), version).Scan(
+		&v.id, &v.interpreter, &v.scriptPath, &v.uploadedBy,
+		&v.createdAt, &v.checksum, &v.note)
+	if err != nil {
+		return ScriptVersion{}, err
+	}
+	v.monitor = monitor.ID()
+	v.version = version
+	return v, nil
+}
+
+// Version is a getter function for the number of the version.
+func (v ScriptVersion) Version() int {
+	return v.version
+}
+
+// Interpreter is a getter function for the type of the script.
+func (v ScriptVersion) Interpreter() Interpreter {
+	return Interpreter(v.interpreter)
+}
+
+// ScriptPath is a getter function for the location of the script on disk,
+// which is empty for fetch monitors.
+func (v ScriptVersion) ScriptPath() string {
+	return v.scriptPath
+}
+
+// UploadedBy is a getter function for the ID of the administrator who
+// uploaded the script.
+func (v ScriptVersion) UploadedBy() int {
+	return v.uploadedBy
+}
+
+// CreatedAt is a getter function for the time that the script was uploaded.
+func (v ScriptVersion) CreatedAt() time.Time {
+	return v.createdAt
+}
+
+// Checksum is a getter function for the hex-encoded SHA256 checksum of the
+// script, which is empty for fetch monitors and scripts uploaded before they
+// were versioned.
+func (v ScriptVersion) Checksum() string {
+	return v.checksum
+}
+
+// Note is a getter function for the description of what changed in the version.
+func (v ScriptVersion) Note() string {
+	return v.note
+}
+
+// AddScriptVersion saves a new version of the monitor's script, uploaded by an
+// administrator, and has the monitor run it from now on. The reports made by
+// earlier versions are kept, as are their scripts.
+func (m *Monitor) AddScriptVersion(
+	db *sql.DB,
+	uploader Archiver,
+	cmd Interpreter,
+	filePath string,
+	checksum string,
+	note string) (ScriptVersion, error) {
+	v, err := m.newScriptVersion(uploader, cmd, filePath, checksum, note)
+	if err != nil {
+		return ScriptVersion{}, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return ScriptVersion{}, err
+	}
+	err = saveScriptVersion(tx, &v)
+	if err != nil {
+		tx.Rollback()
+		return ScriptVersion{}, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return ScriptVersion{}, err
+	}
+	m.useScriptVersion(v)
+	return v, nil
+}
+
+// RollBack has the monitor run an earlier version of its script again.
+func (m *Monitor) RollBack(db *sql.DB, version int) error {
+	if m.status == RetiredMonitor {
+		return ErrMonitorRetired
+	}
+	v, err := FindScriptVersion(db, *m, version)
+	if err != nil {
+		return errors.New("no such script version")
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	err = setMonitorScript(tx, m.id, v)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	m.useScriptVersion(v)
+	return nil
+}
+
+// newScriptVersion creates a version of the monitor's script that hasn't been
+// saved yet, checking that the administrator can upload it.
+func (m Monitor) newScriptVersion(
+	uploader Archiver,
+	cmd Interpreter,
+	filePath string,
+	checksum string,
+	note string) (ScriptVersion, error) {
+	if m.status == RetiredMonitor {
+		return ScriptVersion{}, ErrMonitorRetired
+	}
+	if !uploader.IsAdmin() {
+		return ScriptVersion{}, errors.New("only administrators can upload monitor scripts")
+	}
+	return ScriptVersion{
+		id:          -1,
+		monitor:     m.id,
+		interpreter: string(cmd),
+		scriptPath:  filePath,
+		uploadedBy:  uploader.ID(),
+		createdAt:   time.Now(),
+		checksum:    checksum,
+		note:        note,
+	}, nil
+}
+
+// saveScriptVersion saves a new version of a monitor's script as part of a
+// transaction, numbering it after the monitor's latest version, and has the
+// monitor run it.
+func saveScriptVersion(tx *sql.Tx, v *ScriptVersion) error {
+	_, err := tx.Exec(QSaveScriptVersion,
+		v.monitor, v.interpreter, v.scriptPath, v.uploadedBy, v.createdAt, v.checksum, v.note)
+	if err == nil {
+		err = tx.QueryRow(QLastRowID).Scan(&v.id)
+	}
+	if err == nil {
+		err = tx.QueryRow(QFindScriptVersionNumber, v.id).Scan(&v.version)
+	}
+	if err == nil {
+		err = setMonitorScript(tx, v.monitor, *v)
+	}
+	return err
+}
+
+// setMonitorScript has a monitor that hasn't been retired run a version of its script.
+func setMonitorScript(tx *sql.Tx, monitorID int, v ScriptVersion) error {
+	result, err := tx.Exec(QSetMonitorScript, v.interpreter, v.scriptPath, v.version, monitorID)
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated != 1 {
+		return ErrMonitorRetired
+	}
+	return nil
+}
+
+// useScriptVersion updates the monitor to match the version of its script that
+// it now runs.
+func (m *Monitor) useScriptVersion(v ScriptVersion) {
+	m.interpreter = v.interpreter
+	m.scriptPath = v.scriptPath
+	m.version = v.version
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScriptVersionsAreNumberedPerMonitor(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	admin := Archiver{id: 1, isAdmin: true}
+	first := NewMonitor(admin, Request{id: 1}, Interpreter("python"), "first.py", time.Hour, time.Minute)
+	second := NewMonitor(admin, Request{id: 2}, Interpreter("python"), "second.py", time.Hour, time.Minute)
+	if err := first.Save(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Save(db); err != nil {
+		t.Fatal(err)
+	}
+	uploads := []struct {
+		monitor  *Monitor
+		expected int
+	}{
+		{&first, 1},
+		{&first, 2},
+		{&second, 1},
+		{&first, 3},
+		{&second, 2},
+	}
+	for i, upload := range uploads {
+		v, err := upload.monitor.AddScriptVersion(db, admin, Interpreter("python"), "script.py", "", "")
+		if err != nil {
+			t.Fatalf("upload %d: %v", i, err)
+		}
+		if v.Version() != upload.expected || upload.monitor.ScriptVersion() != upload.expected {
+			t.Errorf("upload %d: expected version %d, got %d and monitor at %d",
+				i, upload.expected, v.Version(), upload.monitor.ScriptVersion())
+		}
+	}
+	versions, err := ListScriptVersions(db, first)
+	if err != nil || len(versions) != 3 || versions[0].Version() != 3 {
+		t.Errorf("expected to list three versions newest first, got %d %v", len(versions), err)
+	}
+}
+
+func TestRollBack(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	admin := Archiver{id: 1, isAdmin: true}
+	monitor := NewMonitor(admin, Request{id: 1}, Interpreter("python"), "v1.py", time.Hour, time.Minute)
+	if err := monitor.Save(db); err != nil {
+		t.Fatal(err)
+	}
+	monitor.AddScriptVersion(db, admin, Interpreter("python"), "v1.py", "", "")
+	monitor.AddScriptVersion(db, admin, Interpreter("ruby"), "v2.rb", "", "")
+	if err := monitor.RollBack(db, 1); err != nil {
+		t.Fatalf("expected to roll back to version 1, got %v", err)
+	}
+	if monitor.ScriptVersion() != 1 || monitor.ScriptPath() != "v1.py" || monitor.Interpreter() != Interpreter("python") {
+		t.Errorf("expected the monitor to run v1.py again, got version %d %s", monitor.ScriptVersion(), monitor.ScriptPath())
+	}
+	saved, err := FindMonitor(db, monitor.ID())
+	if err != nil || saved.ScriptVersion() != 1 || saved.ScriptPath() != "v1.py" {
+		t.Errorf("expected the rollback to be saved, got version %d %v", saved.ScriptVersion(), err)
+	}
+	if err := monitor.RollBack(db, 3); err == nil {
+		t.Error("expected not to be able to roll back to a version that doesn't exist")
+	}
+	if monitor.ScriptVersion() != 1 {
+		t.Errorf("expected a failed rollback to leave the monitor unchanged, got version %d", monitor.ScriptVersion())
+	}
+}
+
+func TestMigrationMakesExistingScriptsTheFirstVersion(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	admin := Archiver{id: 1, isAdmin: true}
+	monitor := NewMonitor(admin, Request{id: 1}, Interpreter("perl"), "old.pl", time.Hour, time.Minute)
+	if err := monitor.Save(db); err != nil {
+		t.Fatal(err)
+	}
+	// Running the migrations twice must not add the version twice.
+	for i := 0; i < 2; i++ {
+		if err := migrateTables(db); err != nil {
+			t.Fatalf("could not migrate tables: %v", err)
+		}
+	}
+	versions, err := ListScriptVersions(db, monitor)
+	if err != nil || len(versions) != 1 {
+		t.Fatalf("expected the existing script to become one version, got %d %v", len(versions), err)
+	}
+	v := versions[0]
+	if v.Version() != 1 || v.ScriptPath() != "old.pl" || v.Interpreter() != Interpreter("perl") || v.UploadedBy() != admin.ID() {
+		t.Errorf("expected version 1 to be old.pl uploaded by the monitor's creator, got %d %s %d",
+			v.Version(), v.ScriptPath(), v.UploadedBy())
+	}
+	saved, err := FindMonitor(db, monitor.ID())
+	if err != nil || saved.ScriptVersion() != 1 {
+		t.Errorf("expected the monitor to run version 1, got %d %v", saved.ScriptVersion(), err)
+	}
+}
@@ -0,0 +1,248 @@
+package models
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SecretsKeyLength is the number of bytes in the key that monitor secrets are
+// encrypted with, which makes it an AES-256 key.
+const SecretsKeyLength int = 32
+
+// DefaultSecretsKeyFile is the file that the key monitor secrets are encrypted
+// with is kept in, if the configuration does not specify one.
+const DefaultSecretsKeyFile string = "secrets.key"
+
+// MaxSecretNameLength and MaxSecretValueLength are the longest names and
+// values that monitor secrets can have.
+const (
+	MaxSecretNameLength  int = 64
+	MaxSecretValueLength int = 4096
+)
+
+// Errors explaining why a secret could not be set, which are safe to show to
+// administrators.
+var (
+	ErrInvalidSecretName = errors.New(
+		"secret names must start with an uppercase letter or underscore and contain only uppercase letters, numbers and underscores")
+	ErrReservedSecretName = errors.New("that name is used by the environment miru runs scripts in")
+	ErrSecretTooLong      = fmt.Errorf(
+		"secret names must be at most %d characters long and values at most %d", MaxSecretNameLength, MaxSecretValueLength)
+	errInvalidSecretsKey = fmt.Errorf("the secrets key must be %d hex-encoded bytes", SecretsKeyLength)
+)
+
+// validSecretName matches the names that environment variables can have.
+var validSecretName = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+
+// reservedSecretNames are the environment variables that miru sets for
+// scripts itself, which secrets cannot replace.
+var reservedSecretNames = map[string]bool{
+	"PATH":   true,
+	"HOME":   true,
+	"TMPDIR": true,
+	"LANG":   true,
+}
+
+// MonitorSecret is a value, such as an API key or a password, that a monitor's
+// script is given as an environment variable when it runs. Secrets are stored
+// encrypted, and can be replaced or deleted but never read back, except by the
+// script that they are given to.
+type MonitorSecret struct {
+	id        int
+	monitor   int
+	name      string
+	value     string
+	setBy     int
+	updatedAt time.Time
+}
+
+// LoadSecretsKey reads the key that monitor secrets are encrypted with from a
+// file, where it is kept hex-encoded. If the file does not exist, a new key is
+// generated and written to it, readable only by miru's own user.
+func LoadSecretsKey(filePath string) ([]byte, error) {
+	if filePath == "" {
+		filePath = DefaultSecretsKeyFile
+	}
+	contents, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		key := make([]byte, SecretsKeyLength)
+		_, err = rand.Read(key)
+		if err != nil {
+			return nil, err
+		}
+		err = ioutil.WriteFile(filePath, []byte(hex.EncodeToString(key)+"\n"), 0600)
+		return key, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(contents)))
+	if err != nil || len(key) != SecretsKeyLength {
+		return nil, errInvalidSecretsKey
+	}
+	return key, nil
+}
+
+// SetMonitorSecret encrypts and saves a secret for a monitor, replacing any
+// secret it has with the same name.
+func SetMonitorSecret(
+	db *sql.DB,
+	key []byte,
+	setter Archiver,
+	monitor Monitor,
+	name string,
+	value string) error {
+	if !setter.IsAdmin() {
+		return errors.New("only administrators can set monitor secrets")
+	}
+	if monitor.Status() == RetiredMonitor {
+		return ErrMonitorRetired
+	}
+	if len(name) > MaxSecretNameLength || len(value) > MaxSecretValueLength {
+		return ErrSecretTooLong
+	}
+	if !validSecretName.MatchString(name) {
+		return ErrInvalidSecretName
+	}
+	if reservedSecretNames[name] {
+		return ErrReservedSecretName
+	}
+	sealed, err := sealSecret(key, monitor.ID(), name, value)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(QSetMonitorSecret, monitor.ID(), name, sealed, setter.ID(), time.Now())
+	return err
+}
+
+// DeleteMonitorSecret deletes one of a monitor's secrets given its name.
+func DeleteMonitorSecret(db *sql.DB, deleter Archiver, monitor Monitor, name string) error {
+	if !deleter.IsAdmin() {
+		return errors.New("only administrators can delete monitor secrets")
+	}
+	_, err := db.Exec(QDeleteMonitorSecret, monitor.ID(), name)
+	return err
+}
+
+// ListMonitorSecrets obtains every secret belonging to a monitor, ordered by name.
+func ListMonitorSecrets(db *sql.DB, monitor Monitor) ([]MonitorSecret, error) {
+	secrets := []MonitorSecret{}
+	rows, err := db.Query(QListMonitorSecrets, monitor.ID())
+	if err != nil {
+		return secrets, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		s := MonitorSecret{}
+		err = rows.Scan(&s.id, &s.name, &s.value, &s.setBy, &s.updatedAt)
+		if err != nil {
+			break
+		}
+		s.monitor = monitor.ID()
+		secrets = append(secrets, s)
+	}
+	return secrets, err
+}
+
+// MonitorEnvironment decrypts a monitor's secrets into the environment
+// variables to run its script with, written as NAME=value.
+func MonitorEnvironment(db *sql.DB, key []byte, monitor Monitor) ([]string, error) {
+	secrets, err := ListMonitorSecrets(db, monitor)
+	if err != nil {
+		return []string{}, err
+	}
+	environment := []string{}
+	for _, secret := range secrets {
+		value, openErr := openSecret(key, secret.monitor, secret.name, secret.value)
+		if openErr != nil {
+			return []string{}, fmt.Errorf("could not decrypt secret %s of monitor #%d: %v",
+				secret.name, monitor.ID(), openErr)
+		}
+		environment = append(environment, secret.name+"="+value)
+	}
+	return environment, nil
+}
+
+// Name is a getter function for the name of the environment variable that the
+// secret is given to the monitor's script as.
+func (s MonitorSecret) Name() string {
+	return s.name
+}
+
+// SetBy is a getter function for the ID of the administrator who last set the
+// secret's value.
+func (s MonitorSecret) SetBy() int {
+	return s.setBy
+}
+
+// UpdatedAt is a getter function for the time that the secret's value was last set.
+func (s MonitorSecret) UpdatedAt() time.Time {
+	return s.updatedAt
+}
+
+// sealSecret encrypts the value of a secret with AES-GCM, and hex-encodes the
+// nonce followed by the ciphertext. The monitor's ID and the secret's name are
+// authenticated along with it so that an encrypted value is only valid for the
+// secret it was set for.
+func sealSecret(key []byte, monitorID int, name, value string) (string, error) {
+	gcm, err := secretsCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), secretContext(monitorID, name))
+	return hex.EncodeToString(sealed), nil
+}
+
+// openSecret decrypts a value encrypted by sealSecret.
+func openSecret(key []byte, monitorID int, name, sealed string) (string, error) {
+	gcm, err := secretsCipher(key)
+	if err != nil {
+		return "", err
+	}
+	data, err := hex.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("encrypted secret is too short")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	value, err := gcm.Open(nil, nonce, ciphertext, secretContext(monitorID, name))
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// secretsCipher creates the AES-GCM cipher that secrets are encrypted with.
+func secretsCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != SecretsKeyLength {
+		return nil, errInvalidSecretsKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// secretContext produces the data that identifies which secret a value was
+// encrypted for.
+func secretContext(monitorID int, name string) []byte {
+	return []byte(fmt.Sprintf("monitor %d secret %s", monitorID, name))
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestSealedSecretsOnlyOpenForTheirMonitorAndName(t *testing.T) {
+	key := make([]byte, SecretsKeyLength)
+	sealed, err := sealSecret(key, 1, "API_KEY", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := openSecret(key, 1, "API_KEY", sealed)
+	if err != nil || value != "hunter2" {
+		t.Errorf("expected to decrypt hunter2, got %q %v", value, err)
+	}
+	if _, err := openSecret(key, 2, "API_KEY", sealed); err == nil {
+		t.Error("expected a secret not to decrypt for another monitor")
+	}
+	if _, err := openSecret(key, 1, "PASSWORD", sealed); err == nil {
+		t.Error("expected a secret not to decrypt under another name")
+	}
+	otherKey := make([]byte, SecretsKeyLength)
+	otherKey[0] = 1
+	if _, err := openSecret(otherKey, 1, "API_KEY", sealed); err == nil {
+		t.Error("expected a secret not to decrypt with another key")
+	}
+}
+
+func TestSetMonitorSecretValidatesInput(t *testing.T) {
+	key := make([]byte, SecretsKeyLength)
+	admin := Archiver{id: 1, isAdmin: true}
+	monitor := Monitor{id: 1, status: ActiveMonitor}
+	cases := map[string]error{
+		"lowercase":   ErrInvalidSecretName,
+		"1_START":     ErrInvalidSecretName,
+		"HAS-DASH":    ErrInvalidSecretName,
+		"":            ErrInvalidSecretName,
+		"PATH":        ErrReservedSecretName,
+		"HOME":        ErrReservedSecretName,
+		longName(100): ErrSecretTooLong,
+	}
+	for name, expected := range cases {
+		if err := SetMonitorSecret(nil, key, admin, monitor, name, "value"); err != expected {
+			t.Errorf("expected %q to be rejected with %v, got %v", name, expected, err)
+		}
+	}
+	if err := SetMonitorSecret(nil, key, Archiver{id: 2}, monitor, "API_KEY", "value"); err == nil {
+		t.Error("expected non-administrators not to be able to set secrets")
+	}
+	retired := Monitor{id: 1, status: RetiredMonitor}
+	if err := SetMonitorSecret(nil, key, admin, retired, "API_KEY", "value"); err != ErrMonitorRetired {
+		t.Errorf("expected secrets not to be set on retired monitors, got %v", err)
+	}
+}
+
+func TestLoadSecretsKeyCreatesKeyOnce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secretskey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile := path.Join(dir, "secrets.key")
+	created, err := LoadSecretsKey(keyFile)
+	if err != nil || len(created) != SecretsKeyLength {
+		t.Fatalf("expected a new key to be created, got %v", err)
+	}
+	info, _ := os.Stat(keyFile)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected the key file to only be readable by its owner, got %v", info.Mode())
+	}
+	loaded, err := LoadSecretsKey(keyFile)
+	if err != nil || string(loaded) != string(created) {
+		t.Errorf("expected the same key to be loaded again, got %v", err)
+	}
+	ioutil.WriteFile(keyFile, []byte("not a key"), 0600)
+	if _, err := LoadSecretsKey(keyFile); err == nil {
+		t.Error("expected an invalid key to be rejected")
+	}
+}
+
+func longName(length int) string {
+	name := make([]byte, length)
+	for i := range name {
+		name[i] = 'A'
+	}
+	return string(name)
+}
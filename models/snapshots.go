@@ -0,0 +1,100 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Snapshot is a record of the body of a page, as it was when a monitor
+// produced a report, having been saved to the snapshot store.
+type Snapshot struct {
+	id        int
+	report    int
+	checksum  string
+	size      int64
+	createdAt time.Time
+}
+
+// NewSnapshot is the constructor function for a Snapshot linking a report to
+// the page body saved in the snapshot store under checksum.
+func NewSnapshot(report Report, checksum string, size int64) Snapshot {
+	return Snapshot{
+		id:        -1,
+		report:    report.ID(),
+		checksum:  checksum,
+		size:      size,
+		createdAt: time.Now(),
+	}
+}
+
+// FindSnapshot attempts to find a snapshot given its ID.
+func FindSnapshot(db *sql.DB, id int) (Snapshot, error) {
+	s := Snapshot{}
+	err := db.QueryRow(QFindSnapshot, id).Scan(
+		&s.report, &s.checksum, &s.size, &s.createdAt)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	s.id = id
+	return s, nil
+}
+
+// FindSnapshotForReport attempts to find the snapshot of the page that a
+// report was produced for.
+func FindSnapshotForReport(db *sql.DB, report Report) (Snapshot, error) {
+	s := Snapshot{}
+	err := db.QueryRow(QFindSnapshotForReport, report.ID()).Scan(
+		&s.id, &s.checksum, &s.size, &s.createdAt)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	s.report = report.ID()
+	return s, nil
+}
+
+// ID is a getter function for the snapshot's unique identifier.
+func (s Snapshot) ID() int {
+	return s.id
+}
+
+// Report is a getter function for the ID of the report the snapshot belongs to.
+func (s Snapshot) Report() int {
+	return s.report
+}
+
+// Checksum is a getter function for the SHA256 checksum that the snapshot is
+// saved under in the snapshot store.
+func (s Snapshot) Checksum() string {
+	return s.checksum
+}
+
+// Size is a getter function for the size of the snapshot in bytes.
+func (s Snapshot) Size() int64 {
+	return s.size
+}
+
+// CreatedAt is a getter function for the time that the snapshot was taken.
+func (s Snapshot) CreatedAt() time.Time {
+	return s.createdAt
+}
+
+// Save inserts a new snapshot record into the database.
+func (s *Snapshot) Save(db *sql.DB) error {
+	_, err := db.Exec(QSaveSnapshot, s.report, s.checksum, s.size, s.createdAt)
+	if err != nil {
+		return err
+	}
+	err = db.QueryRow(QLastRowID).Scan(&s.id)
+	return err
+}
+
+// Update always returns an error because a snapshot cannot be changed.
+func (s *Snapshot) Update(db *sql.DB) error {
+	return errors.New("cannot change a snapshot")
+}
+
+// Delete always returns an error because we don't want to lose archived pages.
+func (s *Snapshot) Delete(db *sql.DB) error {
+	return errors.New("cannot delete a snapshot")
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ReportSubscription records that an administrator wants to be emailed when a
+// monitor reports a significant change.
+type ReportSubscription struct {
+	id        int
+	archiver  int
+	createdAt time.Time
+}
+
+// NewReportSubscription is the constructor function for a new ReportSubscription.
+func NewReportSubscription(archiver Archiver) ReportSubscription {
+	return ReportSubscription{
+		id:        -1,
+		archiver:  archiver.ID(),
+		createdAt: time.Now(),
+	}
+}
+
+// FindReportSubscription attempts to find an archiver's subscription.
+func FindReportSubscription(db *sql.DB, archiver Archiver) (ReportSubscription, error) {
+	s := ReportSubscription{}
+	err := db.QueryRow(QFindReportSubscription, archiver.ID()).Scan(&s.id, &s.createdAt)
+	if err != nil {
+		return ReportSubscription{}, err
+	}
+	s.archiver = archiver.ID()
+	return s, nil
+}
+
+// ListReportSubscribers obtains the email addresses of the administrators who
+// are subscribed to emails about significant changes. Archivers who subscribed
+// while they were an administrator but no longer are aren't included.
+func ListReportSubscribers(db *sql.DB) ([]string, error) {
+	addresses := []string{}
+	rows, err := db.Query(QListReportSubscribers)
+	if err != nil {
+		return addresses, err
+	}
+	for rows.Next() {
+		address := ""
+		err = rows.Scan(&address)
+		if err != nil {
+			break
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, err
+}
+
+// ID is a getter function for the subscription's unique identifier.
+func (s ReportSubscription) ID() int {
+	return s.id
+}
+
+// CreatedAt is a getter function for the time the archiver subscribed.
+func (s ReportSubscription) CreatedAt() time.Time {
+	return s.createdAt
+}
+
+// Save inserts a new subscription into the database.
+func (s *ReportSubscription) Save(db *sql.DB) error {
+	_, err := db.Exec(QSaveReportSubscription, s.archiver, s.createdAt)
+	if err != nil {
+		return err
+	}
+	err = db.QueryRow(QLastRowID).Scan(&s.id)
+	return err
+}
+
+// Update always returns an error since a subscription has nothing to change.
+func (s *ReportSubscription) Update(db *sql.DB) error {
+	return errors.New("cannot change a report subscription")
+}
+
+// Delete unsubscribes the archiver.
+func (s *ReportSubscription) Delete(db *sql.DB) error {
+	_, err := db.Exec(QDeleteReportSubscription, s.id)
+	return err
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openTestDB creates an in-memory database with every table miru uses, or
+// skips the test if the SQLite driver can't be used.
+func openTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err == nil {
+		err = db.Ping()
+	}
+	if err != nil {
+		t.Skipf("could not open an in-memory database: %v", err)
+	}
+	// Every connection to an in-memory database gets a database of its own.
+	db.SetMaxOpenConns(1)
+	err = InitializeTables(db)
+	if err != nil {
+		db.Close()
+		t.Fatalf("could not create tables: %v", err)
+	}
+	return db
+}
+
+// saveTestRequest saves a pending request for a URL made by an archiver.
+func saveTestRequest(t *testing.T, db *sql.DB, creator Archiver, url string) Request {
+	request := NewRequest(creator, url, "")
+	err := request.Save(db)
+	if err != nil {
+		t.Fatalf("could not save request: %v", err)
+	}
+	return request
+}
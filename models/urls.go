@@ -0,0 +1,104 @@
+package models
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// URLOverlap describes how the addresses of two requests relate to each other.
+type URLOverlap int
+
+// Two URLs are duplicates if they normalize to the same address, ignoring
+// whether they use http or https. They overlap if they're on the same host and
+// the path of one contains the other, like /climate and /climate/data.
+const (
+	NoOverlap URLOverlap = iota
+	DuplicateURL
+	OverlappingURL
+)
+
+// ErrUnsupportedURL is returned when asked to normalize something that isn't
+// an http or https URL.
+var ErrUnsupportedURL = errors.New("only http and https urls with a host can be monitored")
+
+// String returns a human-readable description of the overlap.
+func (o URLOverlap) String() string {
+	switch o {
+	case DuplicateURL:
+		return "Same address"
+	case OverlappingURL:
+		return "Overlapping address"
+	default:
+		return "No overlap"
+	}
+}
+
+// NormalizeURL rewrites a URL into a canonical form so that different ways of
+// writing the same address can be recognized:
+// 1. A missing scheme is assumed to be http.
+// 2. The scheme and host are lowercased, and default ports are removed.
+// 3. Dot segments, repeated slashes and trailing slashes are removed from the path.
+// 4. Query parameters are sorted.
+// 5. Fragments and user credentials are removed.
+func NormalizeURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", ErrUnsupportedURL
+	}
+	u.Host = strings.ToLower(u.Host)
+	host, port, splitErr := net.SplitHostPort(u.Host)
+	if splitErr == nil && ((u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443")) {
+		u.Host = host
+	}
+	u.Path = path.Clean("/" + u.Path)
+	u.RawPath = ""
+	u.RawQuery = u.Query().Encode()
+	u.ForceQuery = false
+	u.Fragment = ""
+	u.User = nil
+	return u.String(), nil
+}
+
+// CompareURLs determines whether two URLs are duplicates of each other or
+// overlap. URLs that can't be normalized don't overlap with anything.
+func CompareURLs(a, b string) URLOverlap {
+	normalizedA, errA := NormalizeURL(a)
+	normalizedB, errB := NormalizeURL(b)
+	if errA != nil || errB != nil {
+		return NoOverlap
+	}
+	urlA, _ := url.Parse(normalizedA)
+	urlB, _ := url.Parse(normalizedB)
+	if urlA.Host != urlB.Host {
+		return NoOverlap
+	}
+	if urlA.Path == urlB.Path && urlA.RawQuery == urlB.RawQuery {
+		return DuplicateURL
+	}
+	if pathContains(urlA.Path, urlB.Path) || pathContains(urlB.Path, urlA.Path) {
+		return OverlappingURL
+	}
+	return NoOverlap
+}
+
+// pathContains determines whether the page at child is under parent.
+func pathContains(parent, child string) bool {
+	if parent == child {
+		return true
+	}
+	if parent == "/" {
+		return true
+	}
+	return strings.HasPrefix(child, parent+"/")
+}
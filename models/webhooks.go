@@ -0,0 +1,261 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// webhookSecretLength is the number of random bytes to generate for the
+// secret used to sign the payloads sent to a webhook.
+const webhookSecretLength int = 32
+
+// Webhook is a URL that miru POSTs reports to when a monitor reports a change
+// at least as significant as the webhook's threshold, so that other tools can
+// act on changes without polling miru.
+type Webhook struct {
+	id        int
+	createdBy int
+	createdAt time.Time
+	url       string
+	secret    string
+	minChange Importance
+	active    bool
+}
+
+// NewWebhook is the constructor function for a new Webhook, which generates a
+// random secret for the payloads sent to it to be signed with.
+func NewWebhook(creator Archiver, url string, minChange Importance) (Webhook, error) {
+	secret := make([]byte, webhookSecretLength)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return Webhook{}, err
+	}
+	return Webhook{
+		id:        -1,
+		createdBy: creator.ID(),
+		createdAt: time.Now(),
+		url:       url,
+		secret:    hex.EncodeToString(secret),
+		minChange: minChange,
+		active:    true,
+	}, nil
+}
+
+// ListWebhooks obtains every webhook that is still active.
+func ListWebhooks(db *sql.DB) ([]Webhook, error) {
+	return listWebhooks(db, QListWebhooks)
+}
+
+// ListWebhooksForChange obtains the active webhooks whose threshold a change
+// of a given significance meets.
+func ListWebhooksForChange(db *sql.DB, change Importance) ([]Webhook, error) {
+	return listWebhooks(db, QListWebhooksForChange, change)
+}
+
+// listWebhooks runs a query that lists webhooks.
+func listWebhooks(db *sql.DB, query string, args ...interface{}) ([]Webhook, error) {
+	webhooks := []Webhook{}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return webhooks, err
+	}
+	for rows.Next() {
+		w := Webhook{}
+		err = rows.Scan(
+			&w.id, &w.createdBy, &w.createdAt, &w.url, &w.secret, &w.minChange, &w.active)
+		if err != nil {
+			break
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, err
+}
+
+// FindWebhook attempts to find a webhook given its ID.
+func FindWebhook(db *sql.DB, id int) (Webhook, error) {
+	w := Webhook{}
+	err := db.QueryRow(QFindWebhook, id).Scan(
+		&w.createdBy, &w.createdAt, &w.url, &w.secret, &w.minChange, &w.active)
+	if err != nil {
+		return Webhook{}, err
+	}
+	w.id = id
+	return w, nil
+}
+
+// ID is a getter function for the webhook's unique identifier.
+func (w Webhook) ID() int {
+	return w.id
+}
+
+// URL is a getter function for the URL that reports are POSTed to.
+func (w Webhook) URL() string {
+	return w.url
+}
+
+// Secret is a getter function for the key that payloads sent to the webhook
+// are signed with, which the receiver uses to check that they came from miru.
+func (w Webhook) Secret() string {
+	return w.secret
+}
+
+// MinChange is a getter function for the least significant change that the
+// webhook is sent reports for.
+func (w Webhook) MinChange() Importance {
+	return w.minChange
+}
+
+// CreatedAt is a getter function for the time that the webhook was registered.
+func (w Webhook) CreatedAt() time.Time {
+	return w.createdAt
+}
+
+// IsActive determines whether reports are still being sent to the webhook.
+func (w Webhook) IsActive() bool {
+	return w.active
+}
+
+// Save inserts a new webhook into the database.
+func (w *Webhook) Save(db *sql.DB) error {
+	_, err := db.Exec(QSaveWebhook,
+		w.createdBy, w.createdAt, w.url, w.secret, w.minChange, w.active)
+	if err != nil {
+		return err
+	}
+	err = db.QueryRow(QLastRowID).Scan(&w.id)
+	return err
+}
+
+// Update always returns an error. Webhooks should be deleted and registered
+// again to be changed.
+func (w *Webhook) Update(db *sql.DB) error {
+	return errors.New("cannot change a webhook")
+}
+
+// Delete stops reports from being sent to the webhook. The webhook is kept so
+// that its delivery log isn't lost.
+func (w *Webhook) Delete(db *sql.DB) error {
+	_, err := db.Exec(QDeactivateWebhook, w.id)
+	if err == nil {
+		w.active = false
+	}
+	return err
+}
+
+// WebhookDelivery is a record of a single attempt to send a report to a webhook.
+type WebhookDelivery struct {
+	id          int
+	webhook     int
+	report      int
+	attempt     int
+	attemptedAt time.Time
+	statusCode  int
+	errMessage  string
+	succeeded   bool
+}
+
+// NewWebhookDelivery is the constructor function for a record of an attempt,
+// counting from 1, to send a report to a webhook that is being made now.
+func NewWebhookDelivery(webhook Webhook, report Report, attempt int) WebhookDelivery {
+	return WebhookDelivery{
+		id:          -1,
+		webhook:     webhook.ID(),
+		report:      report.ID(),
+		attempt:     attempt,
+		attemptedAt: time.Now(),
+		statusCode:  0,
+		errMessage:  "",
+		succeeded:   false,
+	}
+}
+
+// ListDeliveriesForWebhook obtains the most recent attempts, up to a limit, to
+// send reports to a webhook.
+func ListDeliveriesForWebhook(db *sql.DB, webhook Webhook, limit uint) ([]WebhookDelivery, error) {
+	deliveries := []WebhookDelivery{}
+	rows, err := db.Query(QListDeliveriesForWebhook, webhook.ID(), limit)
+	if err != nil {
+		return deliveries, err
+	}
+	for rows.Next() {
+		d := WebhookDelivery{}
+		err = rows.Scan(
+			&d.id, &d.report, &d.attempt, &d.attemptedAt, &d.statusCode, &d.errMessage, &d.succeeded)
+		if err != nil {
+			break
+		}
+		d.webhook = webhook.ID()
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, err
+}
+
+// ID is a getter function for the delivery's unique identifier.
+func (d WebhookDelivery) ID() int {
+	return d.id
+}
+
+// Report is a getter function for the ID of the report that was sent.
+func (d WebhookDelivery) Report() int {
+	return d.report
+}
+
+// Attempt is a getter function for which attempt to send the report this was.
+func (d WebhookDelivery) Attempt() int {
+	return d.attempt
+}
+
+// AttemptedAt is a getter function for the time the attempt was made.
+func (d WebhookDelivery) AttemptedAt() time.Time {
+	return d.attemptedAt
+}
+
+// StatusCode is a getter function for the status code that the webhook
+// responded with, which will be 0 if it didn't respond.
+func (d WebhookDelivery) StatusCode() int {
+	return d.statusCode
+}
+
+// Error is a getter function for a description of why the attempt failed.
+func (d WebhookDelivery) Error() string {
+	return d.errMessage
+}
+
+// Succeeded determines whether the webhook accepted the report.
+func (d WebhookDelivery) Succeeded() bool {
+	return d.succeeded
+}
+
+// Finish records the webhook's response to the attempt, which succeeded if
+// it responded with a 2xx status code.
+func (d *WebhookDelivery) Finish(statusCode int, err error) {
+	d.statusCode = statusCode
+	if err != nil {
+		d.errMessage = err.Error()
+	}
+	d.succeeded = err == nil && statusCode >= 200 && statusCode <= 299
+}
+
+// Save inserts a new record of a delivery into the database.
+func (d *WebhookDelivery) Save(db *sql.DB) error {
+	_, err := db.Exec(QSaveWebhookDelivery,
+		d.webhook, d.report, d.attempt, d.attemptedAt, d.statusCode, d.errMessage, d.succeeded)
+	if err != nil {
+		return err
+	}
+	err = db.QueryRow(QLastRowID).Scan(&d.id)
+	return err
+}
+
+// Update always returns an error because a delivery is recorded once it's done.
+func (d *WebhookDelivery) Update(db *sql.DB) error {
+	return errors.New("cannot change a webhook delivery")
+}
+
+// Delete always returns an error because we don't want to lose delivery history.
+func (d *WebhookDelivery) Delete(db *sql.DB) error {
+	return errors.New("cannot delete a webhook delivery")
+}
@@ -0,0 +1,37 @@
+package notify
+
+import (
+	"sync"
+)
+
+// MemorySender is a Sender that keeps the messages sent to it instead of
+// delivering them, so that tests can check what would have been sent.
+type MemorySender struct {
+	lock     *sync.Mutex
+	messages []Message
+}
+
+// NewMemorySender is the constructor function for an empty MemorySender.
+func NewMemorySender() *MemorySender {
+	return &MemorySender{
+		lock:     &sync.Mutex{},
+		messages: []Message{},
+	}
+}
+
+// Send records a message.
+func (s *MemorySender) Send(msg Message) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// Messages returns every message sent so far, oldest first.
+func (s *MemorySender) Messages() []Message {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	messages := make([]Message, len(s.messages))
+	copy(messages, s.messages)
+	return messages
+}
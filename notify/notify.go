@@ -0,0 +1,166 @@
+package notify
+
+import (
+	"../config"
+
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Message is an email to send to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender is implemented by types that can deliver messages, so that the way
+// email is sent can be swapped out, for example to record messages in tests.
+type Sender interface {
+	Send(Message) error
+}
+
+// NewSender creates the Sender described by the application's configuration.
+// If no SMTP server is configured, messages are only logged.
+func NewSender(cfg *config.Config) Sender {
+	if cfg.SMTPHost == "" {
+		return LogSender{}
+	}
+	return NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.EmailFrom)
+}
+
+// LogSender is a Sender that prints messages instead of sending them, which
+// is used when no SMTP server is configured.
+type LogSender struct{}
+
+// Send prints the recipient and subject of a message.
+func (s LogSender) Send(msg Message) error {
+	fmt.Printf("Not emailing %s about %q because no SMTP server is configured\n", msg.To, msg.Subject)
+	return nil
+}
+
+// RequestData is used to fill in the messages sent to the archiver who made a
+// request to have a site monitored.
+type RequestData struct {
+	RequestID int
+	URL       string
+	MonitorID int
+	Reason    string
+	MergedURL string
+}
+
+// ReportData is used to fill in the messages sent to administrators about a
+// significant change reported by a monitor.
+type ReportData struct {
+	ReportID     int
+	MonitorID    int
+	URL          string
+	Significance string
+	Message      string
+}
+
+// Each message template defines a "subject" and a "body" template.
+var (
+	requestFulfilledTemplate = template.Must(template.New("fulfilled").Parse(`
+{{- define "subject"}}Your request to monitor {{.URL}} has been fulfilled{{end}}
+{{- define "body" -}}
+Hello,
+
+An administrator has fulfilled your request (#{{.RequestID}}) to monitor
+{{.URL}}
+
+Monitor #{{.MonitorID}} will now check the site for changes, and you can
+see what it finds on the reports page.
+
+- Miru
+{{end}}`))
+
+	requestRejectedTemplate = template.Must(template.New("rejected").Parse(`
+{{- define "subject"}}Your request to monitor {{.URL}} was rejected{{end}}
+{{- define "body" -}}
+Hello,
+
+An administrator has rejected your request (#{{.RequestID}}) to monitor
+{{.URL}}
+{{if .Reason}}
+The reason they gave was:
+{{.Reason}}
+{{end}}
+- Miru
+{{end}}`))
+
+	requestMergedTemplate = template.Must(template.New("merged").Parse(`
+{{- define "subject"}}Your request to monitor {{.URL}} was merged with another{{end}}
+{{- define "body" -}}
+Hello,
+
+Someone else had already asked to have {{.MergedURL}} monitored,
+so an administrator has merged your request (#{{.RequestID}}) to monitor
+{{.URL}}
+into theirs. You'll be told when it's fulfilled, and can follow it on the
+My Requests page.
+
+- Miru
+{{end}}`))
+
+	significantChangeTemplate = template.Must(template.New("change").Parse(`
+{{- define "subject"}}[{{.Significance}}] {{.URL}}{{end}}
+{{- define "body" -}}
+Hello,
+
+Monitor #{{.MonitorID}} reported a change to {{.URL}}
+with a significance of {{.Significance}}.
+
+{{.Message}}
+
+The full report (#{{.ReportID}}) is available on the reports page.
+
+- Miru
+{{end}}`))
+)
+
+// RequestFulfilled creates the message telling an archiver that a monitor has
+// been created for their request.
+func RequestFulfilled(to string, data RequestData) (Message, error) {
+	return render(requestFulfilledTemplate, to, data)
+}
+
+// RequestRejected creates the message telling an archiver that their request
+// was rejected.
+func RequestRejected(to string, data RequestData) (Message, error) {
+	return render(requestRejectedTemplate, to, data)
+}
+
+// RequestMerged creates the message telling an archiver that their request was
+// merged into another request for the same site.
+func RequestMerged(to string, data RequestData) (Message, error) {
+	return render(requestMergedTemplate, to, data)
+}
+
+// SignificantChange creates the message telling an administrator about a
+// significant change reported by a monitor.
+func SignificantChange(to string, data ReportData) (Message, error) {
+	return render(significantChangeTemplate, to, data)
+}
+
+// render fills in a message template's subject and body. Line breaks are
+// removed from the subject so that they can't be used to inject headers.
+func render(t *template.Template, to string, data interface{}) (Message, error) {
+	subject := new(bytes.Buffer)
+	err := t.ExecuteTemplate(subject, "subject", data)
+	if err != nil {
+		return Message{}, err
+	}
+	body := new(bytes.Buffer)
+	err = t.ExecuteTemplate(body, "body", data)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{
+		To:      to,
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Body:    body.String(),
+	}, nil
+}
@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRequestFulfilled(t *testing.T) {
+	msg, err := RequestFulfilled("archiver@site.com", RequestData{
+		RequestID: 3,
+		URL:       "https://example.com",
+		MonitorID: 7,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.To != "archiver@site.com" {
+		t.Errorf("expected the message to be addressed to the requester, got %s", msg.To)
+	}
+	if msg.Subject != "Your request to monitor https://example.com has been fulfilled" {
+		t.Errorf("unexpected subject %q", msg.Subject)
+	}
+	if !strings.Contains(msg.Body, "(#3)") || !strings.Contains(msg.Body, "Monitor #7") {
+		t.Errorf("expected the body to mention the request and monitor, got %q", msg.Body)
+	}
+}
+
+func TestSubjectCannotInjectHeaders(t *testing.T) {
+	msg, err := RequestRejected("archiver@site.com", RequestData{
+		URL: "https://example.com\r\nBcc: someone@else.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		t.Errorf("expected line breaks to be removed from the subject, got %q", msg.Subject)
+	}
+}
+
+func TestSMTPFormat(t *testing.T) {
+	sender := NewSMTPSender("smtp.site.com", 0, "", "", "miru@site.com")
+	if sender.addr != "smtp.site.com:587" {
+		t.Errorf("expected the default port to be used, got %s", sender.addr)
+	}
+	formatted := string(sender.format(Message{
+		To:      "admin@site.com",
+		Subject: "hello",
+		Body:    "line one\nline two\n",
+	}))
+	headers := strings.SplitN(formatted, "\r\n\r\n", 2)
+	if len(headers) != 2 {
+		t.Fatalf("expected headers to be separated from the body, got %q", formatted)
+	}
+	for _, header := range []string{"From: miru@site.com", "To: admin@site.com", "Subject: hello"} {
+		if !strings.Contains(headers[0], header) {
+			t.Errorf("expected header %q in %q", header, headers[0])
+		}
+	}
+	if headers[1] != "line one\r\nline two\r\n" {
+		t.Errorf("expected body lines to end with CRLF, got %q", headers[1])
+	}
+}
+
+func TestMemorySender(t *testing.T) {
+	sender := NewMemorySender()
+	sender.Send(Message{To: "a@site.com"})
+	sender.Send(Message{To: "b@site.com"})
+	messages := sender.Messages()
+	if len(messages) != 2 || messages[0].To != "a@site.com" || messages[1].To != "b@site.com" {
+		t.Errorf("expected both messages to be recorded in order, got %v", messages)
+	}
+}
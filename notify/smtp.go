@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// defaultSMTPPort is the port to connect to if the configuration does not
+// specify one.
+const defaultSMTPPort uint = 587
+
+// SMTPSender is a Sender that delivers messages through an SMTP server.
+type SMTPSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPSender is the constructor function for an SMTPSender. Credentials are
+// only sent to the server if a username is given.
+func NewSMTPSender(host string, port uint, username, password, from string) SMTPSender {
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return SMTPSender{
+		addr: fmt.Sprintf("%s:%d", host, port),
+		auth: auth,
+		from: from,
+	}
+}
+
+// Send delivers a message as plain text.
+func (s SMTPSender) Send(msg Message) error {
+	if strings.ContainsAny(msg.To, "\r\n") {
+		return errors.New("invalid recipient address")
+	}
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{msg.To}, s.format(msg))
+}
+
+// format writes out a message with the headers needed to send it.
+func (s SMTPSender) format(msg Message) []byte {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "From: %s\r\n", s.from)
+	fmt.Fprintf(buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(buf, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.Replace(msg.Body, "\n", "\r\n", -1))
+	return buf.Bytes()
+}
@@ -0,0 +1,104 @@
+package snapshots
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+)
+
+// DefaultDir is the directory that snapshots are saved to if the
+// configuration does not specify one.
+const DefaultDir string = "pagesnapshots"
+
+// ErrInvalidKey is returned when a key is not a hex-encoded SHA256 checksum,
+// which prevents keys from being used to reach files outside the store.
+var ErrInvalidKey = errors.New("snapshot keys must be hex-encoded SHA256 checksums")
+
+// Store saves the bodies of pages fetched by monitors to disk, keyed by the
+// SHA256 checksum of their content. Because snapshots are content-addressed,
+// a page that hasn't changed between runs is only stored once.
+type Store struct {
+	dir string
+}
+
+// NewStore is the constructor function for a Store that keeps snapshots in
+// dir. The directory is created when the first snapshot is saved.
+func NewStore(dir string) Store {
+	if dir == "" {
+		dir = DefaultDir
+	}
+	return Store{dir}
+}
+
+// Key computes the key that a body is saved under.
+func Key(body []byte) string {
+	checksum := sha256.Sum256(body)
+	return hex.EncodeToString(checksum[:])
+}
+
+// Save writes body to the store if it isn't already there and returns the key
+// that it can be found with.
+func (s Store) Save(body []byte) (string, error) {
+	key := Key(body)
+	filePath, _ := s.path(key)
+	if _, statErr := os.Stat(filePath); statErr == nil {
+		return key, nil
+	}
+	dir := path.Dir(filePath)
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", err
+	}
+	// Write to a temporary file first so that a partially written snapshot is
+	// never found under its key.
+	f, err := ioutil.TempFile(dir, "incomplete-")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(body)
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filePath)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return key, nil
+}
+
+// Stat describes the snapshot saved under key, and produces an error if there
+// is no such snapshot.
+func (s Store) Stat(key string) (os.FileInfo, error) {
+	filePath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(filePath)
+}
+
+// Open opens the snapshot saved under key for reading.
+func (s Store) Open(key string) (*os.File, error) {
+	filePath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(filePath)
+}
+
+// path determines where the snapshot with a given key is kept. Snapshots are
+// spread across subdirectories named after the first two characters of their
+// key to avoid keeping too many files in one directory.
+func (s Store) path(key string) (string, error) {
+	decoded, err := hex.DecodeString(key)
+	if err != nil || len(decoded) != sha256.Size {
+		return "", ErrInvalidKey
+	}
+	return path.Join(s.dir, key[:2], key), nil
+}
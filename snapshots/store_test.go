@@ -0,0 +1,50 @@
+package snapshots
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestSaveAndOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "miru-snapshots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := NewStore(dir)
+	body := []byte("<p>hello world</p>")
+	key, err := store.Save(body)
+	if err != nil {
+		t.Fatalf("expected to be able to save a snapshot, got %v", err)
+	}
+	if key != Key(body) {
+		t.Errorf("expected the snapshot to be saved under the checksum of its body")
+	}
+	again, err := store.Save(body)
+	if err != nil || again != key {
+		t.Errorf("expected saving the same body twice to produce the same key, got %s %v", again, err)
+	}
+	info, err := store.Stat(key)
+	if err != nil || info.Size() != int64(len(body)) {
+		t.Errorf("expected to find the size of the snapshot, got %v %v", info, err)
+	}
+	f, err := store.Open(key)
+	if err != nil {
+		t.Fatalf("expected to be able to open the snapshot, got %v", err)
+	}
+	defer f.Close()
+	saved, _ := ioutil.ReadAll(f)
+	if string(saved) != string(body) {
+		t.Errorf("expected the saved snapshot to match the body, got %s", saved)
+	}
+}
+
+func TestInvalidKeys(t *testing.T) {
+	store := NewStore("")
+	for _, key := range []string{"", "../../etc/passwd", "abc123", Key([]byte("x")) + "00"} {
+		if _, err := store.Open(key); err != ErrInvalidKey {
+			t.Errorf("expected %q to be rejected as a key, got %v", key, err)
+		}
+	}
+}
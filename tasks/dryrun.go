@@ -0,0 +1,37 @@
+package tasks
+
+import (
+	"../models"
+)
+
+// DryRunResult is the outcome of running a monitor's script once without
+// saving anything. Report is only set if Err is nil.
+type DryRunResult struct {
+	Report models.Report
+	Run    models.Run
+	Err    error
+}
+
+// DryRun runs a monitor's script once, the same way the watcher would for its
+// first run, so that administrators can check that a script works before a
+// monitor is created for it. Fetch monitors fetch url instead. Nothing is
+// saved, including snapshots of fetched pages.
+func DryRun(monitor models.Monitor, url string, opts RunOptions) DryRunResult {
+	opts.Snapshots = nil
+	firstReport := models.NewReport(monitor)
+	result := make(chan models.Report, 1)
+	err := make(chan error, 1)
+	var run models.Run
+	if monitor.Interpreter() == models.FetchInterpreter {
+		run = RunFetchMonitor(monitor, url, firstReport, opts, result, err)
+	} else {
+		run = RunMonitorScript(monitor, firstReport, opts, result, err)
+	}
+	// Both runners write their result before returning.
+	select {
+	case report := <-result:
+		return DryRunResult{Report: report, Run: run}
+	case runErr := <-err:
+		return DryRunResult{Run: run, Err: runErr}
+	}
+}
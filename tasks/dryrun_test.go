@@ -0,0 +1,31 @@
+package tasks
+
+import (
+	"../models"
+
+	"testing"
+)
+
+func TestDryRunProducesReport(t *testing.T) {
+	monitor := models.NewMonitor(
+		models.Archiver{}, models.Request{}, models.Interpreter("python"), "testpython.py", 0, 0)
+	result := DryRun(monitor, "", testOptions)
+	if result.Err != nil {
+		t.Fatalf("expected not to get an error: %v", result.Err)
+	}
+	if result.Report.Message() != "hello world" || result.Run.Outcome() != models.RunSucceeded {
+		t.Errorf("expected a successful run, got %q %v", result.Report.Message(), result.Run.Outcome())
+	}
+}
+
+func TestDryRunReportsFailure(t *testing.T) {
+	monitor := models.NewMonitor(
+		models.Archiver{}, models.Request{}, models.Interpreter("python"), "testerror.py", 0, 0)
+	result := DryRun(monitor, "", testOptions)
+	if result.Err == nil {
+		t.Fatalf("expected the failing script to produce an error")
+	}
+	if result.Run.Outcome() != models.RunNonZeroExit || result.Run.ExitCode() != 1 {
+		t.Errorf("expected a non-zero exit to be recorded, got %v %d", result.Run.Outcome(), result.Run.ExitCode())
+	}
+}
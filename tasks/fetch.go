@@ -0,0 +1,158 @@
+package tasks
+
+import (
+	"../diff"
+	"../models"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+)
+
+// maxFetchBytes is the largest page body that a fetch monitor will read.
+const maxFetchBytes int64 = 10 * 1024 * 1024
+
+// FetchError is produced when a fetch monitor cannot retrieve the site it is
+// monitoring for a reason other than the site having been deleted.
+type FetchError struct {
+	URL    string
+	Status int
+}
+
+// Error produces a message explaining which site couldn't be fetched.
+func (e FetchError) Error() string {
+	return fmt.Sprintf("fetching %s failed with status %d", e.URL, e.Status)
+}
+
+// RunFetchMonitor checks a site for changes without running a script by
+// fetching url, computing the SHA256 checksum of the page's body, and comparing
+// it to the checksum in the monitor's last report. The page's body is saved to
+// the snapshot store in opts, if there is one. When the checksum differs,
+// the text of the page is compared to the text saved with the last report to
+// classify how significant the change is. Like RunMonitorScript, either a new
+// report or an error is written to a provided channel, and a record of the run
+// is returned.
+func RunFetchMonitor(
+	monitor models.Monitor,
+	url string,
+	lastReport models.Report,
+	opts RunOptions,
+	result chan<- models.Report,
+	err chan<- error) models.Run {
+	run := models.NewRun(monitor)
+	allowed := AllowedRunTime(monitor, opts.GracePeriod)
+	client := &http.Client{Timeout: allowed}
+	response, getErr := client.Get(url)
+	if getErr != nil {
+		run.Finish(-1, "", 0)
+		if netErr, ok := getErr.(net.Error); ok && netErr.Timeout() {
+			timeoutErr := TimeoutError{monitor.ID(), allowed}
+			run.Fail(models.RunTimedOut, timeoutErr)
+			err <- timeoutErr
+			return run
+		}
+		run.Fail(models.RunFetchFailed, getErr)
+		err <- getErr
+		return run
+	}
+	defer response.Body.Close()
+	body, readErr := ioutil.ReadAll(io.LimitReader(response.Body, maxFetchBytes))
+	run.Finish(0, "", len(body))
+	if readErr != nil {
+		run.Fail(models.RunFetchFailed, readErr)
+		err <- readErr
+		return run
+	}
+	// Drain anything past the size limit so the connection can be reused.
+	io.Copy(ioutil.Discard, response.Body)
+	output := models.ReportOutput{
+		Version: models.ReportProtocolVersion,
+		State: map[string]interface{}{
+			"statusCode": response.StatusCode,
+		},
+	}
+	switch {
+	case isMissingStatus(response.StatusCode):
+		// A page that was already missing at the last check hasn't changed since.
+		if pageWasMissing(lastReport) {
+			output.Change = models.NoChange
+			output.Message = fmt.Sprintf("The site is still responding with status %d.", response.StatusCode)
+		} else {
+			output.Change = models.Deleted
+			output.Message = fmt.Sprintf("The site responded with status %d.", response.StatusCode)
+		}
+		output.Checksum = lastReport.Checksum()
+		output.Content = lastReport.Content()
+	case response.StatusCode < 200 || response.StatusCode > 299:
+		fetchErr := FetchError{url, response.StatusCode}
+		run.Fail(models.RunFetchFailed, fetchErr)
+		err <- fetchErr
+		return run
+	default:
+		checksum := sha256.Sum256(body)
+		output.Checksum = hex.EncodeToString(checksum[:])
+		if opts.Snapshots != nil {
+			// Losing a snapshot shouldn't cost us the report, so a failure to
+			// save one is only logged.
+			_, saveErr := opts.Snapshots.Save(body)
+			if saveErr != nil {
+				fmt.Println("Could not save snapshot of", url, saveErr)
+			}
+		}
+		output.Content = diff.ExtractText(string(body))
+		output.Change, output.Message = compareContent(lastReport, output, opts.Thresholds)
+		if pageWasMissing(lastReport) && output.Change < models.ContentChange {
+			output.Change = models.ContentChange
+			output.Message = "The site is responding again after being reported deleted."
+		}
+	}
+	result <- output.NextReport(monitor)
+	return run
+}
+
+// compareContent determines the significance of the change between a page as
+// it was in the last report and as it was just fetched. If the checksums differ
+// but the last report has no text to compare to, the page is known to have
+// changed but not by how much.
+func compareContent(
+	lastReport models.Report,
+	current models.ReportOutput,
+	thresholds diff.Thresholds) (models.Importance, string) {
+	if lastReport.Checksum() == "" {
+		return models.NoChange, "Recorded the page's checksum for the first time."
+	}
+	if lastReport.Checksum() == current.Checksum {
+		return models.NoChange, "The page has not changed."
+	}
+	if lastReport.Content() == "" {
+		return models.ContentChange, "The page's content has changed."
+	}
+	change := diff.Classify(lastReport.Content(), current.Content, thresholds)
+	if change == models.NoChange {
+		return change, "The page changed, but its text did not."
+	}
+	return change, fmt.Sprintf("The page's text has changed: %v.", change)
+}
+
+// isMissingStatus determines whether a response status means that the page
+// being monitored has been deleted.
+func isMissingStatus(status int) bool {
+	return status == http.StatusNotFound || status == http.StatusGone
+}
+
+// pageWasMissing determines whether a report records that the page was missing
+// when it was fetched. The status code saved in the report's state is a float
+// once the state has been read back from the database.
+func pageWasMissing(report models.Report) bool {
+	switch status := report.State()["statusCode"].(type) {
+	case int:
+		return isMissingStatus(status)
+	case float64:
+		return isMissingStatus(int(status))
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package tasks
+
+import (
+	"../diff"
+	"../models"
+	"../snapshots"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// fetchTestOptions are the options that fetch monitors are run with in tests.
+var fetchTestOptions = RunOptions{
+	GracePeriod:    2 * time.Second,
+	MaxStderrBytes: 16,
+	Thresholds:     diff.DefaultThresholds(),
+}
+
+// runFetch runs a fetch monitor against a URL and returns the report it
+// produced, failing the test if it produced an error instead.
+func runFetch(t *testing.T, url string, lastReport models.Report) models.Report {
+	monitor := models.NewMonitor(
+		models.Archiver{}, models.Request{}, models.FetchInterpreter, "", 0, 0)
+	resultOut := make(chan models.Report, 1)
+	errorOut := make(chan error, 1)
+	RunFetchMonitor(monitor, url, lastReport, fetchTestOptions, resultOut, errorOut)
+	select {
+	case r := <-resultOut:
+		return r
+	case e := <-errorOut:
+		t.Fatalf("expected not to get an error: %v", e)
+	}
+	return models.Report{}
+}
+
+func TestFetchDetectsChanges(t *testing.T) {
+	body := "first version"
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Write([]byte(body))
+	}))
+	defer server.Close()
+	first := runFetch(t, server.URL, models.NewReport(models.Monitor{}))
+	sum := sha256.Sum256([]byte(body))
+	if first.Checksum() != hex.EncodeToString(sum[:]) {
+		t.Errorf("expected checksum to be the SHA256 of the page body, got %s", first.Checksum())
+	}
+	if first.Change() != models.NoChange {
+		t.Errorf("expected the first fetch to report no change, got %v", first.Change())
+	}
+	second := runFetch(t, server.URL, first)
+	if second.Change() != models.NoChange {
+		t.Errorf("expected an unchanged page to report no change, got %v", second.Change())
+	}
+	body = "second version"
+	third := runFetch(t, server.URL, second)
+	if third.Change() != models.Rewritten {
+		t.Errorf("expected a page with half its words changed to be rewritten, got %v", third.Change())
+	}
+	body = "<p>second version</p>"
+	fourth := runFetch(t, server.URL, third)
+	if fourth.Change() != models.NoChange || fourth.Checksum() == third.Checksum() {
+		t.Errorf("expected a change to markup alone not to change the text, got %v", fourth.Change())
+	}
+}
+
+func TestFetchSavesSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "miru-snapshots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := snapshots.NewStore(dir)
+	opts := fetchTestOptions
+	opts.Snapshots = &store
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Write([]byte("<p>archive me</p>"))
+	}))
+	defer server.Close()
+	monitor := models.NewMonitor(
+		models.Archiver{}, models.Request{}, models.FetchInterpreter, "", 0, 0)
+	resultOut := make(chan models.Report, 1)
+	errorOut := make(chan error, 1)
+	RunFetchMonitor(monitor, server.URL, models.NewReport(monitor), opts, resultOut, errorOut)
+	select {
+	case r := <-resultOut:
+		info, statErr := store.Stat(r.Checksum())
+		if statErr != nil || info.Size() != int64(len("<p>archive me</p>")) {
+			t.Errorf("expected the page to be saved under the report's checksum, got %v", statErr)
+		}
+	case e := <-errorOut:
+		t.Errorf("expected not to get an error: %v", e)
+	}
+}
+
+func TestFetchDetectsDeletion(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	report := runFetch(t, server.URL, models.NewReport(models.Monitor{}))
+	if report.Change() != models.Deleted {
+		t.Errorf("expected a missing page to be reported as deleted, got %v", report.Change())
+	}
+}
+
+func TestFetchReportsDeletionOnce(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	first := runFetch(t, server.URL, models.NewReport(models.Monitor{}))
+	if first.Change() != models.Deleted {
+		t.Fatalf("expected the first 404 to be reported as deleted, got %v", first.Change())
+	}
+	last := first
+	for i := 0; i < 3; i++ {
+		last = runFetch(t, server.URL, last)
+		if last.Change() != models.NoChange {
+			t.Errorf("expected a page that is still missing to report no change on run %d, got %v", i+2, last.Change())
+		}
+	}
+	// The state is read back from the database as decoded JSON.
+	var state map[string]interface{}
+	encoded, _ := json.Marshal(last.State())
+	json.Unmarshal(encoded, &state)
+	last.SetState(state)
+	if again := runFetch(t, server.URL, last); again.Change() != models.NoChange {
+		t.Errorf("expected a missing page read back from the database to report no change, got %v", again.Change())
+	}
+}
+
+func TestFetchReportsRestoredPage(t *testing.T) {
+	missing := false
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if missing {
+			http.NotFound(res, req)
+			return
+		}
+		res.Write([]byte("<p>back again</p>"))
+	}))
+	defer server.Close()
+	first := runFetch(t, server.URL, models.NewReport(models.Monitor{}))
+	missing = true
+	deleted := runFetch(t, server.URL, first)
+	if deleted.Change() != models.Deleted {
+		t.Fatalf("expected the page to be reported deleted, got %v", deleted.Change())
+	}
+	missing = false
+	restored := runFetch(t, server.URL, deleted)
+	if restored.Change() < models.ContentChange {
+		t.Errorf("expected a page coming back after a 404 to be reported as changed, got %v", restored.Change())
+	}
+	if after := runFetch(t, server.URL, restored); after.Change() != models.NoChange {
+		t.Errorf("expected the restored page not to change again, got %v", after.Change())
+	}
+}
+
+func TestFetchServerErrorFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	monitor := models.NewMonitor(
+		models.Archiver{}, models.Request{}, models.FetchInterpreter, "", 0, 0)
+	resultOut := make(chan models.Report, 1)
+	errorOut := make(chan error, 1)
+	run := RunFetchMonitor(monitor, server.URL, models.NewReport(monitor), fetchTestOptions, resultOut, errorOut)
+	select {
+	case <-resultOut:
+		t.Errorf("expected not to get a result")
+	case e := <-errorOut:
+		if _, isFetchErr := e.(FetchError); !isFetchErr {
+			t.Errorf("expected to get a FetchError, got %v", e)
+		}
+	}
+	if run.Outcome() != models.RunFetchFailed {
+		t.Errorf("expected the run to be recorded as a failed fetch, got %v", run.Outcome())
+	}
+}
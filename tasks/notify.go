@@ -0,0 +1,78 @@
+package tasks
+
+import (
+	"../config"
+	"../models"
+	"../notify"
+
+	"database/sql"
+	"fmt"
+)
+
+// defaultReportEmailThreshold is the least significant change that subscribed
+// administrators are emailed about if the configuration does not specify one.
+const defaultReportEmailThreshold = models.Rewritten
+
+// NotifyOptions configures how administrators are emailed about changes.
+type NotifyOptions struct {
+	Sender    notify.Sender     // Sends the emails.
+	Threshold models.Importance // The least significant change to email about.
+}
+
+// NewNotifyOptions creates NotifyOptions from the application's configuration.
+func NewNotifyOptions(cfg *config.Config) NotifyOptions {
+	threshold := defaultReportEmailThreshold
+	if cfg.ReportEmailThreshold != nil {
+		threshold = models.Importance(*cfg.ReportEmailThreshold)
+	}
+	return NotifyOptions{
+		Sender:    notify.NewSender(cfg),
+		Threshold: threshold,
+	}
+}
+
+// notifySubscribers starts emailing the administrators subscribed to reports
+// about a newly saved report, if its change is significant enough. Emails are
+// sent in the background so that a slow mail server doesn't hold up monitors.
+func notifySubscribers(
+	db *sql.DB,
+	report models.Report,
+	url string,
+	opts NotifyOptions,
+	errors chan<- error) {
+	if report.Change() < opts.Threshold {
+		return
+	}
+	addresses, err := models.ListReportSubscribers(db)
+	if err != nil {
+		errors <- err
+		return
+	}
+	go func() {
+		for _, err := range EmailReport(opts.Sender, addresses, report, url) {
+			fmt.Println("Could not email report", err)
+		}
+	}()
+}
+
+// EmailReport sends a message about a report of a change to the site at url to
+// each address given, returning any errors encountered.
+func EmailReport(sender notify.Sender, addresses []string, report models.Report, url string) []error {
+	errs := []error{}
+	for _, address := range addresses {
+		msg, err := notify.SignificantChange(address, notify.ReportData{
+			ReportID:     report.ID(),
+			MonitorID:    report.CreatedBy(),
+			URL:          url,
+			Significance: report.Change().String(),
+			Message:      report.Message(),
+		})
+		if err == nil {
+			err = sender.Send(msg)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,89 @@
+package tasks
+
+import (
+	"../config"
+	"../models"
+	"../notify"
+
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmailReport(t *testing.T) {
+	sender := notify.NewMemorySender()
+	report := models.NewReport(models.Monitor{})
+	report.SetChange(models.Deleted)
+	report.SetMessage("the page is gone")
+	errs := EmailReport(sender, []string{"a@site.com", "b@site.com"}, report, "https://example.com")
+	if len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+	messages := sender.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("expected one message for each subscriber, got %d", len(messages))
+	}
+	if messages[1].To != "b@site.com" || !strings.Contains(messages[1].Subject, "https://example.com") {
+		t.Errorf("unexpected message %v", messages[1])
+	}
+	if !strings.Contains(messages[0].Body, "the page is gone") {
+		t.Errorf("expected the report's message in the body, got %q", messages[0].Body)
+	}
+}
+
+func TestNotifySubscribersFiltersBySignificance(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	for _, email := range []string{"admin@site.com", "former-admin@site.com"} {
+		archiver := models.NewArchiver(email, "hash")
+		if err := archiver.Save(db); err != nil {
+			t.Fatal(err)
+		}
+		subscription := models.NewReportSubscription(archiver)
+		if err := subscription.Save(db); err != nil {
+			t.Fatal(err)
+		}
+		if email == "admin@site.com" {
+			db.Exec("update archivers set is_administrator = 1 where id = $1", archiver.ID())
+		}
+	}
+	sender := notify.NewMemorySender()
+	opts := NotifyOptions{Sender: sender, Threshold: models.ContentChange}
+	errs := make(chan error, 2)
+	minor := models.NewReport(models.Monitor{})
+	minor.SetChange(models.MinorUpdate)
+	minor.SetMessage("a typo was fixed")
+	notifySubscribers(db, minor, "https://example.com", opts, errs)
+	significant := models.NewReport(models.Monitor{})
+	significant.SetChange(models.ContentChange)
+	significant.SetMessage("the page was updated")
+	notifySubscribers(db, significant, "https://example.com", opts, errs)
+	// Emails are sent in the background.
+	deadline := time.Now().Add(5 * time.Second)
+	for len(sender.Messages()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(errs)
+	for err := range errs {
+		t.Errorf("expected not to get an error, got %v", err)
+	}
+	messages := sender.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("expected only the administrator to be emailed about the significant change, got %d messages", len(messages))
+	}
+	if messages[0].To != "admin@site.com" || !strings.Contains(messages[0].Body, "the page was updated") {
+		t.Errorf("expected the administrator to be emailed about the significant change, got %v", messages[0])
+	}
+}
+
+func TestNewNotifyOptionsKeepsZeroThreshold(t *testing.T) {
+	unset := NewNotifyOptions(&config.Config{})
+	if unset.Threshold != defaultReportEmailThreshold {
+		t.Errorf("expected an unset threshold to default to %v, got %v", defaultReportEmailThreshold, unset.Threshold)
+	}
+	zero := uint(0)
+	every := NewNotifyOptions(&config.Config{ReportEmailThreshold: &zero})
+	if every.Threshold != models.NoChange {
+		t.Errorf("expected a threshold of 0 to email every report, got %v", every.Threshold)
+	}
+}
@@ -0,0 +1,169 @@
+package tasks
+
+import (
+	"../models"
+
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// truncatedNotice is appended to captured output that exceeded its size limit.
+const truncatedNotice string = "\n[output truncated by miru]"
+
+// redactedNotice replaces the values of secrets found in captured output.
+const redactedNotice string = "[secret redacted by miru]"
+
+// cappedBuffer is an io.Writer that keeps at most a fixed number of bytes
+// written to it and silently discards the rest, so that a misbehaving script
+// cannot exhaust the server's memory by writing endlessly.
+type cappedBuffer struct {
+	limit     int
+	buffer    bytes.Buffer
+	truncated bool
+}
+
+// newCappedBuffer constructs a cappedBuffer that will keep up to limit bytes.
+func newCappedBuffer(limit int) *cappedBuffer {
+	return &cappedBuffer{
+		limit:     limit,
+		truncated: false,
+	}
+}
+
+// Write stores as much of data as fits within the buffer's limit. It always
+// reports having written all of data so that the writer is never interrupted.
+func (b *cappedBuffer) Write(data []byte) (int, error) {
+	remaining := b.limit - b.buffer.Len()
+	if remaining < len(data) {
+		b.truncated = true
+		if remaining > 0 {
+			b.buffer.Write(data[:remaining])
+		}
+		return len(data), nil
+	}
+	b.buffer.Write(data)
+	return len(data), nil
+}
+
+// String gets the contents of the buffer, with a notice at the end if anything
+// had to be discarded.
+func (b *cappedBuffer) String() string {
+	if b.truncated {
+		return b.buffer.String() + truncatedNotice
+	}
+	return b.buffer.String()
+}
+
+// limitedBuffer is an io.Writer that keeps everything written to it up to a
+// fixed number of bytes. Anything past that is discarded, and the buffer's
+// Full channel is closed so that whatever is writing to it can be stopped.
+// A limit of zero allows any amount of output.
+type limitedBuffer struct {
+	limit  int
+	buffer bytes.Buffer
+	full   chan struct{}
+}
+
+// newLimitedBuffer constructs a limitedBuffer that will accept up to limit bytes.
+func newLimitedBuffer(limit int) *limitedBuffer {
+	return &limitedBuffer{
+		limit: limit,
+		full:  make(chan struct{}),
+	}
+}
+
+// Write stores data unless doing so would exceed the buffer's limit. It always
+// reports having written all of data so that the writer is not left blocked
+// before it can be stopped.
+func (b *limitedBuffer) Write(data []byte) (int, error) {
+	if b.Exceeded() {
+		return len(data), nil
+	}
+	if b.limit > 0 && b.buffer.Len()+len(data) > b.limit {
+		close(b.full)
+		return len(data), nil
+	}
+	return b.buffer.Write(data)
+}
+
+// Read reads from the contents of the buffer.
+func (b *limitedBuffer) Read(data []byte) (int, error) {
+	return b.buffer.Read(data)
+}
+
+// Len gets the number of bytes in the buffer that have not been read.
+func (b *limitedBuffer) Len() int {
+	return b.buffer.Len()
+}
+
+// Full produces a channel that is closed once the buffer's limit is exceeded.
+func (b *limitedBuffer) Full() <-chan struct{} {
+	return b.full
+}
+
+// Exceeded determines whether anything was written to the buffer past its limit.
+func (b *limitedBuffer) Exceeded() bool {
+	select {
+	case <-b.full:
+		return true
+	default:
+		return false
+	}
+}
+
+// redactReportOutput replaces the values of a script's secrets wherever they
+// appear in the message, state and content that it output, so that they
+// aren't saved in its report either.
+func redactReportOutput(output *models.ReportOutput, environment []string) {
+	if len(environment) == 0 {
+		return
+	}
+	output.Message = redactSecrets(output.Message, environment)
+	output.Content = redactSecrets(output.Content, environment)
+	for key, value := range output.State {
+		output.State[key] = redactStateValue(value, environment)
+	}
+}
+
+// redactStateValue redacts secrets from every string in a value decoded from
+// a report's state, including those nested in arrays and objects.
+func redactStateValue(value interface{}, environment []string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return redactSecrets(v, environment)
+	case []interface{}:
+		for i := range v {
+			v[i] = redactStateValue(v[i], environment)
+		}
+		return v
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = redactStateValue(v[key], environment)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// redactSecrets replaces the value of every variable in a script's environment,
+// written NAME=value, wherever it appears in output, so that a monitor's
+// secrets aren't saved with a record of its run if its script prints them.
+func redactSecrets(output string, environment []string) string {
+	values := []string{}
+	for _, variable := range environment {
+		parts := strings.SplitN(variable, "=", 2)
+		if len(parts) == 2 && parts[1] != "" {
+			values = append(values, parts[1])
+		}
+	}
+	// Longer values are replaced first, in case one secret contains another.
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+	for _, value := range values {
+		output = strings.Replace(output, value, redactedNotice, -1)
+	}
+	return output
+}
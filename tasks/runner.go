@@ -1,101 +1,226 @@
 package tasks
 
 import (
-	"encoding/json"
-	"fmt"
-	"io"
-
+	"../config"
+	"../diff"
+	"../interpreters"
 	"../models"
+	"../snapshots"
 
-	"errors"
+	"fmt"
 	"os"
 	"os/exec"
+	"strings"
+	"syscall"
+	"time"
 )
 
+// defaultMaxStderrBytes is the amount of a script's stderr output to keep if
+// the configuration does not specify a limit.
+const defaultMaxStderrBytes int = 64 * 1024
+
+// RunOptions configures the limits that monitor scripts are run with.
+type RunOptions struct {
+	GracePeriod    time.Duration    // Time a script may run past its expected run time.
+	MaxStderrBytes int              // The most output from a script's stderr to keep.
+	Thresholds     diff.Thresholds  // Used to score changes to page content.
+	Snapshots      *snapshots.Store // Where fetched pages are saved, if anywhere.
+	Sandbox        Sandbox          // The restrictions scripts are run under.
+	Interpreters   interpreters.Registry
+	Environment    []string // Variables to run a script with, written NAME=value.
+}
+
+// NewRunOptions creates RunOptions from the application's configuration.
+func NewRunOptions(cfg *config.Config) RunOptions {
+	maxStderr := int(cfg.MaxStderrBytes)
+	if maxStderr == 0 {
+		maxStderr = defaultMaxStderrBytes
+	}
+	thresholds := diff.DefaultThresholds()
+	if cfg.ContentChangeThreshold > 0 {
+		thresholds.ContentChange = cfg.ContentChangeThreshold
+	}
+	if cfg.RewrittenThreshold > 0 {
+		thresholds.Rewritten = cfg.RewrittenThreshold
+	}
+	store := snapshots.NewStore(cfg.SnapshotDir)
+	// Problems with the interpreters configured are reported when miru starts,
+	// and monitors that use them fail to run.
+	registry, _ := interpreters.Load(cfg)
+	return RunOptions{
+		GracePeriod:    time.Duration(cfg.ScriptGracePeriod) * time.Second,
+		MaxStderrBytes: maxStderr,
+		Thresholds:     thresholds,
+		Snapshots:      &store,
+		Sandbox:        NewSandbox(cfg),
+		Interpreters:   registry,
+	}
+}
+
+// TimeoutError is produced when a monitor script runs for longer than its
+// expected run time plus the grace period allowed to it, and had to be killed.
+type TimeoutError struct {
+	MonitorID int
+	Allowed   time.Duration
+}
+
+// Error produces a message explaining which monitor's script timed out.
+func (e TimeoutError) Error() string {
+	return fmt.Sprintf("script for monitor #%d timed out after %v", e.MonitorID, e.Allowed)
+}
+
+// AllowedRunTime computes the amount of time a monitor's script is allowed to
+// run for before it will be killed, which is the monitor's expected run time
+// plus some grace period.
+func AllowedRunTime(monitor models.Monitor, gracePeriod time.Duration) time.Duration {
+	return monitor.ExpectedRunTime() + gracePeriod
+}
+
 // RunMonitorScript executes a monitor script in a subprocess and writes either
 // a successful result or an error to a provided channel.
+// If the script runs for longer than its expected run time plus the grace
+// period in opts, the script's entire process group is killed and a
+// TimeoutError is produced.
+// Scripts are run in the sandbox described by opts, and a SandboxViolation is
+// produced if a script is stopped for exceeding one of its limits.
+// A record of the run, including what the script wrote to stderr, is returned
+// once the script has finished, whether or not it succeeded.
 func RunMonitorScript(
 	monitor models.Monitor,
 	lastReport models.Report,
+	opts RunOptions,
 	result chan<- models.Report,
-	err chan<- error) {
-	// Determine which interpreter to run the script with.
-	// This is a bit verbose, but it allows us to control input to prevent
-	// someone trying to supply a command that we don't actually want to run.
-	cmdName := "python"
-	switch monitor.Interpreter() {
-	case models.PythonInterpreter:
-		cmdName = "python"
-	case models.RubyInterpreter:
-		cmdName = "ruby"
-	case models.PerlInterpreter:
-		cmdName = "perl"
-	default:
-		err <- errors.New("unknown interpreter type")
-		return
+	err chan<- error) models.Run {
+	run := models.NewRun(monitor)
+	// Only the interpreters configured can be run, so that nobody can supply a
+	// command that we don't actually want to run.
+	interpreter, findErr := opts.Interpreters.Find(string(monitor.Interpreter()))
+	if findErr != nil {
+		run.Finish(-1, "", 0)
+		run.Fail(models.RunUnknownInterpreter, findErr)
+		err <- findErr
+		return run
 	}
-	fmt.Println("Determined need to run interpreter", cmdName)
-	// We have to run the command in another goroutine because the input part
-	// of the pipe has to have started reading by the time the output part
-	// starts writing, or else we get a deadlock.
-	pipeIn, pipeOut := io.Pipe()
-	defer pipeIn.Close()
-	defer pipeOut.Close()
+	fmt.Println("Determined need to run interpreter", interpreter.Name())
+	// The last report is written to the script's stdin and its output is
+	// buffered in memory so that nothing blocks on a pipe if the script dies
+	// or never reads its input.
+	// Scripts run in their own process group so that any processes they spawn
+	// can be killed along with them.
+	cmd, cleanup, sandboxErr := opts.Sandbox.Command(
+		interpreter.Path(), interpreter.Args(monitor.ScriptPath()))
+	if sandboxErr != nil {
+		fmt.Println("Could not prepare sandbox", sandboxErr)
+		run.Finish(-1, "", 0)
+		run.Fail(models.RunStartFailed, sandboxErr)
+		err <- sandboxErr
+		return run
+	}
+	defer cleanup()
+	maxOutput := 0
+	if opts.Sandbox.Enabled {
+		maxOutput = int(opts.Sandbox.OutputBytes)
+	}
+	stdout := newLimitedBuffer(maxOutput)
+	stderr := newCappedBuffer(opts.MaxStderrBytes)
+	// A monitor's secrets are given to its own script only.
+	if len(opts.Environment) > 0 {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, opts.Environment...)
+	}
+	cmd.Stdin = strings.NewReader(lastReport.String())
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	startErr := cmd.Start()
+	if startErr != nil {
+		fmt.Println("start error", startErr)
+		run.Finish(-1, "", 0)
+		run.Fail(models.RunStartFailed, startErr)
+		err <- startErr
+		return run
+	}
+	finished := make(chan error, 1)
 	go func() {
-		cmd := exec.Command(cmdName, monitor.ScriptPath())
-		cmd.Stdout = pipeOut
-		cmd.Stderr = os.Stderr
-		fmt.Println("Running script with lastReport", lastReport)
-		stdin, getInputErr := cmd.StdinPipe()
-		// TODO - This isn't actually taking care of stdin problems.
-		//        We should be timing out scripts that we can't write to soon.
-		if getInputErr != nil {
-			fmt.Println("Couldn't get stdin for script", getInputErr)
-			err <- getInputErr
+		finished <- cmd.Wait()
+	}()
+	allowed := AllowedRunTime(monitor, opts.GracePeriod)
+	timer := time.NewTimer(allowed)
+	defer timer.Stop()
+	select {
+	case runErr := <-finished:
+		run.Finish(exitCode(cmd), redactSecrets(stderr.String(), opts.Environment), stdout.Len())
+		if limit, violated := opts.Sandbox.violation(cmd, stdout, stderr); violated {
+			violation := SandboxViolation{monitor.ID(), limit}
+			run.Fail(models.RunSandboxViolation, violation)
+			err <- violation
+			return run
 		}
-		go func() {
-			defer stdin.Close()
-			encoded := lastReport.String()
-			fmt.Println("encoded lastReport to", encoded)
-			_, err := io.WriteString(stdin, encoded)
-			fmt.Println("Error writing to script:", err)
-		}()
-		startErr := cmd.Run()
-		if startErr != nil {
-			fmt.Println("start error", startErr)
-			err <- startErr
+		if runErr != nil {
+			fmt.Println("run error", runErr)
+			run.Fail(models.RunNonZeroExit, runErr)
+			err <- runErr
+			return run
 		}
-		fmt.Println("Finished running")
-	}()
-	// Decode the input into a models.Report struct or else produce an error.
-	data := make(map[string]interface{})
-	decoder := json.NewDecoder(pipeIn)
-	decodeErr := decoder.Decode(&data)
+	case <-timer.C:
+		// A negative PID signals every process in the group.
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-finished
+		run.Finish(-1, redactSecrets(stderr.String(), opts.Environment), stdout.Len())
+		timeoutErr := TimeoutError{monitor.ID(), allowed}
+		run.Fail(models.RunTimedOut, timeoutErr)
+		err <- timeoutErr
+		return run
+	case <-stdout.Full():
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-finished
+		run.Finish(-1, redactSecrets(stderr.String(), opts.Environment), stdout.Len())
+		violation := SandboxViolation{monitor.ID(), outputLimit}
+		run.Fail(models.RunSandboxViolation, violation)
+		err <- violation
+		return run
+	}
+	// Decode the output into a new models.Report or else produce an error
+	// describing what is wrong with it.
+	output, decodeErr := models.DecodeReportOutput(stdout)
 	if decodeErr != nil {
 		fmt.Println("Failed to decode", decodeErr)
+		run.Fail(models.RunBadJSON, decodeErr)
 		err <- decodeErr
-	} else {
-		fmt.Println("Successfully decoded data", data)
-		changeSig, found1 := data["lastChangeSignificance"]
-		message, found2 := data["message"]
-		checksum, found3 := data["checksum"]
-		newState, found4 := data["state"]
-		if !found1 || !found2 || !found3 || !found4 {
-			fmt.Println("Didn't find all expected fields")
-			fmt.Println(found1, found2, found3, found4)
-			err <- errors.New("script output invalid data")
-			return
-		}
-		switch changeSig.(type) {
-		case uint:
-			lastReport.SetChange(models.Importance(changeSig.(uint)))
-		case float64:
-			lastReport.SetChange(models.Importance(uint(changeSig.(float64))))
-		}
-		lastReport.SetMessage(message.(string))
-		lastReport.SetChecksum(checksum.(string))
-		lastReport.SetState(newState.(map[string]interface{}))
-		fmt.Println("Put together report with message", lastReport.Message())
-		result <- lastReport
+		return run
+	}
+	redactReportOutput(&output, opts.Environment)
+	// Scripts can leave it to us to score the change to the content they found.
+	if output.ChangeOmitted {
+		output.Change = scoreContent(lastReport, output.Content, opts.Thresholds)
+	}
+	report := output.NextReport(monitor)
+	fmt.Println("Put together report with message", report.Message())
+	result <- report
+	return run
+}
+
+// scoreContent classifies the change between the content in a monitor's last
+// report and the content it just found. If the last report has no content,
+// such as on a monitor's first run, there is nothing to compare against and
+// no change is reported.
+func scoreContent(lastReport models.Report, content string, thresholds diff.Thresholds) models.Importance {
+	if lastReport.Content() == "" {
+		return models.NoChange
+	}
+	return diff.Classify(lastReport.Content(), content, thresholds)
+}
+
+// exitCode gets the status code that a finished command exited with, or -1 if
+// it was killed by a signal.
+func exitCode(cmd *exec.Cmd) int {
+	if cmd.ProcessState == nil {
+		return -1
+	}
+	status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if !ok || !status.Exited() {
+		return -1
 	}
+	return status.ExitStatus()
 }
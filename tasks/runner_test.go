@@ -1,12 +1,32 @@
 package tasks
 
 import (
+	"../diff"
+	"../interpreters"
 	"../models"
 
+	"bytes"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"strconv"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 )
 
+// testGracePeriod is the amount of time test scripts are allowed to run for.
+const testGracePeriod = 5 * time.Second
+
+// testOptions are the options that test scripts are run with.
+var testOptions = RunOptions{
+	GracePeriod:    testGracePeriod,
+	MaxStderrBytes: 16,
+	Thresholds:     diff.DefaultThresholds(),
+	Interpreters:   interpreters.Default(),
+}
+
 const testPythonScript = `
 print('{"changeSignificance": 0, "message": "hello world", "checksum": "", "state": {}}')
 exit(0)
@@ -23,6 +43,41 @@ print $json;
 exit 0;
 `
 
+const testPythonSleepScript = `
+import time
+time.sleep(30)
+`
+
+const testPythonChildScript = `
+import subprocess, sys, time
+child = subprocess.Popen(["sleep", "30"])
+sys.stderr.write(str(child.pid))
+sys.stderr.flush()
+time.sleep(30)
+`
+
+const testPythonBadJSONScript = `
+print("this is not a report")
+`
+
+const testPythonStderrScript = `
+import sys
+sys.stderr.write("debugging output that is far too long to keep")
+print('{"changeSignificance": 0, "message": "hello world", "checksum": "", "state": {}}')
+`
+
+const testPythonContentScript = `
+import json, sys
+last = json.load(sys.stdin)
+print(json.dumps({
+    "version": 2,
+    "content": "the page says goodbye",
+    "message": "scored by miru",
+    "checksum": "",
+    "state": {},
+}))
+`
+
 const testPythonErrorScript = `
 import sys
 print("hi")
@@ -42,22 +97,42 @@ func TestMain(m *testing.M) {
 	f4, _ := os.Create("testerror.py")
 	f4.Write([]byte(testPythonErrorScript))
 	defer f4.Close()
+	f7, _ := os.Create("testcontent.py")
+	f7.Write([]byte(testPythonContentScript))
+	defer f7.Close()
+	f5, _ := os.Create("testsleep.py")
+	f5.Write([]byte(testPythonSleepScript))
+	defer f5.Close()
+	f8, _ := os.Create("testchild.py")
+	f8.Write([]byte(testPythonChildScript))
+	defer f8.Close()
+	f9, _ := os.Create("testbadjson.py")
+	f9.Write([]byte(testPythonBadJSONScript))
+	defer f9.Close()
+	f6, _ := os.Create("teststderr.py")
+	f6.Write([]byte(testPythonStderrScript))
+	defer f6.Close()
 	exitCode := m.Run()
 	os.Remove("testpython.py")
 	os.Remove("testruby.rb")
 	os.Remove("testperl.pl")
 	os.Remove("testerror.py")
+	os.Remove("testsleep.py")
+	os.Remove("teststderr.py")
+	os.Remove("testcontent.py")
+	os.Remove("testchild.py")
+	os.Remove("testbadjson.py")
 	os.Exit(exitCode)
 }
 
 func TestRunPython(t *testing.T) {
 	t.Log("Running python script")
 	monitor := models.NewMonitor(
-		models.Archiver{}, models.Request{}, models.PythonInterpreter, "testpython.py", 0, 0)
+		models.Archiver{}, models.Request{}, models.Interpreter("python"), "testpython.py", 0, 0)
 	lastReport := models.NewReport(monitor)
 	resultOut := make(chan models.Report, 1)
 	errorOut := make(chan error, 1)
-	RunMonitorScript(monitor, lastReport, resultOut, errorOut)
+	RunMonitorScript(monitor, lastReport, testOptions, resultOut, errorOut)
 	select {
 	case r := <-resultOut:
 		if r.Message() != "hello world" {
@@ -71,11 +146,11 @@ func TestRunPython(t *testing.T) {
 func TestRunRuby(t *testing.T) {
 	t.Log("Running Ruby script")
 	monitor := models.NewMonitor(
-		models.Archiver{}, models.Request{}, models.RubyInterpreter, "testruby.rb", 0, 0)
+		models.Archiver{}, models.Request{}, models.Interpreter("ruby"), "testruby.rb", 0, 0)
 	lastReport := models.NewReport(monitor)
 	resultOut := make(chan models.Report, 1)
 	errorOut := make(chan error, 1)
-	RunMonitorScript(monitor, lastReport, resultOut, errorOut)
+	RunMonitorScript(monitor, lastReport, testOptions, resultOut, errorOut)
 	select {
 	case r := <-resultOut:
 		if r.Message() != "hello world" {
@@ -88,11 +163,11 @@ func TestRunRuby(t *testing.T) {
 
 func TestRunPerl(t *testing.T) {
 	monitor := models.NewMonitor(
-		models.Archiver{}, models.Request{}, models.PerlInterpreter, "testperl.pl", 0, 0)
+		models.Archiver{}, models.Request{}, models.Interpreter("perl"), "testperl.pl", 0, 0)
 	lastReport := models.NewReport(monitor)
 	resultOut := make(chan models.Report, 1)
 	errorOut := make(chan error, 1)
-	RunMonitorScript(monitor, lastReport, resultOut, errorOut)
+	RunMonitorScript(monitor, lastReport, testOptions, resultOut, errorOut)
 	select {
 	case r := <-resultOut:
 		if r.Message() != "hello world" {
@@ -109,7 +184,7 @@ func TestRunUnknownFails(t *testing.T) {
 	lastReport := models.NewReport(monitor)
 	resultOut := make(chan models.Report, 1)
 	errorOut := make(chan error, 1)
-	RunMonitorScript(monitor, lastReport, resultOut, errorOut)
+	RunMonitorScript(monitor, lastReport, testOptions, resultOut, errorOut)
 	select {
 	case <-resultOut:
 		t.Errorf("expected not to get a result")
@@ -120,11 +195,11 @@ func TestRunUnknownFails(t *testing.T) {
 
 func TestRunFailProducesError(t *testing.T) {
 	monitor := models.NewMonitor(
-		models.Archiver{}, models.Request{}, models.PythonInterpreter, "testerror.py", 0, 0)
+		models.Archiver{}, models.Request{}, models.Interpreter("python"), "testerror.py", 0, 0)
 	lastReport := models.NewReport(monitor)
 	resultOut := make(chan models.Report, 1)
 	errorOut := make(chan error, 1)
-	RunMonitorScript(monitor, lastReport, resultOut, errorOut)
+	RunMonitorScript(monitor, lastReport, testOptions, resultOut, errorOut)
 	select {
 	case <-resultOut:
 		t.Errorf("expected not to get a result")
@@ -132,3 +207,146 @@ func TestRunFailProducesError(t *testing.T) {
 		t.Logf("got expected error %v", e)
 	}
 }
+
+func TestRunRecordsOutcome(t *testing.T) {
+	cases := []struct {
+		interpreter string
+		script      string
+		outcome     models.RunOutcome
+		exitCode    int
+	}{
+		{"python", "testpython.py", models.RunSucceeded, 0},
+		{"python", "testerror.py", models.RunNonZeroExit, 1},
+		{"python", "testbadjson.py", models.RunBadJSON, 0},
+		{"unknown", "testunknown", models.RunUnknownInterpreter, -1},
+	}
+	for _, c := range cases {
+		monitor := models.NewMonitor(
+			models.Archiver{}, models.Request{}, models.Interpreter(c.interpreter), c.script, 0, 0)
+		run := RunMonitorScript(monitor, models.NewReport(monitor), testOptions,
+			make(chan models.Report, 1), make(chan error, 1))
+		if run.Outcome() != c.outcome || run.ExitCode() != c.exitCode {
+			t.Errorf("expected %s to be recorded as %v with exit code %d, got %v with %d",
+				c.script, c.outcome, c.exitCode, run.Outcome(), run.ExitCode())
+		}
+		if c.outcome == models.RunSucceeded && (run.Error() != "" || run.StdoutSize() == 0) {
+			t.Errorf("expected a successful run to record its output and no error, got %d bytes and %q",
+				run.StdoutSize(), run.Error())
+		}
+		if c.outcome != models.RunSucceeded && run.Error() == "" {
+			t.Errorf("expected the error that failed %s to be recorded", c.script)
+		}
+		if run.FinishedAt().Before(run.StartedAt()) {
+			t.Errorf("expected %s to finish after it started", c.script)
+		}
+	}
+}
+
+func TestRunTimeoutKillsScript(t *testing.T) {
+	monitor := models.NewMonitor(
+		models.Archiver{}, models.Request{}, models.Interpreter("python"), "testsleep.py", 0, 0)
+	lastReport := models.NewReport(monitor)
+	resultOut := make(chan models.Report, 1)
+	errorOut := make(chan error, 1)
+	started := time.Now()
+	opts := testOptions
+	opts.GracePeriod = 500 * time.Millisecond
+	RunMonitorScript(monitor, lastReport, opts, resultOut, errorOut)
+	if time.Since(started) > testGracePeriod {
+		t.Errorf("expected script to be killed shortly after its allowed run time")
+	}
+	select {
+	case <-resultOut:
+		t.Errorf("expected not to get a result")
+	case e := <-errorOut:
+		if _, isTimeout := e.(TimeoutError); !isTimeout {
+			t.Errorf("expected to get a TimeoutError, got %v", e)
+		}
+	}
+}
+
+func TestRunTimeoutKillsChildProcesses(t *testing.T) {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("cannot check whether processes are running without /proc")
+	}
+	monitor := models.NewMonitor(
+		models.Archiver{}, models.Request{}, models.Interpreter("python"), "testchild.py", 0, 0)
+	opts := testOptions
+	opts.GracePeriod = 500 * time.Millisecond
+	started := time.Now()
+	run := RunMonitorScript(monitor, models.NewReport(monitor), opts,
+		make(chan models.Report, 1), make(chan error, 1))
+	// A child left running would hold the script's output open until it exits.
+	if time.Since(started) > testGracePeriod {
+		t.Errorf("expected the script and its child to be stopped shortly after its allowed run time")
+	}
+	if run.Outcome() != models.RunTimedOut {
+		t.Fatalf("expected the script to time out, got %v: %s", run.Outcome(), run.Stderr())
+	}
+	childPID, err := strconv.Atoi(run.Stderr())
+	if err != nil {
+		t.Fatalf("expected the script to write its child's PID to stderr, got %q", run.Stderr())
+	}
+	// The child is reparented when the script is killed, so it may take a
+	// moment to be reaped after it is killed too.
+	deadline := time.Now().Add(2 * time.Second)
+	for isRunning(childPID) {
+		if time.Now().After(deadline) {
+			syscall.Kill(childPID, syscall.SIGKILL)
+			t.Fatalf("expected the script's child process %d to be killed with it", childPID)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// isRunning determines whether a process exists and hasn't exited, which
+// zombie processes waiting to be reaped have.
+func isRunning(pid int) bool {
+	stat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	// The state follows the command name, which is in parentheses.
+	fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+func TestRunCapturesStderr(t *testing.T) {
+	monitor := models.NewMonitor(
+		models.Archiver{}, models.Request{}, models.Interpreter("python"), "teststderr.py", 0, 0)
+	lastReport := models.NewReport(monitor)
+	resultOut := make(chan models.Report, 1)
+	errorOut := make(chan error, 1)
+	run := RunMonitorScript(monitor, lastReport, testOptions, resultOut, errorOut)
+	expected := "debugging output" + truncatedNotice
+	if run.Stderr() != expected {
+		t.Errorf("expected stderr to be captured and truncated to %q, got %q", expected, run.Stderr())
+	}
+}
+
+func TestRunScoresContent(t *testing.T) {
+	monitor := models.NewMonitor(
+		models.Archiver{}, models.Request{}, models.Interpreter("python"), "testcontent.py", 0, 0)
+	resultOut := make(chan models.Report, 1)
+	errorOut := make(chan error, 1)
+	RunMonitorScript(monitor, models.NewReport(monitor), testOptions, resultOut, errorOut)
+	var first models.Report
+	select {
+	case first = <-resultOut:
+		if first.Change() != models.NoChange || first.Content() != "the page says goodbye" {
+			t.Errorf("expected content to be saved with no change on the first run")
+		}
+	case e := <-errorOut:
+		t.Fatalf("expected not to get an error: %v", e)
+	}
+	previous := models.ReportOutput{Content: "the page says hello", State: map[string]interface{}{}}
+	RunMonitorScript(monitor, previous.NextReport(monitor), testOptions, resultOut, errorOut)
+	select {
+	case r := <-resultOut:
+		if r.Change() != models.ContentChange {
+			t.Errorf("expected miru to score the change to the content, got %v", r.Change())
+		}
+	case e := <-errorOut:
+		t.Errorf("expected not to get an error: %v", e)
+	}
+}
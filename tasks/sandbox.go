@@ -0,0 +1,332 @@
+package tasks
+
+import (
+	"../config"
+
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Limits that scripts are run with if the configuration does not specify them.
+const (
+	defaultScriptCPUSeconds  uint64 = 60
+	defaultScriptMemoryBytes uint64 = 1024 * 1024 * 1024
+	defaultScriptOpenFiles   uint64 = 64
+	defaultMaxOutputBytes    uint64 = 1024 * 1024
+)
+
+// sandboxPath lists the only directories that sandboxed scripts can find
+// programs in.
+const sandboxPath string = "/usr/local/bin:/usr/bin:/bin"
+
+// errNoSandboxUser is produced when the sandbox is enabled without a user to
+// run scripts as. Scripts are never run as miru's own user in the sandbox,
+// since that user can read miru's database, secrets and other scripts.
+var errNoSandboxUser = errors.New(
+	"the sandbox needs sandboxUser set to a dedicated unprivileged user to run scripts as")
+
+// Sandbox describes the restrictions that monitor scripts are run under.
+// When it is enabled, each run gets a private temporary directory holding a
+// read-only copy of the script, an environment containing nothing of miru's,
+// and limits on the resources it may use, and the script runs as a dedicated
+// unprivileged user.
+type Sandbox struct {
+	Enabled     bool
+	User        string // The unprivileged user to run scripts as.
+	Namespaces  bool   // Whether to run scripts in new Linux namespaces.
+	TempDir     string // Where each run's private directory is created.
+	CPUSeconds  uint64
+	MemoryBytes uint64
+	OpenFiles   uint64
+	OutputBytes uint64 // The most a script may write to stdout or to a file.
+}
+
+// NewSandbox creates a Sandbox from the application's configuration.
+func NewSandbox(cfg *config.Config) Sandbox {
+	s := Sandbox{
+		Enabled:     !cfg.DisableSandbox,
+		User:        cfg.SandboxUser,
+		Namespaces:  cfg.SandboxNamespaces,
+		TempDir:     cfg.SandboxTempDir,
+		CPUSeconds:  uint64(cfg.ScriptCPUSeconds),
+		MemoryBytes: uint64(cfg.ScriptMemoryBytes),
+		OpenFiles:   uint64(cfg.ScriptOpenFiles),
+		OutputBytes: uint64(cfg.MaxOutputBytes),
+	}
+	if s.CPUSeconds == 0 {
+		s.CPUSeconds = defaultScriptCPUSeconds
+	}
+	if s.MemoryBytes == 0 {
+		s.MemoryBytes = defaultScriptMemoryBytes
+	}
+	if s.OpenFiles == 0 {
+		s.OpenFiles = defaultScriptOpenFiles
+	}
+	if s.OutputBytes == 0 {
+		s.OutputBytes = defaultMaxOutputBytes
+	}
+	return s
+}
+
+// SandboxViolation is produced when a monitor script is stopped for exceeding
+// one of the limits imposed on it by the sandbox.
+type SandboxViolation struct {
+	MonitorID int
+	Limit     string
+}
+
+// Error produces a message explaining which limit a monitor's script exceeded.
+func (e SandboxViolation) Error() string {
+	return fmt.Sprintf("script for monitor #%d exceeded its %s limit", e.MonitorID, e.Limit)
+}
+
+// Names of the limits that a SandboxViolation can describe.
+const (
+	cpuLimit       = "CPU time"
+	memoryLimit    = "memory"
+	openFilesLimit = "open files"
+	outputLimit    = "output size"
+)
+
+// limitMessages are what the interpreters print as their last words when they
+// fail because of the memory or open files limits, which, unlike the others,
+// don't get a script killed by a signal.
+var limitMessages = map[string]string{
+	"MemoryError":               memoryLimit,
+	"Out of memory!":            memoryLimit,
+	"failed to allocate memory": memoryLimit,
+	"Cannot allocate memory":    memoryLimit,
+	"Too many open files":       openFilesLimit,
+}
+
+// Check makes sure that scripts can be run in the sandbox, if it is enabled,
+// which requires a dedicated user other than miru's own to run them as, and
+// miru to be allowed to switch to that user.
+func (s Sandbox) Check() error {
+	if !s.Enabled {
+		return nil
+	}
+	cred, err := s.credential()
+	if err != nil {
+		return err
+	}
+	attr, err := s.sysProcAttr(cred)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("/bin/sh", "-c", "exit 0")
+	cmd.Dir = "/"
+	cmd.Env = []string{"PATH=" + sandboxPath}
+	cmd.SysProcAttr = attr
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("could not run a command as %s, which needs miru to run as root: %v", s.User, err)
+	}
+	return nil
+}
+
+// Command prepares a command that runs a script with an interpreter under the
+// sandbox's restrictions. The path to the script must be the last of the
+// interpreter's arguments. The cleanup function returned removes the run's
+// private directory and must be called once the command has finished.
+// If the sandbox is disabled, the script is run as miru with nothing but its
+// own process group.
+func (s Sandbox) Command(interpreter string, args []string) (*exec.Cmd, func(), error) {
+	if !s.Enabled {
+		cmd := exec.Command(interpreter, args...)
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		return cmd, func() {}, nil
+	}
+	// The interpreter is found before the environment is cleared so that it
+	// can be run from wherever miru finds it.
+	interpreterPath, err := exec.LookPath(interpreter)
+	if err != nil {
+		return nil, nil, err
+	}
+	cred, err := s.credential()
+	if err != nil {
+		return nil, nil, err
+	}
+	runDir, err := ioutil.TempDir(s.TempDir, "miru-run-")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		// The script's directory has to be writable again for its copy of
+		// the script to be removed.
+		os.Chmod(filepath.Join(runDir, "script"), 0755)
+		os.RemoveAll(runDir)
+	}
+	scriptPath := args[len(args)-1]
+	scriptCopy, tempDir, err := prepareRunDir(runDir, scriptPath, cred)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	// The limits are applied by a shell that then replaces itself with the
+	// interpreter, so that they are in place before the script starts.
+	// The soft CPU limit is a second short of the hard one so that the script
+	// is sent SIGXCPU rather than being killed outright, except when it runs
+	// as the init process of a PID namespace, which ignores SIGXCPU.
+	limits := strings.Join([]string{
+		fmt.Sprintf("ulimit -S -t %d", s.CPUSeconds),
+		fmt.Sprintf("ulimit -H -t %d", s.CPUSeconds+1),
+		fmt.Sprintf("ulimit -v %d", s.MemoryBytes/1024),
+		fmt.Sprintf("ulimit -n %d", s.OpenFiles),
+		fmt.Sprintf("ulimit -f %d", s.OutputBytes/512),
+		`exec "$0" "$@"`,
+	}, " && ")
+	shellArgs := append([]string{"-c", limits, interpreterPath}, args[:len(args)-1]...)
+	cmd := exec.Command("/bin/sh", append(shellArgs, scriptCopy)...)
+	cmd.Dir = tempDir
+	cmd.Env = []string{
+		"PATH=" + sandboxPath,
+		"HOME=" + tempDir,
+		"TMPDIR=" + tempDir,
+		"LANG=C.UTF-8",
+	}
+	cmd.SysProcAttr, err = s.sysProcAttr(cred)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return cmd, cleanup, nil
+}
+
+// credential looks up the user that scripts should be run as, which must be
+// neither root nor the user that miru runs as.
+func (s Sandbox) credential() (*syscall.Credential, error) {
+	name := s.User
+	if name == "" {
+		return nil, errNoSandboxUser
+	}
+	account, err := user.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := strconv.ParseUint(account.Uid, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	gid, err := strconv.ParseUint(account.Gid, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	if uid == 0 {
+		return nil, fmt.Errorf("cannot run scripts as %s, who is root", name)
+	}
+	if uid == uint64(os.Geteuid()) {
+		return nil, fmt.Errorf("cannot run scripts as %s, who miru is running as", name)
+	}
+	return &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), Groups: []uint32{}}, nil
+}
+
+// prepareRunDir lays out the private directory for a run. The script is
+// copied into a directory owned by miru that the script's user cannot write
+// to, and a separate directory that only the script's user can use is made to
+// run it in.
+// The paths of the copy of the script and of the script's own directory are
+// returned.
+func prepareRunDir(runDir, scriptPath string, cred *syscall.Credential) (string, string, error) {
+	err := os.Chmod(runDir, 0711)
+	if err != nil {
+		return "", "", err
+	}
+	scriptDir := filepath.Join(runDir, "script")
+	tempDir := filepath.Join(runDir, "tmp")
+	err = os.Mkdir(scriptDir, 0755)
+	if err != nil {
+		return "", "", err
+	}
+	err = os.Mkdir(tempDir, 0700)
+	if err != nil {
+		return "", "", err
+	}
+	err = os.Chown(tempDir, int(cred.Uid), int(cred.Gid))
+	if err != nil {
+		return "", "", err
+	}
+	scriptCopy := filepath.Join(scriptDir, filepath.Base(scriptPath))
+	err = copyFile(scriptPath, scriptCopy, 0444)
+	if err != nil {
+		return "", "", err
+	}
+	err = os.Chmod(scriptDir, 0555)
+	if err != nil {
+		return "", "", err
+	}
+	return scriptCopy, tempDir, nil
+}
+
+// copyFile copies the contents of a file to a new file with the given mode.
+func copyFile(from, to string, mode os.FileMode) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	closeErr := out.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// violation determines which of the sandbox's limits, if any, a script that
+// has finished running exceeded. The CPU time and output size limits are
+// recognized by the signals the kernel kills scripts with, or by the output
+// miru cut off. Running out of memory or open files only makes a script fail,
+// so those limits are recognized by the interpreter's error message, but only
+// if it is the last thing a script that exited with an error wrote.
+func (s Sandbox) violation(cmd *exec.Cmd, stdout *limitedBuffer, stderr *cappedBuffer) (string, bool) {
+	if !s.Enabled || cmd.ProcessState == nil {
+		return "", false
+	}
+	if stdout.Exceeded() {
+		return outputLimit, true
+	}
+	status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if !ok {
+		return "", false
+	}
+	if status.Signaled() {
+		cpuTime := cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime()
+		switch status.Signal() {
+		case syscall.SIGXCPU:
+			return cpuLimit, true
+		case syscall.SIGKILL:
+			if cpuTime >= time.Duration(s.CPUSeconds)*time.Second {
+				return cpuLimit, true
+			}
+		case syscall.SIGXFSZ:
+			return outputLimit, true
+		}
+		return "", false
+	}
+	if !status.Exited() || status.ExitStatus() == 0 || stderr.truncated {
+		return "", false
+	}
+	lines := strings.Split(strings.TrimSpace(stderr.buffer.String()), "\n")
+	lastLine := lines[len(lines)-1]
+	for message, limit := range limitMessages {
+		if strings.Contains(lastLine, message) {
+			return limit, true
+		}
+	}
+	return "", false
+}
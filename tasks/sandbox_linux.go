@@ -0,0 +1,24 @@
+//go:build linux
+// +build linux
+
+package tasks
+
+import (
+	"syscall"
+)
+
+// sysProcAttr sets up the process that a sandboxed script runs in. It runs in
+// its own process group as the sandbox's user, and in new PID, IPC and UTS
+// namespaces if the sandbox uses them. No new filesystem is mounted for it, so
+// the script sees the same files and /proc as its user would anywhere else.
+// The network is shared so that scripts can still reach the pages they monitor.
+func (s Sandbox) sysProcAttr(cred *syscall.Credential) (*syscall.SysProcAttr, error) {
+	attr := &syscall.SysProcAttr{
+		Setpgid:    true,
+		Credential: cred,
+	}
+	if s.Namespaces {
+		attr.Cloneflags = syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	}
+	return attr, nil
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+// +build !linux
+
+package tasks
+
+import (
+	"errors"
+	"syscall"
+)
+
+// sysProcAttr sets up the process that a sandboxed script runs in. It runs in
+// its own process group as the sandbox's user.
+// Namespaces are only available on Linux.
+func (s Sandbox) sysProcAttr(cred *syscall.Credential) (*syscall.SysProcAttr, error) {
+	if s.Namespaces {
+		return nil, errors.New("sandbox namespaces are only supported on Linux")
+	}
+	return &syscall.SysProcAttr{
+		Setpgid:    true,
+		Credential: cred,
+	}, nil
+}
@@ -0,0 +1,223 @@
+package tasks
+
+import (
+	"../diff"
+	"../interpreters"
+	"../models"
+
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testSandboxOptions are the options that scripts are run with in the sandbox.
+var testSandboxOptions = RunOptions{
+	GracePeriod:    testGracePeriod,
+	MaxStderrBytes: 4096,
+	Thresholds:     diff.DefaultThresholds(),
+	Interpreters:   interpreters.Default(),
+	Sandbox: Sandbox{
+		Enabled:     true,
+		User:        "nobody",
+		CPUSeconds:  1,
+		MemoryBytes: 256 * 1024 * 1024,
+		OpenFiles:   16,
+		OutputBytes: 1024,
+	},
+}
+
+const testPerlEnvironmentScript = `
+exit 1 if defined $ENV{MIRU_SANDBOX_TEST};
+exit 1 if $ENV{HOME} ne $ENV{TMPDIR};
+open(my $f, '>', "$ENV{TMPDIR}/scratch") or exit 1;
+print '{"changeSignificance": 0, "message": "hello world", "checksum": "", "state": {}}';
+`
+
+const testPerlOutputScript = `
+print 'x' x 100000;
+`
+
+const testPerlCPUScript = `
+1 while 1;
+`
+
+const testPerlOpenFilesScript = `
+my @files;
+for (1..100) {
+  open(my $f, '<', '/dev/null') or die "could not open: $!";
+  push @files, $f;
+}
+`
+
+const testPerlSecretScript = `
+my $key = $ENV{API_KEY};
+print STDERR "using key $key";
+print '{"version": 2, "changeSignificance": 0, "message": "using key ' . $key . '", "checksum": "",' .
+  ' "state": {"key": "' . $key . '", "nested": [{"url": "https://example.com/?key=' . $key . '"}], "count": 1},' .
+  ' "content": "signed in with ' . $key . '"}';
+`
+
+const testPerlQuotedLimitScript = `
+print STDERR "the server said: Too many open files\n";
+print STDERR "MemoryError reported upstream\n";
+die "could not fetch the page";
+`
+
+const testPerlSucceedsQuotingLimitScript = `
+print STDERR "Cannot allocate memory\n";
+print '{"changeSignificance": 0, "message": "hello world", "checksum": "", "state": {}}';
+`
+
+// runInSandbox writes a perl script to a temporary file and runs it in the
+// test sandbox.
+func runInSandbox(t *testing.T, script string) (models.Report, models.Run, error) {
+	return runInSandboxWith(t, script, testSandboxOptions)
+}
+
+// runInSandboxWith writes a perl script to a temporary file and runs it with
+// the options given.
+func runInSandboxWith(t *testing.T, script string, opts RunOptions) (models.Report, models.Run, error) {
+	f, err := ioutil.TempFile("", "sandboxtest*.pl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte(script))
+	f.Close()
+	monitor := models.NewMonitor(
+		models.Archiver{}, models.Request{}, models.Interpreter("perl"), f.Name(), 0, 0)
+	result := DryRun(monitor, "", opts)
+	return result.Report, result.Run, result.Err
+}
+
+func TestSandboxRunsScriptWithClearedEnvironment(t *testing.T) {
+	os.Setenv("MIRU_SANDBOX_TEST", "secret")
+	defer os.Unsetenv("MIRU_SANDBOX_TEST")
+	report, run, err := runInSandbox(t, testPerlEnvironmentScript)
+	if err != nil {
+		t.Fatalf("Expected the sandboxed script to succeed. Got %v: %s", err, run.Stderr())
+	}
+	if report.Message() != "hello world" {
+		t.Errorf("Expected message 'hello world'. Got %s", report.Message())
+	}
+}
+
+func TestSecretsAreGivenToScriptAndRedacted(t *testing.T) {
+	opts := testSandboxOptions
+	opts.Environment = []string{"API_KEY=s3cret-value"}
+	report, run, err := runInSandboxWith(t, testPerlSecretScript, opts)
+	if err != nil {
+		t.Fatalf("Expected the script to succeed. Got %v: %s", err, run.Stderr())
+	}
+	if report.Message() != "using key "+redactedNotice {
+		t.Errorf("Expected the secret to be given to the script and redacted from the message. Got %q", report.Message())
+	}
+	if run.Stderr() != "using key "+redactedNotice {
+		t.Errorf("Expected the secret to be redacted from stderr. Got %q", run.Stderr())
+	}
+	if report.Content() != "signed in with "+redactedNotice {
+		t.Errorf("Expected the secret to be redacted from the content. Got %q", report.Content())
+	}
+	state, _ := json.Marshal(report.State())
+	if strings.Contains(string(state), "s3cret-value") || !strings.Contains(string(state), "key="+redactedNotice) {
+		t.Errorf("Expected the secret to be redacted from the state. Got %s", state)
+	}
+	report, _, _ = runInSandbox(t, testPerlSecretScript)
+	if report.Message() != "using key " {
+		t.Errorf("Expected a script run without the secret not to get it. Got %q", report.Message())
+	}
+}
+
+func TestSandboxLimitsOutput(t *testing.T) {
+	_, run, err := runInSandbox(t, testPerlOutputScript)
+	violation, ok := err.(SandboxViolation)
+	if !ok {
+		t.Fatalf("Expected a SandboxViolation. Got %v", err)
+	}
+	if violation.Limit != outputLimit {
+		t.Errorf("Expected the %s limit to be exceeded. Got %s", outputLimit, violation.Limit)
+	}
+	if run.Outcome() != models.RunSandboxViolation {
+		t.Errorf("Expected outcome %s. Got %s", models.RunSandboxViolation, run.Outcome())
+	}
+}
+
+func TestSandboxLimitsCPUTime(t *testing.T) {
+	_, run, err := runInSandbox(t, testPerlCPUScript)
+	violation, ok := err.(SandboxViolation)
+	if !ok {
+		t.Fatalf("Expected a SandboxViolation. Got %v", err)
+	}
+	if violation.Limit != cpuLimit {
+		t.Errorf("Expected the %s limit to be exceeded. Got %s", cpuLimit, violation.Limit)
+	}
+	if run.Outcome() != models.RunSandboxViolation {
+		t.Errorf("Expected outcome %s. Got %s", models.RunSandboxViolation, run.Outcome())
+	}
+}
+
+func TestSandboxLimitsOpenFiles(t *testing.T) {
+	_, _, err := runInSandbox(t, testPerlOpenFilesScript)
+	violation, ok := err.(SandboxViolation)
+	if !ok {
+		t.Fatalf("Expected a SandboxViolation. Got %v", err)
+	}
+	if violation.Limit != openFilesLimit {
+		t.Errorf("Expected the %s limit to be exceeded. Got %s", openFilesLimit, violation.Limit)
+	}
+}
+
+func TestSandboxRequiresDedicatedUser(t *testing.T) {
+	sandbox := testSandboxOptions.Sandbox
+	if err := sandbox.Check(); err != nil {
+		t.Fatalf("Expected the test sandbox to be usable. Got %v", err)
+	}
+	sandbox.User = ""
+	if err := sandbox.Check(); err != errNoSandboxUser {
+		t.Errorf("Expected a sandbox without a user to be refused. Got %v", err)
+	}
+	sandbox.User = "root"
+	if err := sandbox.Check(); err == nil {
+		t.Error("Expected a sandbox running scripts as root to be refused")
+	}
+	opts := testSandboxOptions
+	opts.Sandbox.User = ""
+	_, run, err := runInSandboxWith(t, testPerlEnvironmentScript, opts)
+	if err == nil || run.Outcome() != models.RunStartFailed {
+		t.Errorf("Expected a script not to run without a sandbox user. Got %v", err)
+	}
+}
+
+func TestFailuresQuotingLimitsAreNotViolations(t *testing.T) {
+	_, run, err := runInSandbox(t, testPerlQuotedLimitScript)
+	if _, violated := err.(SandboxViolation); violated {
+		t.Errorf("Expected a normal failure not to be a violation. Got %v", err)
+	}
+	if run.Outcome() != models.RunNonZeroExit {
+		t.Errorf("Expected outcome %s. Got %s", models.RunNonZeroExit, run.Outcome())
+	}
+	_, run, err = runInSandbox(t, testPerlSucceedsQuotingLimitScript)
+	if err != nil || run.Outcome() != models.RunSucceeded {
+		t.Errorf("Expected a successful script not to be a violation. Got %v", err)
+	}
+}
+
+func TestLimitedBufferStopsAtLimit(t *testing.T) {
+	buffer := newLimitedBuffer(4)
+	buffer.Write([]byte("abc"))
+	if buffer.Exceeded() {
+		t.Fatal("Expected the buffer not to be full after 3 bytes")
+	}
+	n, err := buffer.Write([]byte("de"))
+	if n != 2 || err != nil {
+		t.Errorf("Expected writes past the limit to be discarded quietly. Got %d, %v", n, err)
+	}
+	if !buffer.Exceeded() {
+		t.Error("Expected the buffer to be full after 5 bytes")
+	}
+	if buffer.Len() != 3 {
+		t.Errorf("Expected 3 bytes to be kept. Got %d", buffer.Len())
+	}
+}
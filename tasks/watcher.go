@@ -1,48 +1,173 @@
 package tasks
 
 import (
+	"../config"
 	"../models"
+	"../snapshots"
 
 	"database/sql"
 	"fmt"
 	"time"
 )
 
+// defaultWorkers is the number of monitor scripts that will be allowed to run
+// at once if the configuration does not specify a number of workers.
+const defaultWorkers uint = 4
+
+// queueSizePerWorker is the number of ready monitors to keep queued for each
+// worker if the configuration does not specify a queue size.
+const queueSizePerWorker uint = 4
+
+// job contains everything a worker needs to run a monitor.
+type job struct {
+	monitor     models.Monitor
+	url         string
+	lastReport  models.Report
+	environment []string
+}
+
+// completion is sent back by a worker when it finishes running a job, containing
+// a record of the run, the URL of the site monitored, and either the new report
+// produced or the error encountered.
+type completion struct {
+	run    models.Run
+	url    string
+	report models.Report
+	err    error
+}
+
+// jobRunner runs the monitor in a job, producing the record of its run along
+// with either its new report or the error it encountered.
+type jobRunner func(j job, opts RunOptions) completion
+
 // RunMonitors runs until signalled to terminate, periodically fetching new
 // monitors whose scripts are ready to be run, running them, and then manages
 // their reports.
-func RunMonitors(db *sql.DB, sleepPeriod time.Duration, errors chan<- error, terminate <-chan bool) {
-	results := make(chan models.Report)
+// Ready monitors are fetched in batches into a Queue and handed out to a fixed
+// number of workers. When every worker is busy and the queue is full, no more
+// monitors are fetched until a worker finishes. Each report saved is sent to
+// any webhooks whose threshold its change meets, and significant changes are
+// emailed to subscribed administrators.
+func RunMonitors(
+	db *sql.DB,
+	cfg *config.Config,
+	sleepPeriod time.Duration,
+	errors chan<- error,
+	terminate <-chan bool) {
+	scheduleMonitors(db, cfg, sleepPeriod, runJob, errors, terminate)
+}
+
+// scheduleMonitors implements RunMonitors, running each monitor with run.
+func scheduleMonitors(
+	db *sql.DB,
+	cfg *config.Config,
+	sleepPeriod time.Duration,
+	run jobRunner,
+	errors chan<- error,
+	terminate <-chan bool) {
+	workers := cfg.MonitorWorkers
+	if workers == 0 {
+		workers = defaultWorkers
+	}
+	queueSize := cfg.MonitorQueueSize
+	if queueSize == 0 {
+		queueSize = workers * queueSizePerWorker
+	}
+	opts := NewRunOptions(cfg)
+	secretsKey, keyErr := models.LoadSecretsKey(cfg.SecretsKeyFile)
+	if keyErr != nil {
+		fmt.Println("Couldn't load the key that monitor secrets are encrypted with")
+		errors <- keyErr
+	}
+	webhookOpts := NewWebhookOptions(cfg)
+	notifyOpts := NewNotifyOptions(cfg)
+	queue := NewQueue(queueSize)
+	jobs := make(chan job)
+	done := make(chan completion, workers)
+	for i := uint(0); i < workers; i++ {
+		go runWorker(jobs, done, opts, run)
+	}
+	idle := workers
+	ticker := time.NewTicker(sleepPeriod)
+	defer ticker.Stop()
 	terminated := false
 	for !terminated {
+		// Hand queued monitors out to any workers that are free.
+		for idle > 0 && queue.Size() > 0 {
+			monitor, _ := queue.Pop()
+			request, findErr := models.FindRequest(db, monitor.CreatedFor())
+			if findErr != nil {
+				fmt.Println("Couldn't find the request satisfied by monitor", monitor.ID())
+				errors <- findErr
+				continue
+			}
+			lastReport, findErr := models.FindLastReportForMonitor(db, monitor)
+			if findErr != nil {
+				fmt.Println("Couldn't find report for monitor", findErr)
+				lastReport = models.NewReport(monitor)
+				saveErr := lastReport.Save(db)
+				if saveErr != nil {
+					fmt.Println("could not save new report", saveErr)
+					errors <- saveErr
+				}
+			}
+			// Monitors whose secrets can't be decrypted aren't run, since
+			// their scripts would only fail without them. The failure is
+			// recorded and the monitor waits until it is next due to try again.
+			environment, envErr := models.MonitorEnvironment(db, secretsKey, monitor)
+			if envErr != nil {
+				fmt.Println("Couldn't decrypt the secrets of monitor", monitor.ID())
+				errors <- envErr
+				skipErr := recordSkippedRun(db, monitor, models.RunSecretsUnavailable, envErr)
+				if skipErr != nil {
+					errors <- skipErr
+				}
+				continue
+			}
+			jobs <- job{monitor, request.URL(), lastReport, environment}
+			idle--
+		}
 		select {
-		case <-time.After(sleepPeriod):
-			monitors, err := models.FindReadyMonitors(db, 1)
+		case <-ticker.C:
+			free := queue.Capacity() - queue.Size()
+			if free == 0 {
+				continue
+			}
+			monitors, err := models.FindReadyMonitors(db, free)
 			if err != nil {
 				errors <- err
 			}
 			for _, monitor := range monitors {
 				fmt.Println("+++ Found monitor", monitor)
+				// Mark the monitor as having run now so that it isn't fetched
+				// again while it waits in the queue.
 				monitor.SetLastRun()
-				updateErr := monitor.Update(db)
+				updateErr := monitor.UpdateLastRun(db)
 				if updateErr != nil {
 					errors <- updateErr
 				}
-				lastReport, findErr := models.FindLastReportForMonitor(db, monitor)
-				if findErr != nil {
-					fmt.Println("Couldn't find report for monitor", findErr)
-					lastReport = models.NewReport(monitor)
-					saveErr := lastReport.Save(db)
-					if saveErr != nil {
-						fmt.Println("could not save new report", saveErr)
-						errors <- saveErr
+				queue.Push(monitor)
+			}
+		case finished := <-done:
+			idle++
+			if finished.err != nil {
+				errors <- finished.err
+			} else {
+				fmt.Println("Got result", finished.report)
+				saveErr := finished.report.Save(db)
+				if saveErr != nil {
+					errors <- saveErr
+				} else {
+					finished.run.SetReport(finished.report)
+					snapshotErr := saveSnapshot(db, opts.Snapshots, finished.report)
+					if snapshotErr != nil {
+						errors <- snapshotErr
 					}
+					notifyWebhooks(db, finished.report, finished.url, webhookOpts, errors)
+					notifySubscribers(db, finished.report, finished.url, notifyOpts, errors)
 				}
-				go RunMonitorScript(monitor, lastReport, results, errors)
 			}
-		case result := <-results:
-			fmt.Println("Got result", result)
-			saveErr := result.Save(db)
+			saveErr := finished.run.Save(db)
 			if saveErr != nil {
 				errors <- saveErr
 			}
@@ -50,6 +175,63 @@ func RunMonitors(db *sql.DB, sleepPeriod time.Duration, errors chan<- error, ter
 			terminated = true
 		}
 	}
+	close(jobs)
 	close(errors)
-	close(results)
+}
+
+// recordSkippedRun saves a failed run for a monitor that could not be run at
+// all, and restarts the wait before it is next run from now, so that the
+// failure is shown to administrators and the monitor isn't retried right away.
+func recordSkippedRun(db *sql.DB, monitor models.Monitor, outcome models.RunOutcome, reason error) error {
+	run := models.NewRun(monitor)
+	run.Fail(outcome, reason)
+	err := run.Save(db)
+	if err != nil {
+		return err
+	}
+	monitor.SetLastRun()
+	return monitor.UpdateLastRun(db)
+}
+
+// saveSnapshot links a report to the snapshot of the page it was produced for,
+// if the snapshot store contains a page matching the report's checksum.
+func saveSnapshot(db *sql.DB, store *snapshots.Store, report models.Report) error {
+	if store == nil || report.Checksum() == "" {
+		return nil
+	}
+	info, statErr := store.Stat(report.Checksum())
+	if statErr != nil {
+		return nil
+	}
+	snapshot := models.NewSnapshot(report, report.Checksum(), info.Size())
+	return snapshot.Save(db)
+}
+
+// runWorker runs the monitors received with run until the jobs channel is
+// closed, reporting each one's result through the done channel.
+func runWorker(jobs <-chan job, done chan<- completion, opts RunOptions, run jobRunner) {
+	for j := range jobs {
+		done <- run(j, opts)
+	}
+}
+
+// runJob runs a monitor and waits for its result. Fetch monitors are run by
+// miru itself, and all others by running their script.
+func runJob(j job, opts RunOptions) completion {
+	results := make(chan models.Report, 1)
+	errs := make(chan error, 1)
+	var run models.Run
+	if j.monitor.Interpreter() == models.FetchInterpreter {
+		run = RunFetchMonitor(j.monitor, j.url, j.lastReport, opts, results, errs)
+	} else {
+		jobOpts := opts
+		jobOpts.Environment = j.environment
+		run = RunMonitorScript(j.monitor, j.lastReport, jobOpts, results, errs)
+	}
+	select {
+	case report := <-results:
+		return completion{run: run, url: j.url, report: report}
+	case err := <-errs:
+		return completion{run: run, url: j.url, err: err}
+	}
 }
@@ -0,0 +1,182 @@
+package tasks
+
+import (
+	"../config"
+	"../models"
+
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+)
+
+// schedulerTestTick is how often the scheduler looks for ready monitors in tests.
+const schedulerTestTick = 5 * time.Millisecond
+
+// fakeRunner stands in for running monitors, holding every job it is given
+// until it is released, so that tests can see how many run at once.
+type fakeRunner struct {
+	lock       sync.Mutex
+	running    int
+	maxRunning int
+	finished   int
+	release    chan bool
+}
+
+func newFakeRunner() *fakeRunner {
+	return &fakeRunner{release: make(chan bool)}
+}
+
+// run implements jobRunner, producing an empty report once released.
+func (r *fakeRunner) run(j job, opts RunOptions) completion {
+	r.lock.Lock()
+	r.running++
+	if r.running > r.maxRunning {
+		r.maxRunning = r.running
+	}
+	r.lock.Unlock()
+	<-r.release
+	r.lock.Lock()
+	r.running--
+	r.finished++
+	r.lock.Unlock()
+	return completion{run: models.NewRun(j.monitor), url: j.url, report: models.NewReport(j.monitor)}
+}
+
+// counts gets the number of jobs running, the most that have run at once, and
+// the number that have finished.
+func (r *fakeRunner) counts() (int, int, int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.running, r.maxRunning, r.finished
+}
+
+func TestSchedulerLimitsWorkersAndQueue(t *testing.T) {
+	cases := []struct {
+		workers   uint
+		queueSize uint
+	}{
+		{2, 2},
+		{1, 3},
+		{3, 1},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%d workers, queue of %d", c.workers, c.queueSize), func(t *testing.T) {
+			testSchedulerLimits(t, c.workers, c.queueSize)
+		})
+	}
+}
+
+// testSchedulerLimits runs more ready monitors than a number of workers and a
+// queue of a size can hold, and checks that only as many as they can hold are
+// run or fetched until the workers are freed.
+func testSchedulerLimits(t *testing.T, workers, queueSize uint) {
+	db := openTestDB(t)
+	defer db.Close()
+	dir, err := ioutil.TempDir("", "miru-scheduler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfg := &config.Config{
+		MonitorWorkers:   workers,
+		MonitorQueueSize: queueSize,
+		SecretsKeyFile:   path.Join(dir, "secrets.key"),
+		SnapshotDir:      dir,
+	}
+	total := int(workers+queueSize) + 3
+	monitors := saveReadyMonitors(t, db, total)
+	started := time.Now()
+	runner := newFakeRunner()
+	errs := make(chan error)
+	terminate := make(chan bool)
+	go scheduleMonitors(db, cfg, schedulerTestTick, runner.run, errs, terminate)
+	stopped := make(chan bool)
+	go func() {
+		for err := range errs {
+			t.Errorf("expected the scheduler not to fail, got %v", err)
+		}
+		close(stopped)
+	}()
+	waitFor(t, "every worker to be busy", func() bool {
+		running, _, _ := runner.counts()
+		return running == int(workers)
+	})
+	// The scheduler keeps ticking while the workers are busy and the queue
+	// fills up, but must not fetch anything once the queue is full.
+	time.Sleep(20 * schedulerTestTick)
+	running, maxRunning, _ := runner.counts()
+	if running != int(workers) || maxRunning != int(workers) {
+		t.Errorf("expected at most %d monitors to run at once, got %d", workers, maxRunning)
+	}
+	fetched := countFetched(t, db, monitors, started)
+	if fetched != int(workers+queueSize) {
+		t.Errorf("expected %d monitors to be running or queued, got %d fetched", workers+queueSize, fetched)
+	}
+	close(runner.release)
+	waitFor(t, "every monitor to run", func() bool {
+		_, _, finished := runner.counts()
+		return finished == total
+	})
+	terminate <- true
+	<-stopped
+	_, maxRunning, _ = runner.counts()
+	if maxRunning != int(workers) {
+		t.Errorf("expected at most %d monitors to run at once, got %d", workers, maxRunning)
+	}
+	for _, monitor := range monitors {
+		if _, err := models.FindLastReportForMonitor(db, monitor); err != nil {
+			t.Errorf("expected a report to be saved for monitor %d, got %v", monitor.ID(), err)
+		}
+	}
+}
+
+// saveReadyMonitors saves a number of monitors, each for a request of its own,
+// that are ready to run.
+func saveReadyMonitors(t *testing.T, db *sql.DB, count int) []models.Monitor {
+	monitors := []models.Monitor{}
+	for i := 0; i < count; i++ {
+		request := models.NewRequest(models.Archiver{}, fmt.Sprintf("https://example.com/%d", i), "")
+		if err := request.Save(db); err != nil {
+			t.Fatal(err)
+		}
+		monitor := models.NewMonitor(
+			models.Archiver{}, request, models.FetchInterpreter, "", time.Hour, time.Minute)
+		if err := monitor.Save(db); err != nil {
+			t.Fatal(err)
+		}
+		monitors = append(monitors, monitor)
+	}
+	return monitors
+}
+
+// countFetched counts the monitors that the scheduler has fetched to run since
+// a time, which it records as their last run.
+func countFetched(t *testing.T, db *sql.DB, monitors []models.Monitor, since time.Time) int {
+	fetched := 0
+	for _, monitor := range monitors {
+		saved, err := models.FindMonitor(db, monitor.ID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if saved.LastRun().After(since) {
+			fetched++
+		}
+	}
+	return fetched
+}
+
+// waitFor waits up to a few seconds for a condition to become true.
+func waitFor(t *testing.T, description string, condition func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", description)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,179 @@
+package tasks
+
+import (
+	"../config"
+	"../models"
+
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Defaults for delivering reports to webhooks if the configuration does not
+// specify them.
+const (
+	defaultWebhookMaxAttempts uint          = 5
+	defaultWebhookBackoff     time.Duration = 1 * time.Second
+	webhookTimeout            time.Duration = 10 * time.Second
+)
+
+// SignatureHeader is the name of the header containing the signature of the
+// payload sent to a webhook. The signature is written as "sha256=" followed by
+// the hex-encoded HMAC-SHA256 of the request body, keyed with the webhook's secret.
+const SignatureHeader string = "X-Miru-Signature"
+
+// WebhookOptions configures how reports are delivered to webhooks.
+type WebhookOptions struct {
+	MaxAttempts uint          // The most times to try sending a report.
+	Backoff     time.Duration // Time to wait before the first retry, doubled after each one.
+	Timeout     time.Duration // Time to wait for a webhook to respond.
+}
+
+// NewWebhookOptions creates WebhookOptions from the application's configuration.
+func NewWebhookOptions(cfg *config.Config) WebhookOptions {
+	maxAttempts := cfg.WebhookMaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = defaultWebhookMaxAttempts
+	}
+	backoff := time.Duration(cfg.WebhookBackoff) * time.Second
+	if backoff == 0 {
+		backoff = defaultWebhookBackoff
+	}
+	return WebhookOptions{
+		MaxAttempts: maxAttempts,
+		Backoff:     backoff,
+		Timeout:     webhookTimeout,
+	}
+}
+
+// webhookPayload is the JSON body sent to webhooks.
+type webhookPayload struct {
+	Event  string            `json:"event"`
+	SentAt time.Time         `json:"sentAt"`
+	Report webhookReportJSON `json:"report"`
+}
+
+// webhookReportJSON describes the report that triggered a webhook.
+type webhookReportJSON struct {
+	ID               int               `json:"id"`
+	MonitorID        int               `json:"monitorId"`
+	URL              string            `json:"url"`
+	CreatedAt        time.Time         `json:"createdAt"`
+	Significance     models.Importance `json:"significance"`
+	SignificanceName string            `json:"significanceName"`
+	Message          string            `json:"message"`
+	Checksum         string            `json:"checksum"`
+}
+
+// Sign computes the value of the SignatureHeader for a payload sent to a
+// webhook with a given secret.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// DeliverWebhook POSTs a report about a change to the site at url to a webhook,
+// retrying with exponential backoff until the webhook accepts it with a 2xx
+// status or opts.MaxAttempts attempts have been made. Client errors other than
+// 429 Too Many Requests are not retried since they won't be fixed by trying
+// again. A record of each attempt is passed to record as soon as it is made, so
+// that attempts are kept even if miru stops before the delivery is done.
+func DeliverWebhook(
+	webhook models.Webhook,
+	report models.Report,
+	url string,
+	opts WebhookOptions,
+	record func(models.WebhookDelivery)) {
+	payload, encodeErr := json.Marshal(webhookPayload{
+		Event:  "report",
+		SentAt: time.Now(),
+		Report: webhookReportJSON{
+			ID:               report.ID(),
+			MonitorID:        report.CreatedBy(),
+			URL:              url,
+			CreatedAt:        report.CreatedAt(),
+			Significance:     report.Change(),
+			SignificanceName: report.Change().String(),
+			Message:          report.Message(),
+			Checksum:         report.Checksum(),
+		},
+	})
+	if encodeErr != nil {
+		delivery := models.NewWebhookDelivery(webhook, report, 1)
+		delivery.Finish(0, encodeErr)
+		record(delivery)
+		return
+	}
+	signature := Sign(webhook.Secret(), payload)
+	client := &http.Client{Timeout: opts.Timeout}
+	backoff := opts.Backoff
+	for attempt := 1; attempt <= int(opts.MaxAttempts); attempt++ {
+		if attempt > 1 {
+			<-time.After(backoff)
+			backoff *= 2
+		}
+		delivery := models.NewWebhookDelivery(webhook, report, attempt)
+		status, postErr := postPayload(client, webhook.URL(), payload, signature)
+		delivery.Finish(status, postErr)
+		record(delivery)
+		retryable := status == 0 || status == http.StatusTooManyRequests || status >= 500
+		if delivery.Succeeded() || !retryable {
+			break
+		}
+	}
+}
+
+// postPayload sends a signed payload to a webhook and returns the status code
+// it responded with.
+func postPayload(client *http.Client, url string, payload []byte, signature string) (int, error) {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, signature)
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	// Drain the body so that the connection can be reused.
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// notifyWebhooks starts delivering a newly saved report to every webhook whose
+// threshold its change meets, and logs each attempt as it is made.
+// Deliveries run in the background so that slow webhooks don't hold up monitors.
+func notifyWebhooks(
+	db *sql.DB,
+	report models.Report,
+	url string,
+	opts WebhookOptions,
+	errors chan<- error) {
+	webhooks, err := models.ListWebhooksForChange(db, report.Change())
+	if err != nil {
+		errors <- err
+		return
+	}
+	for _, webhook := range webhooks {
+		go DeliverWebhook(webhook, report, url, opts, func(delivery models.WebhookDelivery) {
+			saveErr := delivery.Save(db)
+			if saveErr != nil {
+				fmt.Println("Could not save webhook delivery", saveErr)
+			}
+		})
+	}
+}
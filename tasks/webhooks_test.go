@@ -0,0 +1,110 @@
+package tasks
+
+import (
+	"../models"
+
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// webhookTestOptions retry quickly so that tests don't take long.
+var webhookTestOptions = WebhookOptions{
+	MaxAttempts: 3,
+	Backoff:     10 * time.Millisecond,
+	Timeout:     2 * time.Second,
+}
+
+// deliverAll delivers a report to a webhook and returns the record of every
+// attempt made.
+func deliverAll(webhook models.Webhook, report models.Report, url string) []models.WebhookDelivery {
+	deliveries := []models.WebhookDelivery{}
+	DeliverWebhook(webhook, report, url, webhookTestOptions, func(delivery models.WebhookDelivery) {
+		deliveries = append(deliveries, delivery)
+	})
+	return deliveries
+}
+
+func TestDeliverWebhookSignsPayload(t *testing.T) {
+	var webhook models.Webhook
+	report := models.NewReport(models.Monitor{})
+	report.SetChange(models.Rewritten)
+	var received webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		if req.Header.Get(SignatureHeader) != Sign(webhook.Secret(), body) {
+			t.Errorf("expected the payload to be signed with the webhook's secret")
+		}
+		json.Unmarshal(body, &received)
+	}))
+	defer server.Close()
+	webhook, _ = models.NewWebhook(models.Archiver{}, server.URL, models.ContentChange)
+	deliveries := deliverAll(webhook, report, "https://example.com")
+	if len(deliveries) != 1 || !deliveries[0].Succeeded() {
+		t.Fatalf("expected one successful delivery, got %v", deliveries)
+	}
+	if received.Report.URL != "https://example.com" || received.Report.Significance != models.Rewritten {
+		t.Errorf("expected the report to be sent, got %v", received)
+	}
+}
+
+func TestDeliverWebhookRetries(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		attempts++
+		if attempts < 3 {
+			res.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	webhook, _ := models.NewWebhook(models.Archiver{}, server.URL, models.NoChange)
+	deliveries := deliverAll(webhook, models.NewReport(models.Monitor{}), "")
+	if len(deliveries) != 3 {
+		t.Fatalf("expected three attempts, got %d", len(deliveries))
+	}
+	if deliveries[0].Succeeded() || deliveries[0].StatusCode() != http.StatusServiceUnavailable {
+		t.Errorf("expected the first attempt to be recorded as failed, got %v", deliveries[0])
+	}
+	if !deliveries[2].Succeeded() || deliveries[2].Attempt() != 3 {
+		t.Errorf("expected the third attempt to succeed, got %v", deliveries[2])
+	}
+	if deliveries[2].AttemptedAt().Sub(deliveries[0].AttemptedAt()) < 30*time.Millisecond {
+		t.Errorf("expected to back off between attempts")
+	}
+}
+
+func TestDeliverWebhookDoesNotRetryClientErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	webhook, _ := models.NewWebhook(models.Archiver{}, server.URL, models.NoChange)
+	deliveries := deliverAll(webhook, models.NewReport(models.Monitor{}), "")
+	if len(deliveries) != 1 || deliveries[0].Succeeded() {
+		t.Errorf("expected a single failed attempt, got %v", deliveries)
+	}
+}
+
+func TestDeliverWebhookRecordsEachAttemptAsItIsMade(t *testing.T) {
+	recorded := 0
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if recorded != attempts {
+			t.Errorf("expected %d attempts to be recorded before the next one, got %d", attempts, recorded)
+		}
+		attempts++
+		res.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	webhook, _ := models.NewWebhook(models.Archiver{}, server.URL, models.NoChange)
+	DeliverWebhook(webhook, models.NewReport(models.Monitor{}), "", webhookTestOptions, func(delivery models.WebhookDelivery) {
+		recorded++
+		if delivery.Attempt() != recorded {
+			t.Errorf("expected attempt %d to be recorded, got %d", recorded, delivery.Attempt())
+		}
+	})
+	if recorded != int(webhookTestOptions.MaxAttempts) {
+		t.Errorf("expected every attempt to be recorded, got %d", recorded)
+	}
+}